@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"os"
 
 	"github.com/joho/godotenv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/m4ck-y/ETL_go/internal/application"
+	"github.com/m4ck-y/ETL_go/internal/domain"
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
 	"github.com/m4ck-y/ETL_go/internal/infrastructure/api"
 	"github.com/m4ck-y/ETL_go/internal/infrastructure/repository"
 	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
@@ -30,8 +35,151 @@ func main() {
 		logger.GlobalLogger.Info("Variables de entorno cargadas desde .env", "system", nil)
 	}
 
-	repo := repository.NewInMemoryMetricsRepository()
-	handler := &api.APIHandler{Repo: repo}
+	repo, err := newRepository()
+	if err != nil {
+		logger.GlobalLogger.Fatal("Error al inicializar el repositorio", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	// Con el repositorio en memoria los datos no sobreviven al proceso, igual que una clave aleatoria
+	_, inMemory := repo.(*repository.InMemoryMetricsRepository)
+	hasher, err := application.LoadContactHasher(!inMemory)
+	if err != nil {
+		logger.GlobalLogger.Fatal("Clave de seudonimización de contactos inválida", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	application.SetContactHasher(hasher)
+	if hasher.Ephemeral() {
+		logger.GlobalLogger.Warn("CONTACT_HASH_KEY no definida: los contactos se seudonimizan con una clave aleatoria por proceso", "system", nil)
+	}
+	// Los emails guardados en claro antes de seudonimizar al extraer se reemplazan por su hash
+	pseudonymized, err := repo.PseudonymizeContacts(application.HashContact)
+	if err != nil {
+		logger.GlobalLogger.Fatal("Error al seudonimizar los contactos guardados", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if pseudonymized > 0 {
+		logger.GlobalLogger.Info("Contactos guardados seudonimizados", "system", map[string]interface{}{
+			"records": pseudonymized,
+		})
+	}
+
+	sources, err := application.LoadSourceRegistry()
+	if err != nil {
+		// El servicio arranca igualmente; /readyz reporta la falta de fuentes
+		logger.GlobalLogger.Warn("Error al cargar las fuentes de datos", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+		sources = application.NewSourceRegistry()
+	} else {
+		names := make([]string, 0)
+		for _, source := range sources.Sources() {
+			names = append(names, source.Name())
+		}
+		logger.GlobalLogger.Info("Fuentes de datos cargadas", "system", map[string]interface{}{
+			"sources": names,
+		})
+	}
+
+	taxonomy, err := application.LoadStageTaxonomy()
+	if err != nil {
+		logger.GlobalLogger.Fatal("Taxonomía de etapas de CRM inválida", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	application.SetStageTaxonomy(taxonomy)
+
+	utmRules, err := application.LoadUTMRules()
+	if err != nil {
+		logger.GlobalLogger.Fatal("Reglas de normalización de UTMs inválidas", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	application.SetUTMRules(utmRules)
+
+	timezone, err := application.LoadReportingTimezone()
+	if err != nil {
+		logger.GlobalLogger.Fatal("Zona horaria de reporte inválida", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	// Los días guardados se agruparon con la zona de su ingesta: mezclarlos con otra cambiaría su significado
+	if err := checkStoredSetting(repo, "REPORTING_TIMEZONE", timezone.String(), func(b models.Batch) string { return b.Timezone }); err != nil {
+		logger.GlobalLogger.Fatal("Zona horaria de reporte distinta a la de los datos guardados", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	application.SetReportingTimezone(timezone)
+	logger.GlobalLogger.Info("Zona horaria de reporte configurada", "system", map[string]interface{}{
+		"reporting_timezone": timezone.String(),
+	})
+
+	fx, err := application.LoadFXTable(context.Background())
+	if err != nil {
+		logger.GlobalLogger.Fatal("Tipos de cambio inválidos", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	// Los hechos guardados no registran su moneda: sumarlos con los de otra mezclaría montos bajo una sola etiqueta
+	if err := checkStoredSetting(repo, "REPORTING_CURRENCY", fx.ReportingCurrency(), func(b models.Batch) string { return b.Currency }); err != nil {
+		logger.GlobalLogger.Fatal("Moneda de reporte distinta a la de los datos guardados", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	application.SetFXTable(fx)
+	logger.GlobalLogger.Info("Moneda de reporte configurada", "system", map[string]interface{}{
+		"reporting_currency": fx.ReportingCurrency(),
+		"fx_source":          fx.Source(),
+	})
+
+	attributionWindow, err := application.LoadAttributionWindow()
+	if err != nil {
+		logger.GlobalLogger.Fatal("Ventana de atribución inválida", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	application.SetAttributionWindow(attributionWindow)
+	if attributionWindow > 0 {
+		logger.GlobalLogger.Info("Ventana de atribución configurada", "system", map[string]interface{}{
+			"attribution_window_days": attributionWindow,
+		})
+	}
+
+	kpis, err := application.LoadKPIRegistry()
+	if err != nil {
+		logger.GlobalLogger.Fatal("Fórmulas de KPIs inválidas", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	application.SetKPIRegistry(kpis)
+	if names := kpis.Names(); len(names) > 0 {
+		logger.GlobalLogger.Info("KPIs derivados cargados", "system", map[string]interface{}{
+			"kpis": names,
+		})
+	}
+
+	handler := &api.APIHandler{Repo: repo, Sources: sources, Jobs: application.NewJobManager()}
+
+	schedules, err := application.LoadScheduleConfigs()
+	if err != nil {
+		logger.GlobalLogger.Fatal("Error al cargar las ingestiones programadas", "system", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if len(schedules) > 0 {
+		scheduler, err := application.NewScheduler(schedules, handler.RunScheduledIngest)
+		if err != nil {
+			logger.GlobalLogger.Fatal("Configuración de ingestiones programadas inválida", "system", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		handler.Scheduler = scheduler
+		scheduler.Start()
+		defer scheduler.Stop()
+	}
 
 	router := gin.Default()
 
@@ -61,3 +209,48 @@ func main() {
 		})
 	}
 }
+
+// checkStoredSetting compara value con el registrado por el último lote que lo tiene (los lotes que fallan
+// antes de agregar no lo registran); un repositorio sin lotes acepta cualquier valor
+func checkStoredSetting(repo domain.MetricsRepository, name, value string, setting func(models.Batch) string) error {
+	const page = 100
+	for offset := 0; ; offset += page {
+		batches, err := repo.ListBatches(page, offset)
+		if err != nil {
+			return fmt.Errorf("error reading batch ledger: %w", err)
+		}
+		for _, batch := range batches {
+			if stored := setting(batch); stored != "" {
+				if stored != value {
+					return fmt.Errorf("%s is %q but stored data was ingested with %q (batch %s): restore it or start with an empty repository", name, value, stored, batch.ID)
+				}
+				return nil
+			}
+		}
+		if len(batches) < page {
+			return nil
+		}
+	}
+}
+
+// newRepository selecciona la implementación del repositorio según REPOSITORY_DRIVER (memory | sqlite)
+func newRepository() (domain.MetricsRepository, error) {
+	driver := os.Getenv("REPOSITORY_DRIVER")
+
+	switch driver {
+	case "", "memory":
+		logger.GlobalLogger.Info("Usando repositorio en memoria", "system", nil)
+		return repository.NewInMemoryMetricsRepository(), nil
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "etl.db"
+		}
+		logger.GlobalLogger.Info("Usando repositorio SQLite", "system", map[string]interface{}{
+			"path": path,
+		})
+		return repository.NewSQLiteMetricsRepository(path)
+	default:
+		return nil, fmt.Errorf("REPOSITORY_DRIVER desconocido: %s", driver)
+	}
+}
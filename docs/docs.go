@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/admin/reset": {
+        "/admin/contacts/purge": {
             "post": {
-                "description": "Limpia completamente la base de datos en memoria, eliminando todas las métricas y lotes procesados.",
+                "description": "Atiende solicitudes de privacidad: borra las oportunidades del contacto (identificado por el HMAC de su email) y sus registros en cuarentena, y recalcula los contadores de CRM de los días afectados. Requiere la misma CONTACT_HASH_KEY con la que se ingirieron sus datos.",
                 "consumes": [
                     "application/json"
                 ],
@@ -27,10 +27,27 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Resetea todos los datos almacenados",
+                "summary": "Borra los datos de un contacto",
+                "parameters": [
+                    {
+                        "description": "Email del contacto",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.purgeContactRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "Datos reseteados correctamente",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ContactPurge"
+                        }
+                    },
+                    "400": {
+                        "description": "Email ausente o inválido",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -50,62 +67,73 @@ const docTemplate = `{
                 }
             }
         },
-        "/healthz": {
+        "/admin/fx-rates": {
             "get": {
-                "description": "Verifica que el servicio esté funcionando",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retorna la moneda de reporte, la moneda base de la tabla y las tasas por fecha (unidades de cada moneda por 1 de la base). Cada monto se convierte con la tasa más reciente en o antes del día del registro.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "admin"
                 ],
-                "summary": "Health check básico",
+                "summary": "Obtiene la tabla de tipos de cambio",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "reporting_currency, base, rates, source y loaded_at",
                         "schema": {
-                            "$ref": "#/definitions/api.HealthResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     }
                 }
             }
         },
-        "/ingest/run": {
+        "/admin/fx-rates/reload": {
             "post": {
-                "description": "Ejecuta un proceso ETL que extrae datos de ADS y CRM y guarda los resultados. Soporta filtrado por fecha con el parámetro 'since'.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Vuelve a leer FX_RATES_FILE o a descargar FX_RATES_URL. Las tasas nuevas aplican a las ingestas posteriores; los hechos ya guardados conservan su conversión. Si la tabla es inválida se conserva la vigente.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ingest"
-                ],
-                "summary": "Ejecuta el proceso ETL de ingestión",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Fecha desde la cual filtrar datos (YYYY-MM-DD)",
-                        "name": "since",
-                        "in": "query"
-                    }
+                    "admin"
                 ],
+                "summary": "Recarga la tabla de tipos de cambio",
                 "responses": {
-                    "201": {
-                        "description": "ETL completado correctamente",
+                    "200": {
+                        "description": "Tabla recargada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Tabla de tipos de cambio inválida o inaccesible",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    },
-                    "400": {
-                        "description": "Parámetro de fecha inválido",
+                    }
+                }
+            }
+        },
+        "/admin/reset": {
+            "post": {
+                "description": "Limpia completamente la base de datos en memoria, eliminando todas las métricas y lotes procesados.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Resetea todos los datos almacenados",
+                "responses": {
+                    "200": {
+                        "description": "Datos reseteados correctamente",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -125,9 +153,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/metrics": {
+        "/admin/schedules": {
             "get": {
-                "description": "Retorna un listado de métricas con información de campañas, clics, costo, leads, ventas y métricas calculadas (CPC, CPA, CVR, ROAS).",
+                "description": "Retorna cada programación cron con su ventana 'since', próxima ejecución y el resultado de la última (lote, estado, error)",
                 "consumes": [
                     "application/json"
                 ],
@@ -135,21 +163,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "metrics"
+                    "admin"
                 ],
-                "summary": "Obtiene métricas almacenadas con cálculos derivados",
+                "summary": "Lista las ingestiones programadas",
                 "responses": {
                     "200": {
-                        "description": "Lista de métricas con cálculos incluidos",
+                        "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.MetricResponse"
+                                "$ref": "#/definitions/models.ScheduleStatus"
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/admin/utm-rules/reload": {
+            "post": {
+                "description": "Vuelve a leer UTM_RULES_CONFIG. Las nuevas reglas aplican a las ingestas posteriores; los hechos ya guardados conservan su clave. Si el archivo es inválido se conservan las reglas vigentes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Recarga las reglas de normalización de UTMs",
+                "responses": {
+                    "200": {
+                        "description": "Reglas recargadas",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     },
                     "500": {
-                        "description": "Error interno del servidor",
+                        "description": "Archivo de reglas inválido",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -160,9 +212,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/metrics/channel": {
+        "/batches": {
             "get": {
-                "description": "Retorna métricas filtradas por canal con soporte para fechas y paginación",
+                "description": "Retorna la bitácora de lotes (más recientes primero) con estado, duración, conteos de registros y error si lo hubo",
                 "consumes": [
                     "application/json"
                 ],
@@ -170,28 +222,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "metrics"
+                    "batches"
                 ],
-                "summary": "Obtiene métricas por canal",
+                "summary": "Lista los lotes ETL ejecutados",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Fecha desde (YYYY-MM-DD)",
-                        "name": "from",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Fecha hasta (YYYY-MM-DD)",
-                        "name": "to",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Canal específico",
-                        "name": "channel",
-                        "in": "query"
-                    },
                     {
                         "type": "integer",
                         "default": 50,
@@ -213,7 +247,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.MetricResponse"
+                                "$ref": "#/definitions/models.Batch"
                             }
                         }
                     },
@@ -229,9 +263,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/metrics/funnel": {
+        "/batches/{id}": {
             "get": {
-                "description": "Retorna métricas de conversión filtradas por campaña con soporte para fechas y paginación",
+                "description": "Retorna el registro de auditoría de un lote por su ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -239,50 +273,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "metrics"
+                    "batches"
                 ],
-                "summary": "Obtiene métricas de funnel por campaña",
+                "summary": "Obtiene un lote ETL",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Fecha desde (YYYY-MM-DD)",
-                        "name": "from",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Fecha hasta (YYYY-MM-DD)",
-                        "name": "to",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Campaña específica",
-                        "name": "utm_campaign",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 50,
-                        "description": "Límite de resultados",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Offset para paginación",
-                        "name": "offset",
-                        "in": "query"
+                        "description": "ID del lote",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.MetricResponse"
+                            "$ref": "#/definitions/models.Batch"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -298,9 +313,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/readyz": {
+        "/healthz": {
             "get": {
-                "description": "Verifica que el servicio esté listo para recibir tráfico",
+                "description": "Verifica que el servicio esté funcionando",
                 "consumes": [
                     "application/json"
                 ],
@@ -310,55 +325,1294 @@ const docTemplate = `{
                 "tags": [
                     "health"
                 ],
-                "summary": "Readiness check",
+                "summary": "Health check básico",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.ReadinessResponse"
+                            "$ref": "#/definitions/api.HealthResponse"
                         }
+                    }
+                }
+            }
+        },
+        "/ingest/ads": {
+            "post": {
+                "description": "Acepta un arreglo de registros con el formato de AdRecord, los valida y suma sus métricas a las almacenadas. Las entregas repetidas con el mismo header Idempotency-Key (o, sin header, el mismo cuerpo) no se vuelven a contar.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingest"
+                ],
+                "summary": "Recibe registros de ads por webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave de idempotencia de la entrega",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    {
+                        "description": "Registros de ads",
+                        "name": "records",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.ReadinessResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AdRecord"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Entrega ya procesada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Registros incorporados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "JSON inválido o arreglo vacío",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Cuerpo demasiado grande",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Registros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
-                }
-            }
-        }
-    },
-    "definitions": {
-        "api.HealthResponse": {
-            "type": "object",
-            "properties": {
-                "service": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "string"
-                },
-                "time": {
-                    "type": "string"
                 }
             }
         },
-        "api.ReadinessResponse": {
+        "/ingest/crm": {
+            "post": {
+                "description": "Acepta un arreglo de oportunidades con el formato de CRMRecord, las valida y suma sus métricas a las almacenadas. Las entregas repetidas con el mismo header Idempotency-Key (o, sin header, el mismo cuerpo) no se vuelven a contar.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingest"
+                ],
+                "summary": "Recibe oportunidades de CRM por webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave de idempotencia de la entrega",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Oportunidades de CRM",
+                        "name": "records",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.CRMRecord"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Entrega ya procesada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Registros incorporados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "JSON inválido o arreglo vacío",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Cuerpo demasiado grande",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Registros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ingest/run": {
+            "post": {
+                "description": "Ejecuta un proceso ETL que extrae datos de las fuentes configuradas (Ads y CRM) y guarda los resultados. Soporta filtrado por fecha con el parámetro 'since'. Con async=true (o el header 'Prefer: respond-async') responde 202 con un job consultable en /jobs/{id}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingest"
+                ],
+                "summary": "Ejecuta el proceso ETL de ingestión",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Fecha desde la cual filtrar datos (YYYY-MM-DD)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Ejecutar en segundo plano y devolver un job",
+                        "name": "async",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "ETL completado correctamente",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "202": {
+                        "description": "Job de ingestión aceptado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetro de fecha inválido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "El lote ya se está ejecutando",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ingest/upload": {
+            "post": {
+                "description": "Procesa un archivo subido como una fuente más: misma agregación diaria, bitácora de lotes e idempotencia que /ingest/run (el lote se identifica por el contenido del archivo, el tipo y el mapeo). El gasto del archivo se suma al de los demás archivos del mismo día y UTM. Las filas inválidas se descartan y se reportan con su número de línea.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingest"
+                ],
+                "summary": "Ingesta un archivo CSV o NDJSON",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Archivo CSV (con encabezado) o NDJSON",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tipo de registros: ads o crm",
+                        "name": "kind",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv o ndjson; por defecto se infiere de la extensión (.csv, .ndjson, .jsonl)",
+                        "name": "format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON campo destino → columna (CSV) o ruta con puntos (NDJSON), p.ej. {\\",
+                        "name": "mapping",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Zona IANA de los timestamps sin zona del archivo (p.ej. America/Mexico_City); por defecto la de reporte",
+                        "name": "timezone",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "El archivo ya fue procesado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Archivo ingerido; incluye rejected_rows y row_errors",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Archivo, tipo, formato o mapeo inválido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "El mismo archivo se está procesando en otro job",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Ninguna fila válida",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "Retorna estado, fase (queued, fetching_ads, fetching_crm, aggregating, saving, done), contadores de progreso y error si lo hubo",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Obtiene el estado de un job de ingestión",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID del job",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Solicita la cancelación del job; las peticiones y reintentos en curso se interrumpen y el job termina con estado canceled",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Cancela un job de ingestión",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID del job",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Cancelación solicitada",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "El job ya terminó",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Retorna un listado de métricas con información de campañas, clics, costo, leads, ventas y métricas calculadas (CPC, CPA, CVR, ROAS). Soporta filtrado por rango de fechas con 'from' y 'to'.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Obtiene métricas almacenadas con cálculos derivados",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Fecha desde (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha hasta (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "last_touch",
+                            "first_touch",
+                            "linear",
+                            "time_decay"
+                        ],
+                        "type": "string",
+                        "description": "Modelo de atribución multi-touch",
+                        "name": "model",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Lista de métricas con cálculos incluidos",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.MetricResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetro de fecha inválido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/channel": {
+            "get": {
+                "description": "Retorna métricas filtradas por canal con soporte para fechas y paginación",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Obtiene métricas por canal",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Fecha desde (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha hasta (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "last_touch",
+                            "first_touch",
+                            "linear",
+                            "time_decay"
+                        ],
+                        "type": "string",
+                        "description": "Modelo de atribución multi-touch",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Canal específico",
+                        "name": "channel",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Límite de resultados",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Offset para paginación",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.MetricResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/funnel": {
+            "get": {
+                "description": "Retorna métricas de conversión filtradas por campaña con soporte para fechas y paginación",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Obtiene métricas de funnel por campaña",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Fecha desde (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha hasta (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "last_touch",
+                            "first_touch",
+                            "linear",
+                            "time_decay"
+                        ],
+                        "type": "string",
+                        "description": "Modelo de atribución multi-touch",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Campaña específica",
+                        "name": "utm_campaign",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Límite de resultados",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Offset para paginación",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.MetricResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/unattributed": {
+            "get": {
+                "description": "Retorna, por UTM, los contadores de CRM de las oportunidades creadas en el rango sin actividad de ads (impresiones, clics o gasto) de su UTM en los ATTRIBUTION_WINDOW_DAYS días anteriores. Sin ventana configurada la lista es vacía.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metrics"
+                ],
+                "summary": "Obtiene las métricas de CRM no atribuidas",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Fecha desde (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha hasta (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.MetricResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/quality/rejections": {
+            "get": {
+                "description": "Retorna los registros en cuarentena (más recientes primero) con la regla que no superaron (parseable_date, non_negative_clicks, non_negative_impressions, non_negative_cost, non_negative_amount, required_opportunity_id, known_stage, valid_email, convertible_currency), el motivo y el registro original",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quality"
+                ],
+                "summary": "Lista los registros rechazados por validación",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filtrar por lote",
+                        "name": "batch_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filtrar por regla",
+                        "name": "rule",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filtrar por fuente",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Límite de resultados",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Offset para paginación",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Rejection"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/quality/unmatched": {
+            "get": {
+                "description": "Retorna la conciliación de claves UTM de un lote (por defecto el último conciliado): ads_only tiene gasto sin oportunidades, crm_only tiene oportunidades sin gasto. Cada huérfana incluye su gasto o revenue y hasta 3 claves del otro lado con nombre parecido. Sin status se listan solo las huérfanas, de mayor impacto primero.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quality"
+                ],
+                "summary": "Lista las claves UTM huérfanas de un lote",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lote; por defecto el último conciliado",
+                        "name": "batch_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ads_only, crm_only o matched; por defecto ambas huérfanas",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Límite de resultados",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Offset para paginación",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.KeyReconciliation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Status inválido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Verifica que el servicio esté listo para recibir tráfico",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ReadinessResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ReadinessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/utm/mappings": {
+            "get": {
+                "description": "Retorna, por campo (campaign, source, medium) y valor canónico, los valores crudos recibidos en las ingestas y cuántos registros trajo cada uno. El valor canónico de cada valor crudo es el de su última ingesta.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "utm"
+                ],
+                "summary": "Lista los valores UTM crudos agrupados por valor canónico",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "campaign, source o medium",
+                        "name": "field",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UTMMappingGroup"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "service": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.ReadinessResponse": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "service": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.purgeContactRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "ana@example.com"
+                }
+            }
+        },
+        "models.AdRecord": {
+            "type": "object",
+            "properties": {
+                "campaign_id": {
+                    "type": "string"
+                },
+                "channel": {
+                    "type": "string"
+                },
+                "clicks": {
+                    "type": "integer"
+                },
+                "cost": {
+                    "description": "Acepta número o texto en JSON",
+                    "type": "number"
+                },
+                "currency": {
+                    "description": "ISO 4217; vacío = moneda de reporte",
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "impressions": {
+                    "type": "integer"
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Batch": {
+            "type": "object",
+            "properties": {
+                "ads_records": {
+                    "type": "integer"
+                },
+                "attempts": {
+                    "description": "Ejecuciones del mismo lote (reintentos tras un fallo)",
+                    "type": "integer"
+                },
+                "combinations": {
+                    "type": "integer"
+                },
+                "crm_records": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "description": "Currency es la moneda de reporte en la que se guardaron costos y revenue del lote",
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "quality": {
+                    "description": "Quality resume la validación de registros; nil si la ejecución falló antes de agregar",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.QualitySummary"
+                        }
+                    ]
+                },
+                "reconciliation": {
+                    "description": "Reconciliation cuenta las claves UTM por estado; nil si el lote no incluyó ads y CRM",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ReconciliationSummary"
+                        }
+                    ]
+                },
+                "since": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "Timezone es la zona de reporte con la que se agruparon por día los registros del lote",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CRMRecord": {
             "type": "object",
             "properties": {
-                "checks": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
+                "amount": {
+                    "description": "Acepta número o texto en JSON",
+                    "type": "number"
                 },
-                "service": {
+                "contact_email": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "ISO 4217; vacío = moneda de reporte",
+                    "type": "string"
+                },
+                "opportunity_id": {
+                    "type": "string"
+                },
+                "stage": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt es opcional; si la fuente lo informa decide cuál snapshot de la oportunidad es el más reciente",
+                    "type": "string"
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ContactPurge": {
+            "type": "object",
+            "properties": {
+                "contact_hash": {
+                    "type": "string"
+                },
+                "opportunities": {
+                    "description": "Oportunidades borradas; sus contadores de CRM se recalculan",
+                    "type": "integer"
+                },
+                "rejections": {
+                    "description": "Registros en cuarentena borrados",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Job": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string"
+                },
+                "cancel_requested": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "progress": {
+                    "$ref": "#/definitions/models.JobProgress"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JobProgress": {
+            "type": "object",
+            "properties": {
+                "ads_records": {
+                    "type": "integer"
+                },
+                "combinations": {
+                    "type": "integer"
+                },
+                "crm_records": {
+                    "type": "integer"
+                },
+                "sources_done": {
+                    "type": "integer"
+                },
+                "sources_total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.KeyReconciliation": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string"
+                },
+                "clicks": {
+                    "type": "integer"
+                },
+                "cost": {
+                    "description": "Gasto sin leads atribuibles cuando la clave es ads_only",
+                    "type": "string"
+                },
+                "opportunities": {
+                    "type": "integer"
+                },
+                "revenue": {
+                    "description": "Revenue sin gasto atribuible cuando la clave es crm_only",
                     "type": "string"
                 },
                 "status": {
+                    "description": "matched, ads_only o crm_only",
                     "type": "string"
                 },
-                "time": {
+                "suggestions": {
+                    "description": "Suggestions son las claves del otro lado más parecidas, de mayor a menor similitud",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.KeySuggestion"
+                    }
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.KeySuggestion": {
+            "type": "object",
+            "properties": {
+                "similarity": {
+                    "description": "Entre 0 y 1",
+                    "type": "number"
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
                     "type": "string"
                 }
             }
@@ -366,16 +1620,37 @@ const docTemplate = `{
         "models.MetricResponse": {
             "type": "object",
             "properties": {
+                "attribution_model": {
+                    "description": "AttributionModel es el modelo con el que se repartieron los contadores de CRM (vacío: la UTM de cada oportunidad)",
+                    "type": "string"
+                },
+                "attribution_window_days": {
+                    "description": "AttributionWindowDays es la ventana de atribución con la que se unieron CRM y ads (0: solo por UTM y día).\nCon un modelo de atribución no se aplica y vale 0.",
+                    "type": "integer"
+                },
                 "channel": {
                     "type": "string"
                 },
                 "clicks": {
                     "type": "integer"
                 },
+                "closed_lost": {
+                    "type": "number"
+                },
                 "closed_won": {
-                    "type": "integer"
+                    "type": "number"
                 },
                 "cost": {
+                    "description": "Montos en texto con MoneyScale decimales como máximo, para no perder precisión en JSON",
+                    "type": "string",
+                    "example": "1250.5"
+                },
+                "cost_per_opp": {
+                    "description": "Costo por oportunidad = cost / opportunities",
+                    "type": "number"
+                },
+                "cost_per_won": {
+                    "description": "Costo por venta ganada = cost / closed_won",
                     "type": "number"
                 },
                 "cpa": {
@@ -383,30 +1658,85 @@ const docTemplate = `{
                     "type": "number"
                 },
                 "cpc": {
+                    "description": "Cost por click = cost / clicks",
+                    "type": "number"
+                },
+                "cpm": {
+                    "description": "Costo por mil impresiones = cost / impressions * 1000",
+                    "type": "number"
+                },
+                "ctr": {
                     "description": "Métricas adicionales calculadas automáticamente a partir de los datos principales",
                     "type": "number"
                 },
+                "currency": {
+                    "description": "Currency es la moneda de reporte en la que se expresan cost, revenue y las métricas monetarias",
+                    "type": "string"
+                },
+                "cvr_lead_to_mql": {
+                    "description": "Tasas de conversión entre pasos adyacentes del embudo",
+                    "type": "number"
+                },
                 "cvr_lead_to_opp": {
                     "description": "Tasa de conversión de Lead a Opportunity",
                     "type": "number"
                 },
+                "cvr_mql_to_sql": {
+                    "description": "SQLs / MQLs",
+                    "type": "number"
+                },
                 "cvr_opp_to_won": {
                     "description": "Tasa de conversión de Opportunity a ClosedWon",
                     "type": "number"
                 },
-                "leads": {
+                "cvr_sql_to_opp": {
+                    "description": "Opportunities / SQLs",
+                    "type": "number"
+                },
+                "derived": {
+                    "description": "KPIs definidos por configuración (KPI_CONFIG), nombre → valor",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "impressions": {
                     "type": "integer"
                 },
+                "leads": {
+                    "description": "Contadores de CRM; enteros salvo con un modelo de atribución multi-touch, que los reparte entre UTMs",
+                    "type": "number"
+                },
+                "mqls": {
+                    "type": "number"
+                },
                 "opportunities": {
-                    "type": "integer"
+                    "type": "number"
                 },
                 "revenue": {
-                    "type": "number"
+                    "type": "string",
+                    "example": "4000"
                 },
                 "roas": {
                     "description": "Retorno de inversión publicitaria = revenue / cost",
                     "type": "number"
                 },
+                "sqls": {
+                    "type": "number"
+                },
+                "timezone": {
+                    "description": "Timezone es la zona de reporte (IANA) en la que se agrupan los días y se interpretan from y to",
+                    "type": "string"
+                },
+                "touchpoint_window_days": {
+                    "description": "TouchpointWindowDays son los días antes de cada oportunidad en los que el modelo buscó puntos de contacto (0: sin límite)",
+                    "type": "integer"
+                },
+                "unique_contacts": {
+                    "description": "UniqueContacts son los contactos distintos (por hash) de las oportunidades del rango con esta UTM",
+                    "type": "integer"
+                },
                 "utm_campaign": {
                     "type": "string"
                 },
@@ -417,6 +1747,139 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.QualitySummary": {
+            "type": "object",
+            "properties": {
+                "records_accepted": {
+                    "type": "integer"
+                },
+                "records_checked": {
+                    "type": "integer"
+                },
+                "records_rejected": {
+                    "type": "integer"
+                },
+                "rejections_by_rule": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "models.ReconciliationSummary": {
+            "type": "object",
+            "properties": {
+                "ads_only": {
+                    "type": "integer"
+                },
+                "crm_only": {
+                    "type": "integer"
+                },
+                "matched": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Rejection": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "record": {
+                    "description": "Registro original tal como se recibió",
+                    "type": "object"
+                },
+                "rejected_at": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ScheduleStatus": {
+            "type": "object",
+            "properties": {
+                "cron": {
+                    "type": "string"
+                },
+                "last_batch_id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_run": {
+                    "type": "string"
+                },
+                "last_status": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "next_run": {
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "since_window_days": {
+                    "type": "integer"
+                },
+                "skipped_ticks": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UTMMappingGroup": {
+            "type": "object",
+            "properties": {
+                "canonical": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "raw_values": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UTMRawMapping"
+                    }
+                },
+                "records": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UTMRawMapping": {
+            "type": "object",
+            "properties": {
+                "last_seen": {
+                    "type": "string"
+                },
+                "raw": {
+                    "type": "string"
+                },
+                "records": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
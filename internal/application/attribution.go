@@ -0,0 +1,70 @@
+package application
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+)
+
+// maxAttributionWindowDays acota ATTRIBUTION_WINDOW_DAYS a un año
+const maxAttributionWindowDays = 366
+
+// ParseAttributionWindow valida una ventana en días; 0 la desactiva
+func ParseAttributionWindow(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 || days > maxAttributionWindowDays {
+		return 0, fmt.Errorf("invalid attribution window %q: expected whole days between 0 and %d", value, maxAttributionWindowDays)
+	}
+	return days, nil
+}
+
+// LoadAttributionWindow lee ATTRIBUTION_WINDOW_DAYS (0 por defecto: sin ventana)
+func LoadAttributionWindow() (int, error) {
+	return ParseAttributionWindow(os.Getenv("ATTRIBUTION_WINDOW_DAYS"))
+}
+
+// activeAttributionWindow son los días antes de la creación de una oportunidad en los que se busca
+// actividad de ads de su UTM; con 0 el CRM se une a los ads solo por UTM y día, como sin ventana
+var activeAttributionWindow atomic.Int64
+
+// SetAttributionWindow reemplaza la ventana de atribución; se aplica al consultar, no al ingerir
+func SetAttributionWindow(days int) {
+	activeAttributionWindow.Store(int64(days))
+}
+
+// AttributionWindowDays es la ventana de atribución vigente
+func AttributionWindowDays() int {
+	return int(activeAttributionWindow.Load())
+}
+
+// ApplyAttribution reemplaza los contadores de CRM de cada UTM por los de sus oportunidades atribuidas.
+// Las UTMs que quedan sin actividad de ads ni oportunidades atribuidas se omiten.
+func ApplyAttribution(metrics map[models.UTMKey]models.AggregatedMetrics, attribution map[models.UTMKey]models.CRMAttribution) map[models.UTMKey]models.AggregatedMetrics {
+	result := make(map[models.UTMKey]models.AggregatedMetrics, len(metrics))
+	for key, m := range metrics {
+		attributed := m.WithCRM(attribution[key].Attributed)
+		if attributed.Equal(models.AggregatedMetrics{Channel: m.Channel}) {
+			continue
+		}
+		result[key] = attributed
+	}
+	return result
+}
+
+// UnattributedMetrics devuelve, por UTM, los contadores de CRM de las oportunidades sin actividad de ads en la ventana
+func UnattributedMetrics(attribution map[models.UTMKey]models.CRMAttribution) map[models.UTMKey]models.AggregatedMetrics {
+	result := make(map[models.UTMKey]models.AggregatedMetrics)
+	for key, a := range attribution {
+		if a.Unattributed.Equal(models.AggregatedMetrics{}) {
+			continue
+		}
+		result[key] = a.Unattributed
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestParseAttributionWindow(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "0", want: 0},
+		{value: "30", want: 30},
+		{value: "-1", wantErr: true},
+		{value: "7d", wantErr: true},
+		{value: "400", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseAttributionWindow(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAttributionWindow(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAttributionWindow(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyAttribution(t *testing.T) {
+	sale := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	organic := models.UTMKey{Campaign: "organic", Source: "newsletter", Medium: "email"}
+
+	metrics := map[models.UTMKey]models.AggregatedMetrics{
+		sale: {Channel: "google_ads", Clicks: 100, Cost: decimal.NewFromFloat(50),
+			Leads: 2, MQLs: 2, SQLs: 2, Opportunities: 2, ClosedWon: 2, Revenue: decimal.NewFromFloat(800)},
+		organic: {Leads: 1},
+	}
+	attribution := map[models.UTMKey]models.CRMAttribution{
+		sale: {
+			Attributed:   models.AggregatedMetrics{Leads: 1, MQLs: 1, SQLs: 1, Opportunities: 1, ClosedWon: 1, Revenue: decimal.NewFromFloat(500)},
+			Unattributed: models.AggregatedMetrics{Leads: 1, MQLs: 1, SQLs: 1, Opportunities: 1, ClosedWon: 1, Revenue: decimal.NewFromFloat(300)},
+		},
+		organic: {Unattributed: models.AggregatedMetrics{Leads: 1}},
+	}
+
+	result := ApplyAttribution(metrics, attribution)
+	want := models.AggregatedMetrics{Channel: "google_ads", Clicks: 100, Cost: decimal.NewFromFloat(50),
+		Leads: 1, MQLs: 1, SQLs: 1, Opportunities: 1, ClosedWon: 1, Revenue: decimal.NewFromFloat(500)}
+	if !result[sale].Equal(want) {
+		t.Errorf("sale = %v, want %v", result[sale], want)
+	}
+	if derived := CalculateDerivedMetrics(result[sale]); derived.ROAS != 10 {
+		t.Errorf("ROAS = %v, want 10 (solo el revenue atribuido)", derived.ROAS)
+	}
+	if _, ok := result[organic]; ok {
+		t.Errorf("organic sin ads ni oportunidades atribuidas no debería reportarse: %v", result[organic])
+	}
+
+	unattributed := UnattributedMetrics(attribution)
+	if len(unattributed) != 2 || !unattributed[sale].Revenue.Equal(decimal.NewFromFloat(300)) || unattributed[organic].Leads != 1 {
+		t.Errorf("UnattributedMetrics() = %v", unattributed)
+	}
+}
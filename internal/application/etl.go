@@ -1,124 +1,260 @@
 package application
 
 import (
+	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/m4ck-y/ETL_go/internal/domain/models"
 	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/errgroup"
 )
 
-func processAdsMetrics(ads []models.AdRecord, sinceDate *time.Time, metrics map[models.UTMKey]models.AggregatedMetrics) {
-	for _, ad := range ads {
-		if !isRecordInDateRange(ad.Date, sinceDate) {
-			continue
-		}
+// aggregateAd suma el registro a su hecho diario; cost es su costo ya convertido a la moneda de reporte
+func aggregateAd(ad models.AdRecord, cost decimal.Decimal, sinceDate *time.Time, metrics map[models.DailyKey]models.AggregatedMetrics) {
+	if !isRecordInDateRange(ad.Date, sinceDate) {
+		return
+	}
 
-		key := BuildUTMKey(ad.UTMCampaign, ad.UTMSource, ad.UTMMedium)
-		m := metrics[key]
-		// Capturar el canal del primer registro (todos los registros de la misma UTM deberían tener el mismo canal)
-		if m.Channel == "" {
-			m.Channel = ad.Channel
-		}
-		m.Clicks += ad.Clicks
-		m.Cost += ad.Cost
-		metrics[key] = m
+	key := models.DailyKey{
+		Date:   recordDay(ad.Date),
+		UTMKey: BuildUTMKey(ad.UTMCampaign, ad.UTMSource, ad.UTMMedium),
 	}
+	m := metrics[key]
+	// Capturar el canal del primer registro (todos los registros de la misma UTM deberían tener el mismo canal)
+	if m.Channel == "" {
+		m.Channel = ad.Channel
+	}
+	m.Impressions += ad.Impressions
+	m.Clicks += ad.Clicks
+	m.Cost = m.Cost.Add(cost)
+	metrics[key] = m
 }
 
-func processCRMMetrics(crms []models.CRMRecord, sinceDate *time.Time, metrics map[models.UTMKey]models.AggregatedMetrics) {
-	for _, crm := range crms {
-		if !isRecordInDateRange(crm.CreatedAt, sinceDate) {
-			continue
-		}
+// sourceAggregator valida y agrega los registros de una fuente a medida que se decodifican.
+// Solo acepta los registros del tipo declarado por la fuente; el resto se ignora.
+// Las oportunidades se deduplican por ID y se agregan al fusionar las fuentes.
+type sourceAggregator struct {
+	kind      SourceKind
+	timezone  *time.Location // Zona de los timestamps sin zona de la fuente; nil usa la de reporte
+	sinceDate *time.Time
+	// fx es la tabla de tipos de cambio de toda la ejecución: valida y convierte cada monto aunque
+	// la tabla vigente se recargue mientras tanto
+	fx            *FXTable
+	adRules       []ValidationRule[models.AdRecord]
+	crmRules      []ValidationRule[models.CRMRecord]
+	metrics       map[models.DailyKey]models.AggregatedMetrics
+	opportunities map[string]models.Opportunity
+	utm           utmObserver
+	quality       *qualityTracker
+	records       int
+}
 
-		key := BuildUTMKey(crm.UTMCampaign, crm.UTMSource, crm.UTMMedium)
-		m := metrics[key]
+func newSourceAggregator(source Source, sinceDate *time.Time, fx *FXTable) *sourceAggregator {
+	return &sourceAggregator{
+		kind:          source.Kind(),
+		timezone:      sourceTimezone(source),
+		sinceDate:     sinceDate,
+		fx:            fx,
+		adRules:       adRules(fx),
+		crmRules:      crmRules(fx),
+		metrics:       make(map[models.DailyKey]models.AggregatedMetrics),
+		opportunities: make(map[string]models.Opportunity),
+		utm:           make(utmObserver),
+		quality:       newQualityTracker(source.Name(), source.Kind()),
+	}
+}
 
-		stage := strings.ToLower(crm.Stage)
-		switch stage {
-		case "lead":
-			m.Leads++
-		case "closed_won":
-			m.ClosedWon++
-			m.Revenue += crm.Amount
-		}
+func (a *sourceAggregator) AddAd(record models.AdRecord) error {
+	if a.kind != SourceKindAds {
+		return nil
+	}
+	a.records++
+	record.Date = localizeTimestamp(record.Date, a.timezone)
+	// Los registros fuera de la ventana since no se validan: no se agregarían de todos modos
+	if isBeforeSince(record.Date, a.sinceDate) {
+		return nil
+	}
+	violation := evaluateRules(a.adRules, record)
+	var cost decimal.Decimal
+	if violation == nil {
+		cost, violation = a.fx.toReportingCurrency(record.Cost, record.Currency, record.Date)
+	}
+	if a.quality.check(record, violation) {
+		aggregateAd(record, cost, a.sinceDate, a.metrics)
+		a.utm.observe(record.UTMCampaign, record.UTMSource, record.UTMMedium)
+	}
+	return nil
+}
 
-		m.Opportunities++
-		metrics[key] = m
+func (a *sourceAggregator) AddCRM(record models.CRMRecord) error {
+	if a.kind != SourceKindCRM {
+		return nil
+	}
+	a.records++
+	record.CreatedAt = localizeTimestamp(record.CreatedAt, a.timezone)
+	record.UpdatedAt = localizeTimestamp(record.UpdatedAt, a.timezone)
+	if isBeforeSince(record.CreatedAt, a.sinceDate) {
+		return nil
+	}
+	// Las reglas ven el email; la cuarentena guarda el registro ya seudonimizado
+	violation := evaluateRules(a.crmRules, record)
+	var amount decimal.Decimal
+	if violation == nil {
+		amount, violation = a.fx.toReportingCurrency(record.Amount, record.Currency, record.CreatedAt)
+	}
+	if a.quality.check(pseudonymizeCRMRecord(record), violation) {
+		opportunity := opportunityFromRecord(record, amount)
+		keepLatest(a.opportunities, opportunity.ID, opportunity)
+		a.utm.observe(record.UTMCampaign, record.UTMSource, record.UTMMedium)
 	}
+	return nil
 }
 
-func RunETL(adsURL, crmURL string, sinceDate *time.Time) (map[models.UTMKey]models.AggregatedMetrics, error) {
+// ETLResult contiene los hechos diarios agregados y los conteos de la ejecución
+type ETLResult struct {
+	Metrics       map[models.DailyKey]models.AggregatedMetrics
+	AdsRecords    int
+	CRMRecords    int
+	SourceRecords map[string]int // Registros extraídos por nombre de fuente
+	Quality       models.QualitySummary
+	Rejections    []models.Rejection // Registros en cuarentena, sin BatchID asignado
+	// Currency es la moneda de reporte a la que se convirtieron costos y montos
+	Currency string
+	// Timezone es la zona de reporte con la que se agruparon los registros por día
+	Timezone string
+	// Opportunities es el último snapshot de cada oportunidad, ordenado por ID y sin BatchID asignado;
+	// sus contribuciones ya están sumadas en Metrics
+	Opportunities []models.Opportunity
+	// UTMMappings registra a qué valor canónico se normalizó cada valor UTM crudo de la ejecución
+	UTMMappings []models.UTMMapping
+	// Reconciliation clasifica cada clave UTM según aparezca en ads, en CRM o en ambos; solo se calcula
+	// si la ejecución incluyó fuentes de los dos tipos (si no, todas las claves serían huérfanas)
+	Reconciliation        []models.KeyReconciliation
+	ReconciliationSummary *models.ReconciliationSummary
+}
+
+// RunETL extrae de todas las fuentes en paralelo y agrega sus registros según el tipo de la fuente.
+// El primer error cancela las extracciones restantes; cancelar ctx detiene todo el proceso.
+// progress es opcional y recibe el avance por fuente.
+func RunETL(ctx context.Context, sources []Source, sinceDate *time.Time, progress ProgressReporter) (*ETLResult, error) {
+	sourceNames := make([]string, 0, len(sources))
+	for _, source := range sources {
+		sourceNames = append(sourceNames, source.Name())
+	}
 	logger.GlobalLogger.Info("Iniciando proceso ETL", "system", map[string]interface{}{
-		"ads_url":    adsURL,
-		"crm_url":    crmURL,
+		"sources":    sourceNames,
 		"since_date": sinceDate,
 	})
 
-	ads, err := fetchAds(adsURL, sinceDate)
-	if err != nil {
-		logger.GlobalLogger.Error("Error obteniendo datos de ads", "system", map[string]interface{}{
-			"ads_url": adsURL,
-			"error":   err.Error(),
-		})
-		return nil, fmt.Errorf("error obteniendo datos de ads: %w", err)
-	}
-
-	crms, err := fetchCRM(crmURL, sinceDate)
-	if err != nil {
-		logger.GlobalLogger.Error("Error obteniendo datos de crm", "system", map[string]interface{}{
-			"crm_url": crmURL,
-			"error":   err.Error(),
-		})
-		return nil, fmt.Errorf("error obteniendo datos de crm: %w", err)
-	}
+	// Todas las fuentes convierten con la misma tabla, la vigente al iniciar
+	fx := currentFX()
 
-	metrics := make(map[models.UTMKey]models.AggregatedMetrics)
+	// Cada fuente agrega en su propio mapa mientras decodifica, así la memoria depende de las
+	// combinaciones día/UTM y no del número de registros; el orden de fusión sigue al de las fuentes
+	aggregators := make([]*sourceAggregator, len(sources))
+	group, groupCtx := errgroup.WithContext(ctx)
 
-	processAdsMetrics(ads, sinceDate, metrics)
-	processCRMMetrics(crms, sinceDate, metrics)
+	for i, source := range sources {
+		group.Go(func() error {
+			if progress != nil {
+				progress.SourceStarted(source)
+			}
 
-	logger.GlobalLogger.Info("ETL completado exitosamente", "system", map[string]interface{}{
-		"ads_records":        len(ads),
-		"crm_records":        len(crms),
-		"total_combinations": len(metrics),
-	})
-
-	return metrics, nil
-}
-
-func fetchAds(url string, sinceDate *time.Time) ([]models.AdRecord, error) {
-	var response struct {
-		External struct {
-			Ads struct {
-				Performance []models.AdRecord `json:"performance"`
-			} `json:"ads"`
-		} `json:"external"`
+			aggregator := newSourceAggregator(source, sinceDate, fx)
+			if err := source.Extract(groupCtx, sinceDate, aggregator); err != nil {
+				logger.GlobalLogger.Error("Error obteniendo datos de la fuente", "system", map[string]interface{}{
+					"source": source.Name(),
+					"kind":   source.Kind(),
+					"url":    source.URL(),
+					"error":  err.Error(),
+				})
+				return fmt.Errorf("error obteniendo datos de %s: %w", source.Name(), err)
+			}
+			aggregators[i] = aggregator
+			if progress != nil {
+				progress.SourceFinished(source, aggregator.records)
+			}
+			return nil
+		})
 	}
 
-	if err := fetchData(url, &response, "ads"); err != nil {
+	if err := group.Wait(); err != nil {
 		return nil, err
 	}
 
-	records := response.External.Ads.Performance
-	return records, nil
-}
+	if progress != nil {
+		progress.Aggregating()
+	}
+
+	metrics := make(map[models.DailyKey]models.AggregatedMetrics)
+	opportunities := make(map[string]models.Opportunity)
+	utm := make(utmObserver)
+	adsByKey := make(map[models.UTMKey]models.AggregatedMetrics)
+	hasAds, hasCRM := false, false
+	sourceRecords := make(map[string]int, len(sources))
+	adsRecords, crmRecords := 0, 0
+	var quality models.QualitySummary
+	var rejections []models.Rejection
 
-func fetchCRM(url string, sinceDate *time.Time) ([]models.CRMRecord, error) {
-	var response struct {
-		External struct {
-			CRM struct {
-				Opportunities []models.CRMRecord `json:"opportunities"`
-			} `json:"crm"`
-		} `json:"external"`
+	for i, source := range sources {
+		aggregator := aggregators[i]
+		sourceRecords[source.Name()] = aggregator.records
+		switch source.Kind() {
+		case SourceKindAds:
+			adsRecords += aggregator.records
+			hasAds = true
+		case SourceKindCRM:
+			crmRecords += aggregator.records
+			hasCRM = true
+		}
+		for key, partial := range aggregator.metrics {
+			metrics[key] = metrics[key].Add(partial)
+			adsByKey[key.UTMKey] = adsByKey[key.UTMKey].Add(partial)
+		}
+		for id, opportunity := range aggregator.opportunities {
+			keepLatest(opportunities, id, opportunity)
+		}
+		utm.merge(aggregator.utm)
+		mergeQuality(&quality, aggregator.quality.summary)
+		rejections = append(rejections, aggregator.quality.rejections...)
 	}
+	addContributions(opportunities, metrics)
 
-	if err := fetchData(url, &response, "crm"); err != nil {
-		return nil, err
+	var reconciliation []models.KeyReconciliation
+	var reconciliationSummary *models.ReconciliationSummary
+	if hasAds && hasCRM {
+		crmByKey := make(map[models.UTMKey]models.AggregatedMetrics)
+		for _, opportunity := range opportunities {
+			crmByKey[opportunity.UTM] = crmByKey[opportunity.UTM].Add(opportunity.Contribution())
+		}
+		var summary models.ReconciliationSummary
+		reconciliation, summary = reconcileKeys(adsByKey, crmByKey)
+		reconciliationSummary = &summary
 	}
 
-	records := response.External.CRM.Opportunities
-	return records, nil
+	logger.GlobalLogger.Info("ETL completado exitosamente", "system", map[string]interface{}{
+		"ads_records":        adsRecords,
+		"crm_records":        crmRecords,
+		"source_records":     sourceRecords,
+		"rejected_records":   quality.RecordsRejected,
+		"opportunities":      len(opportunities),
+		"total_combinations": len(metrics),
+	})
+
+	return &ETLResult{
+		Metrics:               metrics,
+		AdsRecords:            adsRecords,
+		CRMRecords:            crmRecords,
+		SourceRecords:         sourceRecords,
+		Quality:               quality,
+		Rejections:            rejections,
+		Currency:              fx.ReportingCurrency(),
+		Timezone:              ReportingTimezone().String(),
+		Opportunities:         sortedOpportunities(opportunities),
+		UTMMappings:           utm.mappings(time.Now().UTC()),
+		Reconciliation:        reconciliation,
+		ReconciliationSummary: reconciliationSummary,
+	}, nil
 }
@@ -1,10 +1,12 @@
 package application
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
 )
 
 func TestBuildUTMKey(t *testing.T) {
@@ -185,70 +187,118 @@ func TestIsRecordInDateRange(t *testing.T) {
 	}
 }
 
-func TestProcessAdsMetrics(t *testing.T) {
+func TestRunETLAdsMetrics(t *testing.T) {
 	// Fecha de filtro: 2025-01-15
 	filterDate := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
 
 	ads := []models.AdRecord{
-		{Date: "2025-01-10", CampaignID: "C1", UTMSource: "google", UTMCampaign: "sale", UTMMedium: "cpc", Clicks: 100, Cost: 50.0},
-		{Date: "2025-01-15", CampaignID: "C2", UTMSource: "google", UTMCampaign: "sale", UTMMedium: "cpc", Clicks: 200, Cost: 100.0},
-		{Date: "2025-01-20", CampaignID: "C3", UTMSource: "google", UTMCampaign: "sale", UTMMedium: "cpc", Clicks: 150, Cost: 75.0},
+		{Date: "2025-01-10", CampaignID: "C1", UTMSource: "google", UTMCampaign: "sale", UTMMedium: "cpc", Clicks: 100, Cost: decimal.NewFromFloat(50.0)},
+		{Date: "2025-01-15", CampaignID: "C2", UTMSource: "google", UTMCampaign: "sale", UTMMedium: "cpc", Clicks: 200, Impressions: 8000, Cost: decimal.NewFromFloat(100.0)},
+		{Date: "2025-01-20", CampaignID: "C3", UTMSource: "google", UTMCampaign: "sale", UTMMedium: "cpc", Clicks: 150, Impressions: 5000, Cost: decimal.NewFromFloat(75.0)},
 	}
 
-	metrics := make(map[models.UTMKey]models.AggregatedMetrics)
+	result, err := RunETL(context.Background(), []Source{NewStaticSource("ads", SourceKindAds, Records{Ads: ads})}, &filterDate, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+	metrics := result.Metrics
 
-	processAdsMetrics(ads, &filterDate, metrics)
+	// Debería incluir solo los registros del 15 y 20 (2 registros, un hecho por día)
+	expectedClicks := 200 + 150                        // 350
+	expectedCost := decimal.NewFromFloat(100.0 + 75.0) // 175.0
 
-	// Debería incluir solo los registros del 15 y 20 (2 registros)
-	expectedClicks := 200 + 150  // 350
-	expectedCost := 100.0 + 75.0 // 175.0
+	if len(metrics) != 2 {
+		t.Errorf("Expected 2 daily keys, got %d", len(metrics))
+	}
 
-	if len(metrics) != 1 {
-		t.Errorf("Expected 1 UTM key, got %d", len(metrics))
+	utmKey := BuildUTMKey("sale", "google", "cpc")
+	day15 := metrics[models.DailyKey{Date: "2025-01-15", UTMKey: utmKey}]
+	if day15.Clicks != 200 {
+		t.Errorf("Expected 200 clicks on 2025-01-15, got %d", day15.Clicks)
 	}
 
-	for _, m := range metrics {
-		if m.Clicks != expectedClicks {
-			t.Errorf("Expected %d clicks, got %d", expectedClicks, m.Clicks)
-		}
-		if m.Cost != expectedCost {
-			t.Errorf("Expected %.2f cost, got %.2f", expectedCost, m.Cost)
+	var total models.AggregatedMetrics
+	for key, m := range metrics {
+		if key.UTMKey != utmKey {
+			t.Errorf("Unexpected UTM key %v", key.UTMKey)
 		}
+		total = total.Add(m)
+	}
+	if total.Clicks != expectedClicks {
+		t.Errorf("Expected %d clicks, got %d", expectedClicks, total.Clicks)
+	}
+	if !total.Cost.Equal(expectedCost) {
+		t.Errorf("Expected %s cost, got %s", expectedCost, total.Cost)
+	}
+	if total.Impressions != 13000 {
+		t.Errorf("Expected 13000 impressions, got %d", total.Impressions)
 	}
 }
 
-func TestProcessCRMMetrics(t *testing.T) {
+func TestRunETLCRMMetrics(t *testing.T) {
 	// Fecha de filtro: 2025-01-15
 	filterDate := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
 
 	crms := []models.CRMRecord{
-		{CreatedAt: "2025-01-10", Stage: "lead", UTMCampaign: "sale", UTMSource: "google", UTMMedium: "cpc", Amount: 1000.0},
-		{CreatedAt: "2025-01-15", Stage: "lead", UTMCampaign: "sale", UTMSource: "google", UTMMedium: "cpc", Amount: 0.0},
-		{CreatedAt: "2025-01-15", Stage: "closed_won", UTMCampaign: "sale", UTMSource: "google", UTMMedium: "cpc", Amount: 2000.0},
-		{CreatedAt: "2025-01-20", Stage: "opportunity", UTMCampaign: "sale", UTMSource: "google", UTMMedium: "cpc", Amount: 0.0},
+		{OpportunityID: "O1", CreatedAt: "2025-01-10", Stage: "lead", UTMCampaign: "sale", UTMSource: "google", UTMMedium: "cpc", Amount: decimal.NewFromFloat(1000.0)},
+		{OpportunityID: "O2", CreatedAt: "2025-01-15", Stage: "lead", UTMCampaign: "sale", UTMSource: "google", UTMMedium: "cpc", Amount: decimal.NewFromFloat(0.0)},
+		{OpportunityID: "O3", CreatedAt: "2025-01-15", Stage: "closed_won", UTMCampaign: "sale", UTMSource: "google", UTMMedium: "cpc", Amount: decimal.NewFromFloat(2000.0)},
+		{OpportunityID: "O4", CreatedAt: "2025-01-20", Stage: "opportunity", UTMCampaign: "sale", UTMSource: "google", UTMMedium: "cpc", Amount: decimal.NewFromFloat(0.0)},
 	}
 
-	metrics := make(map[models.UTMKey]models.AggregatedMetrics)
+	result, err := RunETL(context.Background(), []Source{NewStaticSource("crm", SourceKindCRM, Records{CRM: crms})}, &filterDate, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+	metrics := result.Metrics
 
-	processCRMMetrics(crms, &filterDate, metrics)
+	// Debería procesar solo los registros del 15 y 20 (un hecho por día)
+	if len(metrics) != 2 {
+		t.Errorf("Expected 2 daily keys, got %d", len(metrics))
+	}
 
-	// Debería procesar solo los registros del 15 y 20
-	if len(metrics) != 1 {
-		t.Errorf("Expected 1 UTM key, got %d", len(metrics))
+	var m models.AggregatedMetrics
+	for _, daily := range metrics {
+		m = m.Add(daily)
 	}
 
-	for _, m := range metrics {
-		if m.Leads != 1 {
-			t.Errorf("Expected 1 lead, got %d", m.Leads)
-		}
-		if m.Opportunities != 3 { // lead + closed_won + opportunity
-			t.Errorf("Expected 3 opportunities, got %d", m.Opportunities)
-		}
-		if m.ClosedWon != 1 {
-			t.Errorf("Expected 1 closed won, got %d", m.ClosedWon)
-		}
-		if m.Revenue != 2000.0 {
-			t.Errorf("Expected 2000.0 revenue, got %.2f", m.Revenue)
-		}
+	// Los pasos del embudo son acumulativos: closed_won y opportunity también pasaron por lead
+	if m.Leads != 3 {
+		t.Errorf("Expected 3 leads, got %d", m.Leads)
+	}
+	if m.Opportunities != 2 { // closed_won + opportunity
+		t.Errorf("Expected 2 opportunities, got %d", m.Opportunities)
+	}
+	if m.ClosedWon != 1 {
+		t.Errorf("Expected 1 closed won, got %d", m.ClosedWon)
+	}
+	if !m.Revenue.Equal(decimal.NewFromFloat(2000.0)) {
+		t.Errorf("Expected 2000.0 revenue, got %s", m.Revenue)
+	}
+
+	day20 := metrics[models.DailyKey{Date: "2025-01-20", UTMKey: BuildUTMKey("sale", "google", "cpc")}]
+	if day20.Opportunities != 1 {
+		t.Errorf("Expected 1 opportunity on 2025-01-20, got %d", day20.Opportunities)
+	}
+}
+
+func TestRecordDay(t *testing.T) {
+	tests := []struct {
+		name     string
+		dateStr  string
+		expected string
+	}{
+		{name: "Fecha YYYY-MM-DD", dateStr: "2025-01-15", expected: "2025-01-15"},
+		{name: "Fecha con tiempo", dateStr: "2025-01-15T23:30:00Z", expected: "2025-01-15"},
+		{name: "Fecha inválida", dateStr: "invalid-date", expected: ""},
+		{name: "Fecha vacía", dateStr: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := recordDay(tt.dateStr); result != tt.expected {
+				t.Errorf("recordDay(%q) = %q, want %q", tt.dateStr, result, tt.expected)
+			}
+		})
 	}
 }
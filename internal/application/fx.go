@@ -0,0 +1,249 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+// defaultReportingCurrency es la moneda de reporte sin REPORTING_CURRENCY
+const defaultReportingCurrency = "USD"
+
+// maxFXRatesBytes limita la respuesta de FX_RATES_URL
+const maxFXRatesBytes = 16 << 20
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// FXRatesConfig es la tabla de tipos de cambio: por fecha (YYYY-MM-DD), unidades de cada moneda por 1 de Base.
+// Es el formato habitual de las APIs de tipos de cambio, p.ej. {"base": "USD", "rates": {"2025-01-01": {"EUR": 0.92}}}.
+type FXRatesConfig struct {
+	Base  string                        `json:"base"`
+	Rates map[string]map[string]float64 `json:"rates"`
+}
+
+type datedRate struct {
+	day  time.Time
+	rate decimal.Decimal // Representación decimal más corta de la tasa configurada (0.92, no 0.9200000000000000177...)
+}
+
+// FXTable convierte montos a la moneda de reporte con el tipo de cambio vigente en la fecha del registro
+type FXTable struct {
+	reporting string
+	config    FXRatesConfig
+	rates     map[string][]datedRate // Por moneda, ordenadas por fecha
+	source    string
+	loadedAt  time.Time
+}
+
+// NewFXTable valida la tabla: códigos ISO de 3 letras, fechas YYYY-MM-DD y tasas positivas.
+// La moneda de reporte debe ser la base o tener tasas; sin tasas solo se aceptan montos en la moneda de reporte.
+func NewFXTable(reporting string, config FXRatesConfig) (*FXTable, error) {
+	reporting = normalizeCurrency(reporting)
+	if reporting == "" {
+		reporting = defaultReportingCurrency
+	}
+	if !currencyCodePattern.MatchString(reporting) {
+		return nil, fmt.Errorf("invalid reporting currency %q: expected an ISO 4217 code such as USD", reporting)
+	}
+
+	table := &FXTable{reporting: reporting, rates: make(map[string][]datedRate), loadedAt: time.Now().UTC()}
+	if len(config.Rates) == 0 {
+		table.config = FXRatesConfig{Base: normalizeCurrency(config.Base), Rates: map[string]map[string]float64{}}
+		return table, nil
+	}
+
+	base := normalizeCurrency(config.Base)
+	if !currencyCodePattern.MatchString(base) {
+		return nil, fmt.Errorf("invalid FX base currency %q", config.Base)
+	}
+	normalized := FXRatesConfig{Base: base, Rates: make(map[string]map[string]float64, len(config.Rates))}
+	for date, rates := range config.Rates {
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FX date %q: expected YYYY-MM-DD", date)
+		}
+		normalized.Rates[date] = make(map[string]float64, len(rates))
+		for currency, rate := range rates {
+			code := normalizeCurrency(currency)
+			if !currencyCodePattern.MatchString(code) {
+				return nil, fmt.Errorf("invalid currency %q on %s", currency, date)
+			}
+			if !(rate > 0) {
+				return nil, fmt.Errorf("FX rate for %s on %s must be positive, got %v", code, date, rate)
+			}
+			normalized.Rates[date][code] = rate
+			table.rates[code] = append(table.rates[code], datedRate{day: day, rate: decimal.NewFromFloat(rate)})
+		}
+	}
+	for code := range table.rates {
+		rates := table.rates[code]
+		sort.Slice(rates, func(i, j int) bool { return rates[i].day.Before(rates[j].day) })
+	}
+	if _, ok := table.rates[reporting]; !ok && reporting != base {
+		return nil, fmt.Errorf("reporting currency %s has no FX rates against base %s", reporting, base)
+	}
+	table.config = normalized
+	return table, nil
+}
+
+// DefaultFXTable reporta en USD sin tipos de cambio
+func DefaultFXTable() *FXTable {
+	table, _ := NewFXTable(defaultReportingCurrency, FXRatesConfig{})
+	return table
+}
+
+// ReportingCurrency es la moneda en la que se expresan costos y revenue agregados
+func (t *FXTable) ReportingCurrency() string { return t.reporting }
+
+// Config devuelve la tabla cargada, con los códigos normalizados
+func (t *FXTable) Config() FXRatesConfig { return t.config }
+
+// Source indica de dónde se cargó la tabla (archivo, URL o vacío si no hay tasas)
+func (t *FXTable) Source() string { return t.source }
+
+// LoadedAt es el momento en que se cargó la tabla
+func (t *FXTable) LoadedAt() time.Time { return t.loadedAt }
+
+// Convert expresa amount (en currency, moneda de reporte si está vacía) en la moneda de reporte
+// con la tasa más reciente en o antes de day. El resultado se redondea a models.MoneyScale decimales.
+func (t *FXTable) Convert(amount decimal.Decimal, currency string, day time.Time) (decimal.Decimal, error) {
+	currency = normalizeCurrency(currency)
+	if currency == "" || currency == t.reporting {
+		return models.RoundMoney(amount), nil
+	}
+	if !currencyCodePattern.MatchString(currency) {
+		return decimal.Zero, fmt.Errorf("invalid currency %q", currency)
+	}
+	from, err := t.rateOn(currency, day)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	to, err := t.rateOn(t.reporting, day)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	// Multiplicar antes de dividir: una sola división inexacta
+	return models.RoundMoney(amount.Mul(to).Div(from)), nil
+}
+
+func (t *FXTable) rateOn(currency string, day time.Time) (decimal.Decimal, error) {
+	if currency == t.config.Base {
+		return decimal.NewFromInt(1), nil
+	}
+	rates := t.rates[currency]
+	// Primera tasa posterior a day; la anterior es la vigente
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].day.After(day) })
+	if i == 0 {
+		return decimal.Zero, fmt.Errorf("no FX rate for %s on or before %s", currency, day.Format("2006-01-02"))
+	}
+	return rates[i-1].rate, nil
+}
+
+func normalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+// LoadFXTable usa REPORTING_CURRENCY (USD por defecto) y lee la tabla de FX_RATES_FILE o de FX_RATES_URL
+func LoadFXTable(ctx context.Context) (*FXTable, error) {
+	path, url := os.Getenv("FX_RATES_FILE"), os.Getenv("FX_RATES_URL")
+	if path != "" && url != "" {
+		return nil, fmt.Errorf("FX_RATES_FILE and FX_RATES_URL are mutually exclusive")
+	}
+
+	var config FXRatesConfig
+	source := ""
+	switch {
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading FX rates: %w", err)
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("error parsing FX rates: %w", err)
+		}
+		source = path
+	case url != "":
+		resp, err := retryHTTPRequest(ctx, url, defaultRetryConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch FX rates: %w", err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxFXRatesBytes)).Decode(&config); err != nil {
+			return nil, fmt.Errorf("error parsing FX rates: %w", err)
+		}
+		source = url
+	}
+
+	table, err := NewFXTable(os.Getenv("REPORTING_CURRENCY"), config)
+	if err != nil {
+		return nil, err
+	}
+	table.source = source
+	return table, nil
+}
+
+// ReloadFXTable vuelve a leer la tabla; si es inválida se conserva la vigente
+func ReloadFXTable(ctx context.Context) (*FXTable, error) {
+	table, err := LoadFXTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	SetFXTable(table)
+	return table, nil
+}
+
+// activeFX es la tabla que usan la validación y la agregación
+var activeFX atomic.Pointer[FXTable]
+
+func init() {
+	activeFX.Store(DefaultFXTable())
+}
+
+// SetFXTable reemplaza la tabla vigente; afecta a los registros que se agreguen después
+func SetFXTable(table *FXTable) {
+	activeFX.Store(table)
+}
+
+func currentFX() *FXTable {
+	return activeFX.Load()
+}
+
+// CurrentFXTable devuelve la tabla vigente
+func CurrentFXTable() *FXTable {
+	return currentFX()
+}
+
+// ReportingCurrency es la moneda de reporte vigente
+func ReportingCurrency() string {
+	return currentFX().ReportingCurrency()
+}
+
+// toReportingCurrency convierte el monto de un registro con la tasa del día del registro. Es el punto de
+// entrada de los montos al pipeline: el resultado queda redondeado a models.MoneyScale. Sin tasa el
+// registro se rechaza con convertible_currency en lugar de contar el monto como 0.
+func (t *FXTable) toReportingCurrency(amount decimal.Decimal, currency, date string) (decimal.Decimal, *RuleViolation) {
+	converted, err := t.convertOnRecordDay(amount, currency, date)
+	if err != nil {
+		return decimal.Zero, &RuleViolation{Rule: RuleConvertibleCurrency, Reason: err.Error()}
+	}
+	return converted, nil
+}
+
+// convertOnRecordDay convierte con la tasa vigente el día calendario de la fecha del registro
+func (t *FXTable) convertOnRecordDay(amount decimal.Decimal, currency, date string) (decimal.Decimal, error) {
+	day, err := time.Parse("2006-01-02", recordDay(date))
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid date %q", date)
+	}
+	return t.Convert(amount, currency, day)
+}
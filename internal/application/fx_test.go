@@ -0,0 +1,235 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+var testFXRates = FXRatesConfig{
+	Base: "USD",
+	Rates: map[string]map[string]float64{
+		"2025-01-01": {"EUR": 0.8, "MXN": 20},
+		"2025-02-01": {"EUR": 0.9, "MXN": 18},
+	},
+}
+
+func TestFXTableConvert(t *testing.T) {
+	table, err := NewFXTable("usd", testFXRates)
+	if err != nil {
+		t.Fatalf("NewFXTable() error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		day      string
+		want     string
+	}{
+		{"Sin moneda es la de reporte", "100", "", "2024-06-01", "100"},
+		{"Moneda de reporte", "100", "USD", "2024-06-01", "100"},
+		{"Tasa del mismo día", "80", "EUR", "2025-01-01", "100"},
+		{"Tasa vigente anterior", "80", "eur", "2025-01-20", "100"},
+		{"Tasa más reciente", "90", "EUR", "2025-03-15", "100"},
+		{"Otra moneda", "2000", "MXN", "2025-01-10", "100"},
+		{"Sin error de float64", "0.3", "EUR", "2025-02-01", "0.333333"},
+		{"Redondeo bancario a la micro-unidad", "10.0000025", "", "2024-06-01", "10.000002"},
+		{"Redondeo bancario hacia arriba", "10.0000035", "", "2024-06-01", "10.000004"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day, _ := time.Parse("2006-01-02", tt.day)
+			got, err := table.Convert(decimal.RequireFromString(tt.amount), tt.currency, day)
+			if err != nil {
+				t.Fatalf("Convert() error: %v", err)
+			}
+			if !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("Convert(%v %s) = %v, want %v", tt.amount, tt.currency, got, tt.want)
+			}
+		})
+	}
+
+	// Antes de la primera tasa o para una moneda sin tasas no hay conversión posible
+	day, _ := time.Parse("2006-01-02", "2024-12-31")
+	if _, err := table.Convert(decimal.NewFromInt(10), "EUR", day); err == nil || !strings.Contains(err.Error(), "no FX rate for EUR") {
+		t.Errorf("Convert() before first rate error = %v", err)
+	}
+	if _, err := table.Convert(decimal.NewFromInt(10), "GBP", day); err == nil {
+		t.Error("Expected error for currency without rates")
+	}
+}
+
+func TestFXTableCrossRate(t *testing.T) {
+	// Reportar en EUR con una tabla en base USD: MXN → USD → EUR
+	table, err := NewFXTable("EUR", testFXRates)
+	if err != nil {
+		t.Fatalf("NewFXTable() error: %v", err)
+	}
+	day, _ := time.Parse("2006-01-02", "2025-02-10")
+	got, _ := table.Convert(decimal.NewFromInt(1800), "MXN", day)
+	if !got.Equal(decimal.NewFromInt(90)) {
+		t.Errorf("Convert(1800 MXN) = %v EUR, want 90", got)
+	}
+	got, _ = table.Convert(decimal.NewFromInt(100), "USD", day)
+	if !got.Equal(decimal.NewFromInt(90)) {
+		t.Errorf("Convert(100 USD) = %v EUR, want 90", got)
+	}
+}
+
+func TestNewFXTableRejectsInvalidTables(t *testing.T) {
+	tests := []struct {
+		name      string
+		reporting string
+		config    FXRatesConfig
+		wantErr   string
+	}{
+		{"Moneda de reporte inválida", "dollars", FXRatesConfig{}, "invalid reporting currency"},
+		{"Sin base", "USD", FXRatesConfig{Rates: map[string]map[string]float64{"2025-01-01": {"EUR": 0.9}}}, "invalid FX base"},
+		{"Fecha inválida", "USD", FXRatesConfig{Base: "USD", Rates: map[string]map[string]float64{"01/01/2025": {"EUR": 0.9}}}, "invalid FX date"},
+		{"Tasa no positiva", "USD", FXRatesConfig{Base: "USD", Rates: map[string]map[string]float64{"2025-01-01": {"EUR": 0}}}, "must be positive"},
+		{"Reporte sin tasas", "GBP", testFXRates, "reporting currency GBP has no FX rates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFXTable(tt.reporting, tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewFXTable() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunETLConvertsToReportingCurrency(t *testing.T) {
+	table, err := NewFXTable("USD", testFXRates)
+	if err != nil {
+		t.Fatalf("NewFXTable() error: %v", err)
+	}
+	SetFXTable(table)
+	t.Cleanup(func() { SetFXTable(DefaultFXTable()) })
+
+	ads := NewStaticSource("ads", SourceKindAds, Records{Ads: []models.AdRecord{
+		{Date: "2025-01-15", Clicks: 10, Cost: decimal.NewFromFloat(50), UTMCampaign: "sale"},
+		{Date: "2025-01-15", Clicks: 10, Cost: decimal.NewFromFloat(40), Currency: "EUR", UTMCampaign: "sale"},
+		{Date: "2025-01-15", Clicks: 10, Cost: decimal.NewFromFloat(10), Currency: "GBP", UTMCampaign: "sale"},
+	}})
+	crm := NewStaticSource("crm", SourceKindCRM, Records{CRM: []models.CRMRecord{
+		{OpportunityID: "O1", Stage: "closed_won", Amount: decimal.NewFromFloat(4000), Currency: "MXN", CreatedAt: "2025-02-03T10:00:00Z", UTMCampaign: "sale"},
+	}})
+
+	result, err := RunETL(context.Background(), []Source{ads, crm}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+
+	key := BuildUTMKey("sale", "", "")
+	if got := result.Metrics[models.DailyKey{Date: "2025-01-15", UTMKey: key}]; !got.Cost.Equal(decimal.NewFromFloat(100)) || got.Clicks != 20 {
+		t.Errorf("Ads metrics = %+v, want cost 100 USD from 2 records", got)
+	}
+	// 4000 / 18 = 222.2222..., redondeado a la micro-unidad
+	if got := result.Opportunities[0].Amount; got.String() != "222.222222" {
+		t.Errorf("Opportunity amount = %v, want 222.222222", got)
+	}
+	if result.Currency != "USD" {
+		t.Errorf("Currency = %q, want USD", result.Currency)
+	}
+	if result.Quality.RejectionsByRule[RuleConvertibleCurrency] != 1 {
+		t.Errorf("Expected GBP record in quarantine, got %+v", result.Quality)
+	}
+}
+
+// reloadingSource recarga la tabla de tipos de cambio entre sus dos registros
+type reloadingSource struct {
+	fakeSource
+	records []models.AdRecord
+	reload  *FXTable
+}
+
+func (s reloadingSource) Extract(ctx context.Context, sinceDate *time.Time, sink RecordSink) error {
+	for i, record := range s.records {
+		if i == 1 {
+			SetFXTable(s.reload)
+		}
+		if err := sink.AddAd(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRunETLKeepsFXTableDuringRun(t *testing.T) {
+	table, err := NewFXTable("USD", testFXRates)
+	if err != nil {
+		t.Fatalf("NewFXTable() error: %v", err)
+	}
+	SetFXTable(table)
+	t.Cleanup(func() { SetFXTable(DefaultFXTable()) })
+
+	// La tabla recargada no tiene EUR: el segundo registro se convierte igual con la de la ejecución
+	ads := reloadingSource{
+		fakeSource: fakeSource{name: "ads", kind: SourceKindAds},
+		records: []models.AdRecord{
+			{Date: "2025-01-15", Clicks: 1, Cost: decimal.NewFromFloat(40), Currency: "EUR", UTMCampaign: "sale"},
+			{Date: "2025-01-15", Clicks: 1, Cost: decimal.NewFromFloat(40), Currency: "EUR", UTMCampaign: "sale"},
+		},
+		reload: DefaultFXTable(),
+	}
+
+	result, err := RunETL(context.Background(), []Source{ads}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+	key := models.DailyKey{Date: "2025-01-15", UTMKey: BuildUTMKey("sale", "", "")}
+	if got := result.Metrics[key]; !got.Cost.Equal(decimal.NewFromFloat(100)) || got.Clicks != 2 {
+		t.Errorf("Metrics[%v] = %+v, want cost 100 USD from 2 records", key, got)
+	}
+	if result.Quality.RecordsRejected != 0 {
+		t.Errorf("Expected no rejections, got %+v", result.Quality)
+	}
+}
+
+func TestLoadFXTable(t *testing.T) {
+	const rates = `{"base": "USD", "rates": {"2025-01-01": {"EUR": 0.8}}}`
+
+	path := filepath.Join(t.TempDir(), "fx.json")
+	if err := os.WriteFile(path, []byte(rates), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("REPORTING_CURRENCY", "EUR")
+	t.Setenv("FX_RATES_FILE", path)
+	t.Setenv("FX_RATES_URL", "")
+	table, err := LoadFXTable(context.Background())
+	if err != nil {
+		t.Fatalf("LoadFXTable() from file error: %v", err)
+	}
+	if table.ReportingCurrency() != "EUR" || table.Source() != path {
+		t.Errorf("LoadFXTable() = %s from %s", table.ReportingCurrency(), table.Source())
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(rates))
+	}))
+	defer server.Close()
+	t.Setenv("FX_RATES_FILE", "")
+	t.Setenv("FX_RATES_URL", server.URL)
+	table, err = LoadFXTable(context.Background())
+	if err != nil {
+		t.Fatalf("LoadFXTable() from URL error: %v", err)
+	}
+	if len(table.Config().Rates) != 1 || table.Source() != server.URL {
+		t.Errorf("LoadFXTable() from URL = %+v", table.Config())
+	}
+
+	t.Setenv("FX_RATES_FILE", path)
+	if _, err := LoadFXTable(context.Background()); err == nil {
+		t.Error("Expected error when both FX_RATES_FILE and FX_RATES_URL are set")
+	}
+}
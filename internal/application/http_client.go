@@ -2,7 +2,6 @@ package application
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"math"
@@ -19,6 +18,13 @@ type retryConfig struct {
 	maxDelay   time.Duration
 }
 
+// defaultRetryConfig se aplica a cada petición de extracción, incluida cada página por separado
+var defaultRetryConfig = retryConfig{
+	maxRetries: 3,
+	baseDelay:  1 * time.Second,
+	maxDelay:   10 * time.Second,
+}
+
 type HTTPError struct {
 	StatusCode int
 	Message    string
@@ -49,13 +55,15 @@ func isRetryableError(err error) bool {
 	return false
 }
 
-func retryHTTPRequest(url string, config retryConfig) (*http.Response, error) {
+// retryHTTPRequest reintenta con backoff exponencial; la cancelación de ctx detiene de inmediato
+// la petición en curso y la espera entre intentos
+func retryHTTPRequest(ctx context.Context, url string, config retryConfig) (*http.Response, error) {
 	var lastErr error
 
 	for attempt := 0; attempt <= config.maxRetries; attempt++ {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		attemptCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		req, err := http.NewRequestWithContext(attemptCtx, "GET", url, nil)
 		if err != nil {
 			cancel()
 			return nil, fmt.Errorf("error creating request: %w", err)
@@ -68,12 +76,18 @@ func retryHTTPRequest(url string, config retryConfig) (*http.Response, error) {
 		resp, err := client.Do(req)
 
 		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			cancel()
+			// El contexto del intento se libera al cerrar el cuerpo de la respuesta
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
 			return resp, nil
 		}
 
 		cancel()
 
+		// Si el contexto padre se canceló no tiene sentido reintentar
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("request canceled after %d attempts: %w", attempt+1, ctx.Err())
+		}
+
 		if err != nil {
 			lastErr = err
 		} else {
@@ -101,34 +115,50 @@ func retryHTTPRequest(url string, config retryConfig) (*http.Response, error) {
 				"url":         url,
 				"error":       lastErr.Error(),
 			})
-			time.Sleep(delay)
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, fmt.Errorf("request canceled after %d attempts: %w", attempt+1, ctx.Err())
+			case <-timer.C:
+			}
 		}
 	}
 
 	return nil, fmt.Errorf("request failed after %d attempts: %w", config.maxRetries+1, lastErr)
 }
 
-func fetchData(url string, target interface{}, dataType string) error {
-	config := retryConfig{
-		maxRetries: 3,
-		baseDelay:  1 * time.Second,
-		maxDelay:   10 * time.Second,
-	}
+// cancelOnClose cancela el contexto del intento cuando se termina de leer la respuesta
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
 
-	resp, err := retryHTTPRequest(url, config)
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// fetchData hace una sola petición y decodifica el cuerpo en streaming hacia sink
+func fetchData(ctx context.Context, url string, decoder Decoder, dataType string, maxBodyBytes int64, sink RecordSink) error {
+	resp, err := retryHTTPRequest(ctx, url, defaultRetryConfig)
 	if err != nil {
 		return fmt.Errorf("failed to fetch %s data: %w", dataType, err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read %s response body: %w", dataType, err)
-	}
-
-	if err := json.Unmarshal(body, target); err != nil {
+	if err := decodeBody(resp, decoder, maxBodyBytes, sink); err != nil {
 		return fmt.Errorf("failed to parse %s JSON: %w", dataType, err)
 	}
 
 	return nil
 }
+
+// decodeBody rechaza de inmediato un Content-Length mayor al límite y corta la lectura si el cuerpo lo supera
+func decodeBody(resp *http.Response, decoder Decoder, maxBodyBytes int64, sink RecordSink) error {
+	if resp.ContentLength > maxBodyBytes {
+		return fmt.Errorf("%w: content length %d exceeds %d bytes", ErrBodyTooLarge, resp.ContentLength, maxBodyBytes)
+	}
+	return decoder(limitBody(resp.Body, maxBodyBytes), sink)
+}
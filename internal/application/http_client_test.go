@@ -1,7 +1,10 @@
 package application
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -140,7 +143,7 @@ func TestRetryHTTPRequestTimeout(t *testing.T) {
 	url := "http://nonexistent-domain-that-should-fail-fast.com"
 
 	start := time.Now()
-	_, err := retryHTTPRequest(url, config)
+	_, err := retryHTTPRequest(context.Background(), url, config)
 	duration := time.Since(start)
 
 	// Debería fallar
@@ -158,3 +161,31 @@ func TestRetryHTTPRequestTimeout(t *testing.T) {
 		t.Errorf("Expected error to contain 'attempts', got: %v", err)
 	}
 }
+
+func TestRetryHTTPRequestStopsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// Backoff largo: sin cancelación el test tardaría más de un minuto
+	config := retryConfig{
+		maxRetries: 3,
+		baseDelay:  30 * time.Second,
+		maxDelay:   time.Minute,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := retryHTTPRequest(ctx, server.URL, config)
+	duration := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline error, got: %v", err)
+	}
+	if duration > 2*time.Second {
+		t.Errorf("Expected retry sleep to stop on cancellation, took %v", duration)
+	}
+}
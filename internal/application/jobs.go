@@ -0,0 +1,263 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// jobRetention es el tiempo que se conservan los jobs terminados para consulta
+const jobRetention = 24 * time.Hour
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job already finished")
+)
+
+// ProgressReporter recibe los avances de una ingestión; RunETL acepta nil
+type ProgressReporter interface {
+	SourceStarted(source Source)
+	SourceFinished(source Source, records int)
+	Aggregating()
+	Saving(combinations int)
+}
+
+// JobFunc ejecuta el trabajo de un job; debe respetar la cancelación de ctx
+type JobFunc func(ctx context.Context, progress ProgressReporter) error
+
+type jobEntry struct {
+	job    models.Job
+	cancel context.CancelFunc
+	// pendientes por tipo para derivar la fase mientras las fuentes corren en paralelo
+	pendingAds int
+	pendingCRM int
+}
+
+// JobManager ejecuta ingestiones en segundo plano y guarda su estado en memoria
+type JobManager struct {
+	jobs map[string]*jobEntry
+	mu   sync.RWMutex
+}
+
+func NewJobManager() *JobManager {
+	return &JobManager{
+		jobs: make(map[string]*jobEntry),
+	}
+}
+
+// StartOrGet lanza fn en una goroutine con un contexto propio, independiente de la petición HTTP, salvo
+// que el lote ya tenga un job en ejecución: entonces devuelve ese job y started en false
+func (m *JobManager) StartOrGet(batchID string, sourcesTotal int, fn JobFunc) (job models.Job, started bool) {
+	job, ctx, cancel, started := m.reserve(context.Background(), batchID, sourcesTotal)
+	if !started {
+		return job, false
+	}
+	go m.run(ctx, cancel, job.ID, fn)
+	return job, true
+}
+
+// RunOrGet ejecuta fn en la goroutine actual registrada como job del lote, para que ninguna otra
+// ejecución del mismo lote (síncrona o asíncrona) corra a la vez; cancelar ctx la cancela. Si el lote
+// ya tiene un job en ejecución devuelve ese job y started en false sin ejecutar fn.
+func (m *JobManager) RunOrGet(ctx context.Context, batchID string, sourcesTotal int, fn JobFunc) (job models.Job, started bool, err error) {
+	job, jobCtx, cancel, started := m.reserve(ctx, batchID, sourcesTotal)
+	if !started {
+		return job, false, nil
+	}
+	return job, true, m.run(jobCtx, cancel, job.ID, fn)
+}
+
+// reserve verifica que el lote no tenga un job en ejecución y registra uno nuevo bajo el mismo lock,
+// así dos peticiones simultáneas del mismo lote no pasan ambas la verificación
+func (m *JobManager) reserve(parent context.Context, batchID string, sourcesTotal int) (models.Job, context.Context, context.CancelFunc, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, running := m.activeJobLocked(batchID); running {
+		return job, nil, nil, false
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	entry := &jobEntry{
+		job: models.Job{
+			ID:        newJobID(),
+			BatchID:   batchID,
+			Status:    models.JobStatusRunning,
+			Phase:     models.JobPhaseQueued,
+			Progress:  models.JobProgress{SourcesTotal: sourcesTotal},
+			CreatedAt: time.Now().UTC(),
+		},
+		cancel: cancel,
+	}
+	m.pruneLocked()
+	m.jobs[entry.job.ID] = entry
+	return entry.job, ctx, cancel, true
+}
+
+func (m *JobManager) run(ctx context.Context, cancel context.CancelFunc, jobID string, fn JobFunc) error {
+	defer cancel()
+	err := fn(ctx, &jobTracker{manager: m, jobID: jobID})
+	m.finish(ctx, jobID, err)
+	return err
+}
+
+// Get devuelve una copia del estado actual del job
+func (m *JobManager) Get(jobID string) (models.Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, found := m.jobs[jobID]
+	if !found {
+		return models.Job{}, false
+	}
+	return entry.job, true
+}
+
+// ActiveJobForBatch busca un job en ejecución para el lote
+func (m *JobManager) ActiveJobForBatch(batchID string) (models.Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.activeJobLocked(batchID)
+}
+
+func (m *JobManager) activeJobLocked(batchID string) (models.Job, bool) {
+	for _, entry := range m.jobs {
+		if entry.job.BatchID == batchID && entry.job.Status == models.JobStatusRunning {
+			return entry.job, true
+		}
+	}
+	return models.Job{}, false
+}
+
+// Cancel solicita la cancelación; el job pasa a canceled cuando su goroutine termina
+func (m *JobManager) Cancel(jobID string) (models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, found := m.jobs[jobID]
+	if !found {
+		return models.Job{}, ErrJobNotFound
+	}
+	if entry.job.Status != models.JobStatusRunning {
+		return entry.job, ErrJobFinished
+	}
+
+	entry.job.CancelRequested = true
+	entry.cancel()
+	return entry.job, nil
+}
+
+func (m *JobManager) finish(ctx context.Context, jobID string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.jobs[jobID]
+	finishedAt := time.Now().UTC()
+	entry.job.FinishedAt = &finishedAt
+
+	switch {
+	case err == nil:
+		entry.job.Status = models.JobStatusCompleted
+		entry.job.Phase = models.JobPhaseDone
+	case ctx.Err() != nil && errors.Is(err, context.Canceled):
+		entry.job.Status = models.JobStatusCanceled
+		entry.job.Error = err.Error()
+	default:
+		entry.job.Status = models.JobStatusFailed
+		entry.job.Error = err.Error()
+	}
+
+	logger.GlobalLogger.Info("Job de ingestión finalizado", "system", map[string]interface{}{
+		"job_id":   jobID,
+		"batch_id": entry.job.BatchID,
+		"status":   entry.job.Status,
+		"phase":    entry.job.Phase,
+	})
+}
+
+// pruneLocked elimina los jobs terminados hace más de jobRetention
+func (m *JobManager) pruneLocked() {
+	cutoff := time.Now().Add(-jobRetention)
+	for id, entry := range m.jobs {
+		if entry.job.FinishedAt != nil && entry.job.FinishedAt.Before(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+func (m *JobManager) update(jobID string, fn func(entry *jobEntry)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, found := m.jobs[jobID]; found {
+		fn(entry)
+	}
+}
+
+// jobTracker traduce los eventos de progreso en cambios de fase y contadores del job
+type jobTracker struct {
+	manager *JobManager
+	jobID   string
+}
+
+func (t *jobTracker) SourceStarted(source Source) {
+	t.manager.update(t.jobID, func(entry *jobEntry) {
+		switch source.Kind() {
+		case SourceKindAds:
+			entry.pendingAds++
+		case SourceKindCRM:
+			entry.pendingCRM++
+		}
+		entry.job.Phase = fetchingPhase(entry)
+	})
+}
+
+func (t *jobTracker) SourceFinished(source Source, records int) {
+	t.manager.update(t.jobID, func(entry *jobEntry) {
+		switch source.Kind() {
+		case SourceKindAds:
+			entry.pendingAds--
+			entry.job.Progress.AdsRecords += records
+		case SourceKindCRM:
+			entry.pendingCRM--
+			entry.job.Progress.CRMRecords += records
+		}
+		entry.job.Progress.SourcesDone++
+		entry.job.Phase = fetchingPhase(entry)
+	})
+}
+
+func (t *jobTracker) Aggregating() {
+	t.manager.update(t.jobID, func(entry *jobEntry) {
+		entry.job.Phase = models.JobPhaseAggregating
+	})
+}
+
+func (t *jobTracker) Saving(combinations int) {
+	t.manager.update(t.jobID, func(entry *jobEntry) {
+		entry.job.Phase = models.JobPhaseSaving
+		entry.job.Progress.Combinations = combinations
+	})
+}
+
+// fetchingPhase reporta primero las fuentes de ads pendientes y luego las de CRM
+func fetchingPhase(entry *jobEntry) string {
+	switch {
+	case entry.pendingAds > 0:
+		return models.JobPhaseFetchingAds
+	case entry.pendingCRM > 0:
+		return models.JobPhaseFetchingCRM
+	default:
+		return entry.job.Phase
+	}
+}
+
+func newJobID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return fmt.Sprintf("%x", bytes)
+}
@@ -0,0 +1,208 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+)
+
+// fakeSource es una fuente mínima para reportar progreso en tests
+type fakeSource struct {
+	name string
+	kind SourceKind
+}
+
+func (s fakeSource) Name() string     { return s.name }
+func (s fakeSource) Kind() SourceKind { return s.kind }
+func (s fakeSource) URL() string      { return "fake://" + s.name }
+func (s fakeSource) Extract(ctx context.Context, sinceDate *time.Time, sink RecordSink) error {
+	return nil
+}
+
+// waitForJob espera a que el job termine o falla el test
+func waitForJob(t *testing.T, manager *JobManager, jobID string) models.Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, _ := manager.Get(jobID)
+		if job.Status != models.JobStatusRunning {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish in time", jobID)
+	return models.Job{}
+}
+
+func TestJobManagerTracksPhasesAndProgress(t *testing.T) {
+	manager := NewJobManager()
+	ads := fakeSource{name: "ads", kind: SourceKindAds}
+	crm := fakeSource{name: "crm", kind: SourceKindCRM}
+
+	// El job espera su propio ID para poder leer su fase tras cada evento
+	jobIDs := make(chan string, 1)
+	phases := make(chan string, 10)
+	job, _ := manager.StartOrGet("batch-1", 2, func(ctx context.Context, progress ProgressReporter) error {
+		jobID := <-jobIDs
+		recordPhase := func() {
+			current, _ := manager.Get(jobID)
+			phases <- current.Phase
+		}
+
+		progress.SourceStarted(ads)
+		progress.SourceStarted(crm)
+		recordPhase()
+		progress.SourceFinished(ads, 10)
+		recordPhase()
+		progress.SourceFinished(crm, 4)
+		progress.Aggregating()
+		recordPhase()
+		progress.Saving(3)
+		recordPhase()
+		return nil
+	})
+	jobIDs <- job.ID
+
+	final := waitForJob(t, manager, job.ID)
+	close(phases)
+
+	var got []string
+	for phase := range phases {
+		got = append(got, phase)
+	}
+	expected := []string{models.JobPhaseFetchingAds, models.JobPhaseFetchingCRM, models.JobPhaseAggregating, models.JobPhaseSaving}
+	if len(got) != len(expected) {
+		t.Fatalf("phases = %v, want %v", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("phase[%d] = %s, want %s", i, got[i], expected[i])
+		}
+	}
+
+	if final.Status != models.JobStatusCompleted || final.Phase != models.JobPhaseDone {
+		t.Errorf("Expected completed/done, got %s/%s", final.Status, final.Phase)
+	}
+	expectedProgress := models.JobProgress{SourcesTotal: 2, SourcesDone: 2, AdsRecords: 10, CRMRecords: 4, Combinations: 3}
+	if final.Progress != expectedProgress {
+		t.Errorf("Progress = %+v, want %+v", final.Progress, expectedProgress)
+	}
+	if final.FinishedAt == nil {
+		t.Error("Expected finished_at to be set")
+	}
+}
+
+func TestJobManagerCancel(t *testing.T) {
+	manager := NewJobManager()
+
+	started := make(chan struct{})
+	job, _ := manager.StartOrGet("batch-1", 1, func(ctx context.Context, progress ProgressReporter) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	if active, found := manager.ActiveJobForBatch("batch-1"); !found || active.ID != job.ID {
+		t.Errorf("ActiveJobForBatch() = %v, %v", active, found)
+	}
+
+	if _, err := manager.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel() unexpected error: %v", err)
+	}
+
+	final := waitForJob(t, manager, job.ID)
+	if final.Status != models.JobStatusCanceled || !final.CancelRequested {
+		t.Errorf("Expected canceled job, got %+v", final)
+	}
+
+	if _, err := manager.Cancel(job.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel() on finished job = %v, want ErrJobFinished", err)
+	}
+	if _, err := manager.Cancel("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Cancel() on missing job = %v, want ErrJobNotFound", err)
+	}
+	if _, found := manager.ActiveJobForBatch("batch-1"); found {
+		t.Error("Expected no active job after cancellation")
+	}
+}
+
+func TestJobManagerFailure(t *testing.T) {
+	manager := NewJobManager()
+
+	job, _ := manager.StartOrGet("batch-1", 1, func(ctx context.Context, progress ProgressReporter) error {
+		return errors.New("HTTP 400: Bad Request")
+	})
+
+	final := waitForJob(t, manager, job.ID)
+	if final.Status != models.JobStatusFailed || final.Error != "HTTP 400: Bad Request" {
+		t.Errorf("Expected failed job with error, got %+v", final)
+	}
+}
+
+func TestJobManagerStartsOneJobPerBatch(t *testing.T) {
+	manager := NewJobManager()
+	release := make(chan struct{})
+	blocked := func(ctx context.Context, progress ProgressReporter) error {
+		<-release
+		return nil
+	}
+
+	// Peticiones simultáneas del mismo lote: solo una lo ejecuta, el resto recibe ese job
+	var wg sync.WaitGroup
+	var startedCount atomic.Int32
+	ids := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job, started := manager.StartOrGet("batch-1", 1, blocked)
+			if started {
+				startedCount.Add(1)
+			}
+			ids <- job.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	if startedCount.Load() != 1 {
+		t.Errorf("StartOrGet() started %d jobs, want 1", startedCount.Load())
+	}
+	first := <-ids
+	for id := range ids {
+		if id != first {
+			t.Errorf("StartOrGet() returned job %s, want the running job %s", id, first)
+		}
+	}
+
+	// Una ejecución síncrona del mismo lote no corre mientras el job sigue en ejecución
+	ran := false
+	job, started, err := manager.RunOrGet(context.Background(), "batch-1", 1, func(ctx context.Context, progress ProgressReporter) error {
+		ran = true
+		return nil
+	})
+	if started || ran || err != nil || job.ID != first {
+		t.Errorf("RunOrGet() = %v, %v, %v (ran %v); want the running job without running", job.ID, started, err, ran)
+	}
+
+	close(release)
+	waitForJob(t, manager, first)
+
+	job, started, err = manager.RunOrGet(context.Background(), "batch-1", 1, func(ctx context.Context, progress ProgressReporter) error {
+		if active, found := manager.ActiveJobForBatch("batch-1"); !found || active.Status != models.JobStatusRunning {
+			t.Errorf("ActiveJobForBatch() during RunOrGet = %v, %v", active, found)
+		}
+		return nil
+	})
+	if !started || err != nil {
+		t.Fatalf("RunOrGet() after the job finished = %v, %v", started, err)
+	}
+	if final, _ := manager.Get(job.ID); final.Status != models.JobStatusCompleted {
+		t.Errorf("Expected completed sync job, got %+v", final)
+	}
+}
@@ -0,0 +1,332 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync/atomic"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+// maxKPIExpressionLength acota el tamaño de cada fórmula (y con él la profundidad del parser)
+const maxKPIExpressionLength = 512
+
+var kpiNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// kpiValues son los contadores sobre los que se evalúa una fórmula: los de ads de la combinación
+// y el crédito de CRM, entero o repartido por un modelo de atribución
+type kpiValues struct {
+	ads models.AggregatedMetrics
+	crm models.CRMCredit
+}
+
+// kpiFields son los contadores que una fórmula puede usar, con el nombre de MetricResponse
+var kpiFields = map[string]func(v kpiValues) decimal.Decimal{
+	"impressions":   func(v kpiValues) decimal.Decimal { return decimalCount(v.ads.Impressions) },
+	"clicks":        func(v kpiValues) decimal.Decimal { return decimalCount(v.ads.Clicks) },
+	"cost":          func(v kpiValues) decimal.Decimal { return v.ads.Cost },
+	"leads":         func(v kpiValues) decimal.Decimal { return v.crm.Leads },
+	"mqls":          func(v kpiValues) decimal.Decimal { return v.crm.MQLs },
+	"sqls":          func(v kpiValues) decimal.Decimal { return v.crm.SQLs },
+	"opportunities": func(v kpiValues) decimal.Decimal { return v.crm.Opportunities },
+	"closed_won":    func(v kpiValues) decimal.Decimal { return v.crm.ClosedWon },
+	"closed_lost":   func(v kpiValues) decimal.Decimal { return v.crm.ClosedLost },
+	"revenue":       func(v kpiValues) decimal.Decimal { return v.crm.Revenue },
+}
+
+// kpiExpr es una fórmula compilada; solo admite números, campos, + - * / y paréntesis.
+// Se evalúa en aritmética decimal para que "revenue - cost" sea exacto; solo el resultado pasa a float64.
+type kpiExpr func(v kpiValues) decimal.Decimal
+
+type kpiDefinition struct {
+	name string
+	eval kpiExpr
+}
+
+// KPIRegistry son las métricas derivadas definidas por configuración (nombre → fórmula)
+type KPIRegistry struct {
+	kpis []kpiDefinition
+}
+
+// NewKPIRegistry compila cada fórmula; falla ante nombres inválidos, errores de sintaxis o campos desconocidos
+func NewKPIRegistry(formulas map[string]string) (*KPIRegistry, error) {
+	names := make([]string, 0, len(formulas))
+	for name := range formulas {
+		names = append(names, name)
+	}
+	// Orden estable para que el primer error reportado no dependa del mapa
+	sort.Strings(names)
+
+	registry := &KPIRegistry{}
+	for _, name := range names {
+		if !kpiNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid KPI name %q: use lowercase letters, digits and underscores", name)
+		}
+		eval, err := compileKPIExpression(formulas[name])
+		if err != nil {
+			return nil, fmt.Errorf("KPI %q: %w", name, err)
+		}
+		registry.kpis = append(registry.kpis, kpiDefinition{name: name, eval: eval})
+	}
+	return registry, nil
+}
+
+// Evaluate calcula cada KPI; una división por cero vale 0, como safeDivide
+func (r *KPIRegistry) Evaluate(agg models.AggregatedMetrics) map[string]float64 {
+	return r.EvaluateCredited(agg, agg.CRMCredit())
+}
+
+// EvaluateCredited calcula cada KPI con los contadores de ads de agg y el crédito de CRM de credit
+func (r *KPIRegistry) EvaluateCredited(agg models.AggregatedMetrics, credit models.CRMCredit) map[string]float64 {
+	values := make(map[string]float64, len(r.kpis))
+	for _, kpi := range r.kpis {
+		values[kpi.name] = kpi.eval(kpiValues{ads: agg, crm: credit}).InexactFloat64()
+	}
+	return values
+}
+
+// Names devuelve los nombres de los KPIs registrados en orden alfabético
+func (r *KPIRegistry) Names() []string {
+	names := make([]string, 0, len(r.kpis))
+	for _, kpi := range r.kpis {
+		names = append(names, kpi.name)
+	}
+	return names
+}
+
+// LoadKPIRegistry lee KPI_CONFIG (JSON nombre → fórmula); sin variable no hay KPIs adicionales
+func LoadKPIRegistry() (*KPIRegistry, error) {
+	path := os.Getenv("KPI_CONFIG")
+	if path == "" {
+		return &KPIRegistry{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading KPI config: %w", err)
+	}
+
+	var formulas map[string]string
+	if err := json.Unmarshal(data, &formulas); err != nil {
+		return nil, fmt.Errorf("error parsing KPI config: %w", err)
+	}
+	return NewKPIRegistry(formulas)
+}
+
+// activeKPIs es el registro que usan las respuestas de métricas
+var activeKPIs atomic.Pointer[KPIRegistry]
+
+func init() {
+	activeKPIs.Store(&KPIRegistry{})
+}
+
+// SetKPIRegistry reemplaza los KPIs vigentes
+func SetKPIRegistry(registry *KPIRegistry) {
+	activeKPIs.Store(registry)
+}
+
+// EvaluateKPIs calcula los KPIs configurados para una combinación de métricas
+func EvaluateKPIs(agg models.AggregatedMetrics) map[string]float64 {
+	return activeKPIs.Load().Evaluate(agg)
+}
+
+// EvaluateCreditedKPIs calcula los KPIs configurados con el crédito de CRM de un modelo de atribución
+func EvaluateCreditedKPIs(agg models.AggregatedMetrics, credit models.CRMCredit) map[string]float64 {
+	return activeKPIs.Load().EvaluateCredited(agg, credit)
+}
+
+// compileKPIExpression analiza la fórmula con un parser descendente:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = número | campo | "(" expr ")"
+func compileKPIExpression(expression string) (kpiExpr, error) {
+	if len(expression) > maxKPIExpressionLength {
+		return nil, fmt.Errorf("expression longer than %d characters", maxKPIExpressionLength)
+	}
+	tokens, err := tokenizeKPIExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	p := &kpiParser{tokens: tokens}
+	eval, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].offset)
+	}
+	return eval, nil
+}
+
+type kpiTokenKind int
+
+const (
+	kpiNumber kpiTokenKind = iota
+	kpiIdent
+	kpiOperator
+)
+
+type kpiToken struct {
+	kind   kpiTokenKind
+	text   string
+	value  decimal.Decimal
+	offset int
+}
+
+func tokenizeKPIExpression(expression string) ([]kpiToken, error) {
+	var tokens []kpiToken
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '+' || c == '-' || c == '*' || c == '/' || c == '(' || c == ')':
+			tokens = append(tokens, kpiToken{kind: kpiOperator, text: string(c), offset: i})
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(expression) && (expression[i] >= '0' && expression[i] <= '9' || expression[i] == '.') {
+				i++
+			}
+			value, err := decimal.NewFromString(expression[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", expression[start:i], start)
+			}
+			tokens = append(tokens, kpiToken{kind: kpiNumber, text: expression[start:i], value: value, offset: start})
+		case c >= 'a' && c <= 'z' || c == '_':
+			start := i
+			for i < len(expression) && (expression[i] >= 'a' && expression[i] <= 'z' || expression[i] == '_' || expression[i] >= '0' && expression[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, kpiToken{kind: kpiIdent, text: expression[start:i], offset: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+type kpiParser struct {
+	tokens []kpiToken
+	pos    int
+}
+
+// accept consume el operador op si es el siguiente token
+func (p *kpiParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == kpiOperator && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *kpiParser) expr() (kpiExpr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("+"):
+			right, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			l := left
+			left = func(v kpiValues) decimal.Decimal { return l(v).Add(right(v)) }
+		case p.accept("-"):
+			right, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			l := left
+			left = func(v kpiValues) decimal.Decimal { return l(v).Sub(right(v)) }
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *kpiParser) term() (kpiExpr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("*"):
+			right, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			l := left
+			left = func(v kpiValues) decimal.Decimal { return l(v).Mul(right(v)) }
+		case p.accept("/"):
+			right, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			l := left
+			left = func(v kpiValues) decimal.Decimal {
+				denominator := right(v)
+				if denominator.IsZero() {
+					return decimal.Zero
+				}
+				return l(v).Div(denominator)
+			}
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *kpiParser) unary() (kpiExpr, error) {
+	if p.accept("-") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(v kpiValues) decimal.Decimal { return operand(v).Neg() }, nil
+	}
+	return p.primary()
+}
+
+func (p *kpiParser) primary() (kpiExpr, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	switch token.kind {
+	case kpiNumber:
+		p.pos++
+		return func(kpiValues) decimal.Decimal { return token.value }, nil
+	case kpiIdent:
+		field, ok := kpiFields[token.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q at position %d", token.text, token.offset)
+		}
+		p.pos++
+		return field, nil
+	}
+	if p.accept("(") {
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing closing parenthesis for '(' at position %d", token.offset)
+		}
+		return inner, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", token.text, token.offset)
+}
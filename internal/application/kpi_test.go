@@ -0,0 +1,99 @@
+package application
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestKPIRegistryEvaluate(t *testing.T) {
+	registry, err := NewKPIRegistry(map[string]string{
+		"profit":          "revenue - cost",
+		"won_per_click":   "closed_won / clicks",
+		"margin_pct":      "(revenue - cost) / revenue * 100",
+		"negative":        "-cost + -(-2)",
+		"precedence":      "1 + 2 * 3 - 4 / 2",
+		"per_thousand":    "cost / impressions * 1000",
+		"zero_impression": "clicks / (impressions - impressions)",
+	})
+	if err != nil {
+		t.Fatalf("NewKPIRegistry() error: %v", err)
+	}
+
+	got := registry.Evaluate(models.AggregatedMetrics{Clicks: 200, Cost: decimal.NewFromFloat(50), ClosedWon: 4, Revenue: decimal.NewFromFloat(250), Impressions: 10000})
+	want := map[string]float64{
+		"profit":          200,
+		"won_per_click":   0.02,
+		"margin_pct":      80,
+		"negative":        -48,
+		"precedence":      5,
+		"per_thousand":    5,
+		"zero_impression": 0, // división por cero protegida
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Evaluate() = %v, want %v", got, want)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %v, want %v", name, got[name], value)
+		}
+	}
+
+	if names := registry.Names(); names[0] != "margin_pct" || len(names) != 7 {
+		t.Errorf("Names() = %v", names)
+	}
+}
+
+func TestNewKPIRegistryRejectsInvalidFormulas(t *testing.T) {
+	tests := []struct {
+		name, expression, wantErr string
+	}{
+		{"Profit", "revenue - cost", "invalid KPI name"},
+		{"unknown", "revenue - spend", `unknown field "spend"`},
+		{"syntax", "revenue -", "unexpected end"},
+		{"trailing", "revenue cost", `unexpected "cost"`},
+		{"paren", "(revenue - cost", "missing closing parenthesis"},
+		{"chars", "revenue; os.Exit(1)", "unexpected character"},
+		{"number", "1.2.3 * cost", "invalid number"},
+		{"empty", "  ", "empty expression"},
+		{"long", strings.Repeat("1+", maxKPIExpressionLength) + "1", "expression longer"},
+	}
+	for _, tt := range tests {
+		_, err := NewKPIRegistry(map[string]string{tt.name: tt.expression})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("NewKPIRegistry(%s: %q) error = %v, want %q", tt.name, tt.expression, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadKPIRegistry(t *testing.T) {
+	t.Setenv("KPI_CONFIG", "")
+	registry, err := LoadKPIRegistry()
+	if err != nil || len(registry.Names()) != 0 {
+		t.Fatalf("LoadKPIRegistry() without config = %v, err %v", registry.Names(), err)
+	}
+
+	path := filepath.Join(t.TempDir(), "kpis.json")
+	if err := os.WriteFile(path, []byte(`{"profit": "revenue - cost"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KPI_CONFIG", path)
+	registry, err = LoadKPIRegistry()
+	if err != nil {
+		t.Fatalf("LoadKPIRegistry() error: %v", err)
+	}
+	if got := registry.Evaluate(models.AggregatedMetrics{Cost: decimal.NewFromFloat(10), Revenue: decimal.NewFromFloat(25)}); got["profit"] != 15 {
+		t.Errorf("profit = %v, want 15", got["profit"])
+	}
+
+	if err := os.WriteFile(path, []byte(`{"profit": "revenue - spend"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKPIRegistry(); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+}
@@ -0,0 +1,217 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+// FieldMapping describe de forma declarativa el formato de respuesta de una fuente
+type FieldMapping struct {
+	// RecordsPath es la ruta con puntos al arreglo de registros (p.ej. "data.rows"); vacío indica un arreglo en la raíz
+	RecordsPath string `json:"records_path"`
+	// Fields asocia cada campo destino (nombre JSON de AdRecord o CRMRecord) con la ruta con puntos
+	// del valor dentro de cada registro. Los campos no declarados se leen con su propio nombre.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+type fieldSetter[T any] func(record *T, value interface{}) error
+
+var adFieldSetters = map[string]fieldSetter[models.AdRecord]{
+	"date":         func(r *models.AdRecord, v interface{}) (err error) { r.Date, err = coerceString(v); return },
+	"campaign_id":  func(r *models.AdRecord, v interface{}) (err error) { r.CampaignID, err = coerceString(v); return },
+	"channel":      func(r *models.AdRecord, v interface{}) (err error) { r.Channel, err = coerceString(v); return },
+	"clicks":       func(r *models.AdRecord, v interface{}) (err error) { r.Clicks, err = coerceInt(v); return },
+	"impressions":  func(r *models.AdRecord, v interface{}) (err error) { r.Impressions, err = coerceInt(v); return },
+	"cost":         func(r *models.AdRecord, v interface{}) (err error) { r.Cost, err = coerceDecimal(v); return },
+	"currency":     func(r *models.AdRecord, v interface{}) (err error) { r.Currency, err = coerceString(v); return },
+	"utm_campaign": func(r *models.AdRecord, v interface{}) (err error) { r.UTMCampaign, err = coerceString(v); return },
+	"utm_source":   func(r *models.AdRecord, v interface{}) (err error) { r.UTMSource, err = coerceString(v); return },
+	"utm_medium":   func(r *models.AdRecord, v interface{}) (err error) { r.UTMMedium, err = coerceString(v); return },
+}
+
+var crmFieldSetters = map[string]fieldSetter[models.CRMRecord]{
+	"opportunity_id": func(r *models.CRMRecord, v interface{}) (err error) { r.OpportunityID, err = coerceString(v); return },
+	"contact_email":  func(r *models.CRMRecord, v interface{}) (err error) { r.ContactEmail, err = coerceString(v); return },
+	"stage":          func(r *models.CRMRecord, v interface{}) (err error) { r.Stage, err = coerceString(v); return },
+	"amount":         func(r *models.CRMRecord, v interface{}) (err error) { r.Amount, err = coerceDecimal(v); return },
+	"currency":       func(r *models.CRMRecord, v interface{}) (err error) { r.Currency, err = coerceString(v); return },
+	"created_at":     func(r *models.CRMRecord, v interface{}) (err error) { r.CreatedAt, err = coerceString(v); return },
+	"utm_campaign":   func(r *models.CRMRecord, v interface{}) (err error) { r.UTMCampaign, err = coerceString(v); return },
+	"utm_source":     func(r *models.CRMRecord, v interface{}) (err error) { r.UTMSource, err = coerceString(v); return },
+	"utm_medium":     func(r *models.CRMRecord, v interface{}) (err error) { r.UTMMedium, err = coerceString(v); return },
+	"updated_at":     func(r *models.CRMRecord, v interface{}) (err error) { r.UpdatedAt, err = coerceString(v); return },
+}
+
+// newMappedDecoder construye un decoder a partir del mapeo, validando los campos destino según el tipo de fuente
+func newMappedDecoder(kind SourceKind, mapping FieldMapping) (Decoder, error) {
+	path := splitPath(mapping.RecordsPath)
+
+	switch kind {
+	case SourceKindAds:
+		if err := validateFieldMapping(mapping.Fields, adFieldSetters); err != nil {
+			return nil, err
+		}
+		return func(body io.Reader, sink RecordSink) error {
+			return streamMappedRecords(body, path, mapping.Fields, adFieldSetters, sink.AddAd)
+		}, nil
+	case SourceKindCRM:
+		if err := validateFieldMapping(mapping.Fields, crmFieldSetters); err != nil {
+			return nil, err
+		}
+		return func(body io.Reader, sink RecordSink) error {
+			return streamMappedRecords(body, path, mapping.Fields, crmFieldSetters, sink.AddCRM)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown kind %q", kind)
+	}
+}
+
+func validateFieldMapping[T any](fields map[string]string, setters map[string]fieldSetter[T]) error {
+	for target, sourcePath := range fields {
+		if _, ok := setters[target]; !ok {
+			valid := make([]string, 0, len(setters))
+			for name := range setters {
+				valid = append(valid, name)
+			}
+			sort.Strings(valid)
+			return fmt.Errorf("unknown mapping field %q (expected one of %s)", target, strings.Join(valid, ", "))
+		}
+		if sourcePath == "" {
+			return fmt.Errorf("mapping field %q has an empty source path", target)
+		}
+	}
+	return nil
+}
+
+// streamMappedRecords decodifica cada elemento del arreglo como objeto genérico y lo convierte al registro destino
+func streamMappedRecords[T any](body io.Reader, path []string, fields map[string]string, setters map[string]fieldSetter[T], emit func(T) error) error {
+	dec := json.NewDecoder(body)
+	// UseNumber conserva los números tal cual para convertirlos sin pérdida según el campo destino
+	dec.UseNumber()
+
+	index := 0
+	return streamArray(dec, path, func(dec *json.Decoder) error {
+		var element map[string]interface{}
+		if err := dec.Decode(&element); err != nil {
+			return fmt.Errorf("record %d: %w", index, err)
+		}
+
+		record, err := mapRecord(fields, setters, func(path string) interface{} {
+			return lookupPath(element, splitPath(path))
+		})
+		if err != nil {
+			return fmt.Errorf("record %d: %w", index, err)
+		}
+		index++
+		return emit(record)
+	})
+}
+
+// mapRecord arma un registro leyendo cada campo destino con lookup; los campos no declarados en fields
+// se buscan con su propio nombre
+func mapRecord[T any](fields map[string]string, setters map[string]fieldSetter[T], lookup func(path string) interface{}) (T, error) {
+	var record T
+	for target, set := range setters {
+		sourcePath, ok := fields[target]
+		if !ok {
+			sourcePath = target
+		}
+		if err := set(&record, lookup(sourcePath)); err != nil {
+			return record, fmt.Errorf("field %s (%s): %w", target, sourcePath, err)
+		}
+	}
+	return record, nil
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// lookupPath devuelve nil si algún tramo de la ruta no existe
+func lookupPath(element map[string]interface{}, path []string) interface{} {
+	var current interface{} = element
+	for _, key := range path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[key]
+	}
+	return current
+}
+
+// coerceString acepta textos, números y booleanos; null o ausente es ""
+func coerceString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("expected string, got %T", value)
+	}
+}
+
+// coerceFloat acepta números y números como texto ("12.5"); null, ausente o "" es 0
+func coerceFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if trimmed == "" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(trimmed, 64)
+	default:
+		return 0, fmt.Errorf("expected number, got %T", value)
+	}
+}
+
+// coerceDecimal acepta los mismos valores que coerceFloat sin pasar por float64, para conservar
+// exactos los montos ("45.10" no se convierte en 45.099999...)
+func coerceDecimal(value interface{}) (decimal.Decimal, error) {
+	switch v := value.(type) {
+	case nil:
+		return decimal.Zero, nil
+	case json.Number:
+		return decimal.NewFromString(v.String())
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if trimmed == "" {
+			return decimal.Zero, nil
+		}
+		return decimal.NewFromString(trimmed)
+	default:
+		return decimal.Zero, fmt.Errorf("expected number, got %T", value)
+	}
+}
+
+// coerceInt acepta enteros, números sin parte decimal ("10.0") y sus equivalentes como texto
+func coerceInt(value interface{}) (int, error) {
+	number, err := coerceFloat(value)
+	if err != nil {
+		return 0, err
+	}
+	if number != math.Trunc(number) {
+		return 0, fmt.Errorf("expected integer, got %v", number)
+	}
+	return int(number), nil
+}
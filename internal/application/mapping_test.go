@@ -0,0 +1,90 @@
+package application
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMappedDecoderAds(t *testing.T) {
+	decoder, err := newMappedDecoder(SourceKindAds, FieldMapping{
+		RecordsPath: "data.rows",
+		Fields: map[string]string{
+			"date":         "day",
+			"clicks":       "stats.clicks",
+			"cost":         "stats.spend",
+			"utm_campaign": "tracking.campaign",
+		},
+	})
+	if err != nil {
+		t.Fatalf("newMappedDecoder() error: %v", err)
+	}
+
+	body := `{"data": {"rows": [
+		{"day": "2025-01-15", "channel": "meta", "impressions": "1000", "stats": {"clicks": "12", "spend": "45.50"}, "tracking": {"campaign": "Sale"}, "utm_source": "fb"},
+		{"day": "2025-01-16", "stats": {"clicks": 3.0, "spend": 7}, "campaign_id": 991}
+	]}}`
+
+	records := &Records{}
+	if err := decoder(strings.NewReader(body), records); err != nil {
+		t.Fatalf("decoder() error: %v", err)
+	}
+	if len(records.Ads) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records.Ads))
+	}
+
+	first := records.Ads[0]
+	if first.Date != "2025-01-15" || first.Clicks != 12 || !first.Cost.Equal(decimal.NewFromFloat(45.5)) || first.Impressions != 1000 ||
+		first.UTMCampaign != "Sale" || first.UTMSource != "fb" || first.Channel != "meta" {
+		t.Errorf("Unexpected first record: %+v", first)
+	}
+	second := records.Ads[1]
+	if second.Clicks != 3 || !second.Cost.Equal(decimal.NewFromFloat(7)) || second.CampaignID != "991" {
+		t.Errorf("Unexpected second record: %+v", second)
+	}
+}
+
+func TestMappedDecoderCRMRootArray(t *testing.T) {
+	decoder, err := newMappedDecoder(SourceKindCRM, FieldMapping{
+		Fields: map[string]string{"opportunity_id": "id", "amount": "deal.value"},
+	})
+	if err != nil {
+		t.Fatalf("newMappedDecoder() error: %v", err)
+	}
+
+	records := &Records{}
+	body := `[{"id": "O1", "stage": "closed_won", "deal": {"value": "1500.25"}, "created_at": "2025-01-15T10:00:00Z"}]`
+	if err := decoder(strings.NewReader(body), records); err != nil {
+		t.Fatalf("decoder() error: %v", err)
+	}
+	if len(records.CRM) != 1 || records.CRM[0].OpportunityID != "O1" || !records.CRM[0].Amount.Equal(decimal.NewFromFloat(1500.25)) {
+		t.Errorf("Unexpected records: %+v", records.CRM)
+	}
+}
+
+func TestMappedDecoderErrors(t *testing.T) {
+	if _, err := newMappedDecoder(SourceKindAds, FieldMapping{Fields: map[string]string{"revenue": "x"}}); err == nil {
+		t.Error("Expected error for unknown target field")
+	}
+	if _, err := newMappedDecoder(SourceKindCRM, FieldMapping{Fields: map[string]string{"stage": ""}}); err == nil {
+		t.Error("Expected error for empty source path")
+	}
+
+	decoder, _ := newMappedDecoder(SourceKindAds, FieldMapping{})
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Texto no numérico", body: `[{"clicks": "doce"}]`},
+		{name: "Entero con decimales", body: `[{"clicks": 1.5}]`},
+		{name: "Objeto en campo de texto", body: `[{"channel": {"name": "meta"}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := decoder(strings.NewReader(tt.body), &Records{}); err == nil {
+				t.Error("Expected coercion error")
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package application
 
 import (
 	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
 )
 
 // safeDivide realiza división segura protegiendo contra división por cero
@@ -12,22 +13,77 @@ func safeDivide(numerator, denominator float64) float64 {
 	return numerator / denominator
 }
 
-// CalculateDerivedMetrics calcula las métricas derivadas de CPC, CPA, CVR y ROAS
-func CalculateDerivedMetrics(agg models.AggregatedMetrics) (cpc, cpa, cvrLeadToOpp, cvrOppToWon, roas float64) {
-	// CPC = cost / clicks (proteger división por cero)
-	cpc = safeDivide(agg.Cost, float64(agg.Clicks))
-
-	// CPA = cost / leads (proteger división por cero)
-	cpa = safeDivide(agg.Cost, float64(agg.Leads))
+// safeDivideMoney divide montos exactos y convierte a float64 solo el cociente, para que los ratios
+// monetarios no arrastren el error de representar cada monto como float64. También divide crédito
+// de CRM fraccionario.
+func safeDivideMoney(numerator, denominator decimal.Decimal) float64 {
+	if denominator.IsZero() {
+		return 0.0
+	}
+	return numerator.Div(denominator).InexactFloat64()
+}
 
-	// CVR Lead to Opportunity = opportunities / leads
-	cvrLeadToOpp = safeDivide(float64(agg.Opportunities), float64(agg.Leads))
+// decimalCount expresa un contador como decimal para operar con montos
+func decimalCount(count int) decimal.Decimal {
+	return decimal.NewFromInt(int64(count))
+}
 
-	// CVR Opportunity to Won = won / opportunities
-	cvrOppToWon = safeDivide(float64(agg.ClosedWon), float64(agg.Opportunities))
+// DerivedMetrics son las métricas calculadas a partir de los contadores agregados
+type DerivedMetrics struct {
+	CTR          float64
+	CPM          float64
+	CPC          float64
+	CPA          float64
+	CVRLeadToOpp float64
+	// Conversión entre pasos adyacentes del embudo
+	CVRLeadToMQL float64
+	CVRMQLToSQL  float64
+	CVRSQLToOpp  float64
+	CVROppToWon  float64
+	CostPerOpp   float64
+	CostPerWon   float64
+	ROAS         float64
+}
 
-	// ROAS = revenue / cost
-	roas = safeDivide(agg.Revenue, agg.Cost)
+// CalculateDerivedMetrics calcula las métricas derivadas de CTR, CPM, CPC, CPA, CVR, costo por resultado y ROAS
+func CalculateDerivedMetrics(agg models.AggregatedMetrics) DerivedMetrics {
+	return DerivedMetrics{
+		// CTR = clicks / impressions
+		CTR: safeDivide(float64(agg.Clicks), float64(agg.Impressions)),
+		// CPM = cost por cada mil impresiones
+		CPM: safeDivideMoney(agg.Cost.Mul(decimalCount(1000)), decimalCount(agg.Impressions)),
+		// CPC = cost / clicks (proteger división por cero)
+		CPC: safeDivideMoney(agg.Cost, decimalCount(agg.Clicks)),
+		// CPA = cost / leads (proteger división por cero)
+		CPA: safeDivideMoney(agg.Cost, decimalCount(agg.Leads)),
+		// CVR Lead to Opportunity = opportunities / leads
+		CVRLeadToOpp: safeDivide(float64(agg.Opportunities), float64(agg.Leads)),
+		// Los pasos son acumulativos, así que cada tasa es la fracción que avanzó al paso siguiente
+		CVRLeadToMQL: safeDivide(float64(agg.MQLs), float64(agg.Leads)),
+		CVRMQLToSQL:  safeDivide(float64(agg.SQLs), float64(agg.MQLs)),
+		CVRSQLToOpp:  safeDivide(float64(agg.Opportunities), float64(agg.SQLs)),
+		// CVR Opportunity to Won = won / opportunities
+		CVROppToWon: safeDivide(float64(agg.ClosedWon), float64(agg.Opportunities)),
+		// Costo por oportunidad y por venta ganada
+		CostPerOpp: safeDivideMoney(agg.Cost, decimalCount(agg.Opportunities)),
+		CostPerWon: safeDivideMoney(agg.Cost, decimalCount(agg.ClosedWon)),
+		// ROAS = revenue / cost
+		ROAS: safeDivideMoney(agg.Revenue, agg.Cost),
+	}
+}
 
-	return cpc, cpa, cvrLeadToOpp, cvrOppToWon, roas
+// CalculateCreditedMetrics calcula las mismas métricas con los contadores de ads de agg y el crédito
+// de CRM de credit, repartido por un modelo de atribución
+func CalculateCreditedMetrics(agg models.AggregatedMetrics, credit models.CRMCredit) DerivedMetrics {
+	derived := CalculateDerivedMetrics(agg)
+	derived.CPA = safeDivideMoney(agg.Cost, credit.Leads)
+	derived.CVRLeadToOpp = safeDivideMoney(credit.Opportunities, credit.Leads)
+	derived.CVRLeadToMQL = safeDivideMoney(credit.MQLs, credit.Leads)
+	derived.CVRMQLToSQL = safeDivideMoney(credit.SQLs, credit.MQLs)
+	derived.CVRSQLToOpp = safeDivideMoney(credit.Opportunities, credit.SQLs)
+	derived.CVROppToWon = safeDivideMoney(credit.ClosedWon, credit.Opportunities)
+	derived.CostPerOpp = safeDivideMoney(agg.Cost, credit.Opportunities)
+	derived.CostPerWon = safeDivideMoney(agg.Cost, credit.ClosedWon)
+	derived.ROAS = safeDivideMoney(credit.Revenue, agg.Cost)
+	return derived
 }
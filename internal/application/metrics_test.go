@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
 )
 
 func TestSafeDivide(t *testing.T) {
@@ -76,11 +77,11 @@ func TestCalculateDerivedMetrics(t *testing.T) {
 			name: "Métricas normales",
 			metrics: models.AggregatedMetrics{
 				Clicks:        1000,
-				Cost:          500.0,
+				Cost:          decimal.NewFromFloat(500.0),
 				Leads:         50,
 				Opportunities: 30,
 				ClosedWon:     15,
-				Revenue:       7500.0,
+				Revenue:       decimal.NewFromFloat(7500.0),
 			},
 			expectedCPC:          0.5,  // 500 / 1000
 			expectedCPA:          10.0, // 500 / 50
@@ -92,11 +93,11 @@ func TestCalculateDerivedMetrics(t *testing.T) {
 			name: "Sin clics (CPC = 0)",
 			metrics: models.AggregatedMetrics{
 				Clicks:        0,
-				Cost:          100.0,
+				Cost:          decimal.NewFromFloat(100.0),
 				Leads:         10,
 				Opportunities: 5,
 				ClosedWon:     2,
-				Revenue:       500.0,
+				Revenue:       decimal.NewFromFloat(500.0),
 			},
 			expectedCPC:          0.0,  // 100 / 0 = 0 (protegido)
 			expectedCPA:          10.0, // 100 / 10
@@ -108,11 +109,11 @@ func TestCalculateDerivedMetrics(t *testing.T) {
 			name: "Sin leads (CPA = 0, CVR = 0)",
 			metrics: models.AggregatedMetrics{
 				Clicks:        500,
-				Cost:          250.0,
+				Cost:          decimal.NewFromFloat(250.0),
 				Leads:         0,
 				Opportunities: 0,
 				ClosedWon:     0,
-				Revenue:       0.0,
+				Revenue:       decimal.NewFromFloat(0.0),
 			},
 			expectedCPC:          0.5, // 250 / 500
 			expectedCPA:          0.0, // 250 / 0 = 0 (protegido)
@@ -124,11 +125,11 @@ func TestCalculateDerivedMetrics(t *testing.T) {
 			name: "Sin oportunidades (CVR = 0)",
 			metrics: models.AggregatedMetrics{
 				Clicks:        200,
-				Cost:          100.0,
+				Cost:          decimal.NewFromFloat(100.0),
 				Leads:         20,
 				Opportunities: 0,
 				ClosedWon:     0,
-				Revenue:       0.0,
+				Revenue:       decimal.NewFromFloat(0.0),
 			},
 			expectedCPC:          0.5, // 100 / 200
 			expectedCPA:          5.0, // 100 / 20
@@ -140,11 +141,11 @@ func TestCalculateDerivedMetrics(t *testing.T) {
 			name: "Sin costo (ROAS = 0, CPC = 0, CPA = 0)",
 			metrics: models.AggregatedMetrics{
 				Clicks:        100,
-				Cost:          0.0,
+				Cost:          decimal.NewFromFloat(0.0),
 				Leads:         10,
 				Opportunities: 5,
 				ClosedWon:     2,
-				Revenue:       1000.0,
+				Revenue:       decimal.NewFromFloat(1000.0),
 			},
 			expectedCPC:          0.0, // 0 / 100 = 0 (protegido)
 			expectedCPA:          0.0, // 0 / 10 = 0 (protegido)
@@ -156,11 +157,11 @@ func TestCalculateDerivedMetrics(t *testing.T) {
 			name: "Caso realista de marketing",
 			metrics: models.AggregatedMetrics{
 				Clicks:        5000,
-				Cost:          1250.0,
+				Cost:          decimal.NewFromFloat(1250.0),
 				Leads:         125,
 				Opportunities: 75,
 				ClosedWon:     30,
-				Revenue:       15000.0,
+				Revenue:       decimal.NewFromFloat(15000.0),
 			},
 			expectedCPC:          0.25, // 1250 / 5000
 			expectedCPA:          10.0, // 1250 / 125
@@ -172,59 +173,126 @@ func TestCalculateDerivedMetrics(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cpc, cpa, cvrLeadToOpp, cvrOppToWon, roas := CalculateDerivedMetrics(tt.metrics)
+			derived := CalculateDerivedMetrics(tt.metrics)
 
-			if cpc != tt.expectedCPC {
-				t.Errorf("CPC = %v, want %v", cpc, tt.expectedCPC)
+			if derived.CPC != tt.expectedCPC {
+				t.Errorf("CPC = %v, want %v", derived.CPC, tt.expectedCPC)
 			}
-			if cpa != tt.expectedCPA {
-				t.Errorf("CPA = %v, want %v", cpa, tt.expectedCPA)
+			if derived.CPA != tt.expectedCPA {
+				t.Errorf("CPA = %v, want %v", derived.CPA, tt.expectedCPA)
 			}
-			if cvrLeadToOpp != tt.expectedCVRLeadToOpp {
-				t.Errorf("CVR Lead to Opp = %v, want %v", cvrLeadToOpp, tt.expectedCVRLeadToOpp)
+			if derived.CVRLeadToOpp != tt.expectedCVRLeadToOpp {
+				t.Errorf("CVR Lead to Opp = %v, want %v", derived.CVRLeadToOpp, tt.expectedCVRLeadToOpp)
 			}
-			if cvrOppToWon != tt.expectedCVROppToWon {
-				t.Errorf("CVR Opp to Won = %v, want %v", cvrOppToWon, tt.expectedCVROppToWon)
+			if derived.CVROppToWon != tt.expectedCVROppToWon {
+				t.Errorf("CVR Opp to Won = %v, want %v", derived.CVROppToWon, tt.expectedCVROppToWon)
 			}
-			if roas != tt.expectedROAS {
-				t.Errorf("ROAS = %v, want %v", roas, tt.expectedROAS)
+			if derived.ROAS != tt.expectedROAS {
+				t.Errorf("ROAS = %v, want %v", derived.ROAS, tt.expectedROAS)
 			}
 		})
 	}
 }
 
+func TestCalculateDerivedMetricsAdsAndCostPerResult(t *testing.T) {
+	derived := CalculateDerivedMetrics(models.AggregatedMetrics{
+		Impressions:   40000,
+		Clicks:        1000,
+		Cost:          decimal.NewFromFloat(500.0),
+		Leads:         50,
+		Opportunities: 20,
+		ClosedWon:     4,
+	})
+
+	if derived.CTR != 0.025 { // 1000 / 40000
+		t.Errorf("CTR = %v, want 0.025", derived.CTR)
+	}
+	if derived.CPM != 12.5 { // 500 / 40000 * 1000
+		t.Errorf("CPM = %v, want 12.5", derived.CPM)
+	}
+	if derived.CostPerOpp != 25.0 { // 500 / 20
+		t.Errorf("CostPerOpp = %v, want 25", derived.CostPerOpp)
+	}
+	if derived.CostPerWon != 125.0 { // 500 / 4
+		t.Errorf("CostPerWon = %v, want 125", derived.CostPerWon)
+	}
+
+	// Sin impresiones ni resultados las métricas quedan en 0 (protegido)
+	derived = CalculateDerivedMetrics(models.AggregatedMetrics{Clicks: 10, Cost: decimal.NewFromFloat(100.0)})
+	if derived.CTR != 0 || derived.CPM != 0 || derived.CostPerOpp != 0 || derived.CostPerWon != 0 {
+		t.Errorf("Expected zero metrics without denominators, got %+v", derived)
+	}
+}
+
+func TestCalculateDerivedMetricsExactMoney(t *testing.T) {
+	// Tres gastos de 0.1 suman exactamente 0.3; en float64 el ROAS daría 2.9999999999999996
+	var agg models.AggregatedMetrics
+	for i := 0; i < 3; i++ {
+		agg = agg.Add(models.AggregatedMetrics{Clicks: 1, Cost: decimal.RequireFromString("0.1")})
+	}
+	agg.Revenue = decimal.RequireFromString("0.9")
+
+	if agg.Cost.String() != "0.3" {
+		t.Errorf("Cost = %s, want 0.3", agg.Cost)
+	}
+	derived := CalculateDerivedMetrics(agg)
+	if derived.ROAS != 3 {
+		t.Errorf("ROAS = %v, want 3", derived.ROAS)
+	}
+	if derived.CPC != 0.1 {
+		t.Errorf("CPC = %v, want 0.1", derived.CPC)
+	}
+}
+
 func TestCalculateDerivedMetricsEdgeCases(t *testing.T) {
 	t.Run("Métricas completamente vacías", func(t *testing.T) {
 		metrics := models.AggregatedMetrics{}
-		cpc, cpa, cvrLeadToOpp, cvrOppToWon, roas := CalculateDerivedMetrics(metrics)
+		derived := CalculateDerivedMetrics(metrics)
 
-		if cpc != 0.0 || cpa != 0.0 || cvrLeadToOpp != 0.0 || cvrOppToWon != 0.0 || roas != 0.0 {
-			t.Errorf("Expected all metrics to be 0.0, got CPC=%v, CPA=%v, CVR1=%v, CVR2=%v, ROAS=%v",
-				cpc, cpa, cvrLeadToOpp, cvrOppToWon, roas)
+		if derived != (DerivedMetrics{}) {
+			t.Errorf("Expected all metrics to be 0.0, got %+v", derived)
 		}
 	})
 
 	t.Run("Solo clics sin costo", func(t *testing.T) {
 		metrics := models.AggregatedMetrics{
 			Clicks: 1000,
-			Cost:   0.0,
+			Cost:   decimal.NewFromFloat(0.0),
 		}
-		cpc, _, _, _, _ := CalculateDerivedMetrics(metrics)
+		derived := CalculateDerivedMetrics(metrics)
 
-		if cpc != 0.0 {
-			t.Errorf("Expected CPC to be 0.0 when cost is 0, got %v", cpc)
+		if derived.CPC != 0.0 {
+			t.Errorf("Expected CPC to be 0.0 when cost is 0, got %v", derived.CPC)
 		}
 	})
 
 	t.Run("Revenue sin costo", func(t *testing.T) {
 		metrics := models.AggregatedMetrics{
-			Cost:    0.0,
-			Revenue: 10000.0,
+			Cost:    decimal.NewFromFloat(0.0),
+			Revenue: decimal.NewFromFloat(10000.0),
 		}
-		_, _, _, _, roas := CalculateDerivedMetrics(metrics)
+		derived := CalculateDerivedMetrics(metrics)
 
-		if roas != 0.0 {
-			t.Errorf("Expected ROAS to be 0.0 when cost is 0, got %v", roas)
+		if derived.ROAS != 0.0 {
+			t.Errorf("Expected ROAS to be 0.0 when cost is 0, got %v", derived.ROAS)
 		}
 	})
 }
+
+func TestCalculateDerivedMetricsFunnelSteps(t *testing.T) {
+	derived := CalculateDerivedMetrics(models.AggregatedMetrics{
+		Leads: 100, MQLs: 50, SQLs: 20, Opportunities: 10, ClosedWon: 4, ClosedLost: 3,
+	})
+
+	want := map[string][2]float64{
+		"lead→mql": {derived.CVRLeadToMQL, 0.5},
+		"mql→sql":  {derived.CVRMQLToSQL, 0.4},
+		"sql→opp":  {derived.CVRSQLToOpp, 0.5},
+		"opp→won":  {derived.CVROppToWon, 0.4},
+	}
+	for step, values := range want {
+		if values[0] != values[1] {
+			t.Errorf("CVR %s = %v, want %v", step, values[0], values[1])
+		}
+	}
+}
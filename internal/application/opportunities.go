@@ -0,0 +1,60 @@
+package application
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+// opportunityFromRecord convierte un registro de CRM validado en el snapshot de su oportunidad;
+// amount es su monto ya convertido a la moneda de reporte.
+// Un updated_at ausente o no interpretable deja UpdatedAt en nil: gana el orden de llegada.
+// Una etapa fuera de la taxonomía deja Step vacío y la oportunidad no cuenta en el embudo.
+// El email del contacto solo se conserva como su HMAC (ver HashContact), que reconoce sus puntos de contacto entre registros.
+func opportunityFromRecord(crm models.CRMRecord, amount decimal.Decimal) models.Opportunity {
+	step, _ := currentTaxonomy().Step(crm.Stage)
+	opportunity := models.Opportunity{
+		ID:          strings.TrimSpace(crm.OpportunityID),
+		Stage:       normalizeStage(crm.Stage),
+		Step:        step,
+		Amount:      amount,
+		ContactHash: HashContact(crm.ContactEmail),
+		CreatedAt:   crm.CreatedAt,
+		Day:         recordDay(crm.CreatedAt),
+		UTM:         BuildUTMKey(crm.UTMCampaign, crm.UTMSource, crm.UTMMedium),
+		IngestedAt:  time.Now().UTC(),
+	}
+	if updatedAt, err := parseRecordDate(crm.UpdatedAt); err == nil {
+		opportunity.UpdatedAt = &updatedAt
+	}
+	return opportunity
+}
+
+// keepLatest guarda opportunity bajo key salvo que el snapshot ya guardado sea más reciente
+func keepLatest(opportunities map[string]models.Opportunity, key string, opportunity models.Opportunity) {
+	if current, exists := opportunities[key]; exists && !current.SupersededBy(opportunity) {
+		return
+	}
+	opportunities[key] = opportunity
+}
+
+// addContributions suma a metrics los contadores de CRM de cada oportunidad en su hecho diario
+func addContributions(opportunities map[string]models.Opportunity, metrics map[models.DailyKey]models.AggregatedMetrics) {
+	for _, opportunity := range opportunities {
+		key := opportunity.Key()
+		metrics[key] = metrics[key].Add(opportunity.Contribution())
+	}
+}
+
+// sortedOpportunities devuelve las oportunidades ordenadas por ID para que el resultado sea determinista
+func sortedOpportunities(opportunities map[string]models.Opportunity) []models.Opportunity {
+	result := make([]models.Opportunity, 0, len(opportunities))
+	for _, opportunity := range opportunities {
+		result = append(result, opportunity)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
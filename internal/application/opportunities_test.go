@@ -0,0 +1,61 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestRunETLDeduplicatesOpportunities(t *testing.T) {
+	crm := NewStaticSource("crm", SourceKindCRM, Records{CRM: []models.CRMRecord{
+		{OpportunityID: "O1", Stage: "lead", CreatedAt: "2025-01-15", UTMCampaign: "sale"},
+		// Snapshots de O2 fuera de orden: gana el de updated_at mayor, no el último recibido
+		{OpportunityID: "O2", Stage: "Closed_Won", Amount: decimal.NewFromFloat(800), CreatedAt: "2025-01-15", UTMCampaign: "sale", UpdatedAt: "2025-01-20T10:00:00Z"},
+		{OpportunityID: "O2", Stage: "lead", CreatedAt: "2025-01-15", UTMCampaign: "sale", UpdatedAt: "2025-01-16T10:00:00Z"},
+	}})
+	// Una segunda fuente con el mismo O1 ya ganado: sin updated_at gana la fuente posterior
+	export := NewStaticSource("crm-export", SourceKindCRM, Records{CRM: []models.CRMRecord{
+		{OpportunityID: "O1", Stage: "closed_won", Amount: decimal.NewFromFloat(200), CreatedAt: "2025-01-15", UTMCampaign: "sale"},
+	}})
+
+	result, err := RunETL(context.Background(), []Source{crm, export}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+
+	if len(result.Opportunities) != 2 || result.Opportunities[0].ID != "O1" || result.Opportunities[1].Stage != "closed_won" {
+		t.Fatalf("Unexpected opportunities: %+v", result.Opportunities)
+	}
+
+	key := models.DailyKey{Date: "2025-01-15", UTMKey: BuildUTMKey("sale", "", "")}
+	want := models.AggregatedMetrics{Leads: 2, MQLs: 2, SQLs: 2, Opportunities: 2, ClosedWon: 2, Revenue: decimal.NewFromFloat(1000)}
+	if got := result.Metrics[key]; !got.Equal(want) {
+		t.Errorf("Metrics[%v] = %+v, want %+v", key, got, want)
+	}
+}
+
+func TestRunETLRejectsOpportunitiesWithoutID(t *testing.T) {
+	crm := NewStaticSource("crm", SourceKindCRM, Records{CRM: []models.CRMRecord{
+		{OpportunityID: "O1", Stage: "lead", CreatedAt: "2025-01-15"},
+		{OpportunityID: "O1", Stage: "closed_won", Amount: decimal.NewFromFloat(300), CreatedAt: "2025-01-15"},
+		// Sin ID no hay forma de deduplicar: se rechazan en lugar de contar cada uno
+		{Stage: "lead", CreatedAt: "2025-01-15"},
+		{Stage: "lead", CreatedAt: "2025-01-15"},
+	}})
+
+	result, err := RunETL(context.Background(), []Source{crm}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+
+	if got := result.Quality.RejectionsByRule[RuleRequiredOpportunity]; got != 2 {
+		t.Errorf("RejectionsByRule[%s] = %d, want 2", RuleRequiredOpportunity, got)
+	}
+	key := models.DailyKey{Date: "2025-01-15", UTMKey: BuildUTMKey("", "", "")}
+	want := models.AggregatedMetrics{Leads: 1, MQLs: 1, SQLs: 1, Opportunities: 1, ClosedWon: 1, Revenue: decimal.NewFromFloat(300)}
+	if got := result.Metrics[key]; !got.Equal(want) {
+		t.Errorf("Metrics[%v] = %+v, want %+v", key, got, want)
+	}
+}
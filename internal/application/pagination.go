@@ -0,0 +1,258 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// Estrategias de paginación soportadas por las fuentes HTTP
+const (
+	PaginationPage   = "page"   // Parámetros de query page/limit
+	PaginationCursor = "cursor" // Cursor opaco devuelto en el cuerpo
+	PaginationLink   = "link"   // Header Link con rel="next" (RFC 5988)
+)
+
+const (
+	defaultPageParam   = "page"
+	defaultLimitParam  = "limit"
+	defaultPageSize    = 100
+	defaultCursorParam = "cursor"
+	defaultCursorPath  = "next_cursor"
+	defaultMaxPages    = 100
+)
+
+// PaginationConfig declara cómo recorrer las páginas de una fuente
+type PaginationConfig struct {
+	Strategy string `json:"strategy"`
+	// Estrategia page
+	PageParam  string `json:"page_param,omitempty"`
+	LimitParam string `json:"limit_param,omitempty"`
+	PageSize   int    `json:"page_size,omitempty"`
+	StartPage  int    `json:"start_page,omitempty"`
+	// Estrategia cursor: CursorPath es la ruta con puntos al cursor en el cuerpo (p.ej. "meta.next")
+	CursorParam string `json:"cursor_param,omitempty"`
+	CursorPath  string `json:"cursor_path,omitempty"`
+	// MaxPages limita las páginas por extracción para no quedar en un ciclo infinito
+	MaxPages int `json:"max_pages,omitempty"`
+}
+
+// withDefaults valida la estrategia y completa los valores no configurados
+func (c PaginationConfig) withDefaults() (PaginationConfig, error) {
+	switch c.Strategy {
+	case PaginationPage:
+		if c.PageParam == "" {
+			c.PageParam = defaultPageParam
+		}
+		if c.LimitParam == "" {
+			c.LimitParam = defaultLimitParam
+		}
+		if c.PageSize <= 0 {
+			c.PageSize = defaultPageSize
+		}
+		if c.StartPage <= 0 {
+			c.StartPage = 1
+		}
+	case PaginationCursor:
+		if c.CursorParam == "" {
+			c.CursorParam = defaultCursorParam
+		}
+		if c.CursorPath == "" {
+			c.CursorPath = defaultCursorPath
+		}
+	case PaginationLink:
+	default:
+		return c, fmt.Errorf("unknown pagination strategy %q (expected %q, %q or %q)",
+			c.Strategy, PaginationPage, PaginationCursor, PaginationLink)
+	}
+
+	if c.MaxPages <= 0 {
+		c.MaxPages = defaultMaxPages
+	}
+	return c, nil
+}
+
+// fetchPages recorre todas las páginas de una fuente, reintentando cada página por separado.
+// Las estrategias page y link decodifican cada página en streaming; cursor necesita el cuerpo
+// completo de la página para leer el siguiente cursor, siempre acotado por maxBodyBytes.
+func fetchPages(ctx context.Context, baseURL string, decoder Decoder, dataType string, maxBodyBytes int64, config PaginationConfig, sink RecordSink) error {
+	pageURL, err := firstPageURL(baseURL, config)
+	if err != nil {
+		return fmt.Errorf("invalid %s url: %w", dataType, err)
+	}
+	pageNumber := config.StartPage
+
+	for page := 1; ; page++ {
+		if page > config.MaxPages {
+			logger.GlobalLogger.Warn("Límite de páginas alcanzado, se detiene la paginación", "system", map[string]interface{}{
+				"source":    dataType,
+				"max_pages": config.MaxPages,
+				"next_url":  pageURL,
+			})
+			return nil
+		}
+
+		resp, err := retryHTTPRequest(ctx, pageURL, defaultRetryConfig)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s data (page %d): %w", dataType, page, err)
+		}
+
+		counter := &countingSink{sink: sink}
+		var nextURL string
+		switch config.Strategy {
+		case PaginationCursor:
+			var body []byte
+			body, err = io.ReadAll(limitBody(resp.Body, maxBodyBytes))
+			if err == nil {
+				err = decoder(bytes.NewReader(body), counter)
+			}
+			if err == nil {
+				var cursor string
+				cursor, err = extractCursor(body, config.CursorPath)
+				if err == nil && cursor != "" {
+					nextURL, err = withQueryParams(baseURL, map[string]string{config.CursorParam: cursor})
+				}
+			}
+		case PaginationPage:
+			err = decodeBody(resp, decoder, maxBodyBytes, counter)
+			// Una página incompleta indica que es la última
+			if err == nil && counter.records >= config.PageSize {
+				pageNumber++
+				nextURL, err = withQueryParams(baseURL, map[string]string{
+					config.PageParam:  strconv.Itoa(pageNumber),
+					config.LimitParam: strconv.Itoa(config.PageSize),
+				})
+			}
+		case PaginationLink:
+			err = decodeBody(resp, decoder, maxBodyBytes, counter)
+			if err == nil {
+				nextURL, err = nextLinkURL(pageURL, resp.Header.Get("Link"))
+			}
+		}
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse %s JSON (page %d): %w", dataType, page, err)
+		}
+
+		// Sin siguiente página, o una fuente que repite la misma URL, termina el recorrido
+		if nextURL == "" || nextURL == pageURL {
+			return nil
+		}
+		pageURL = nextURL
+	}
+}
+
+// countingSink cuenta los registros de una página para la estrategia page
+type countingSink struct {
+	sink    RecordSink
+	records int
+}
+
+func (c *countingSink) AddAd(record models.AdRecord) error {
+	c.records++
+	return c.sink.AddAd(record)
+}
+
+func (c *countingSink) AddCRM(record models.CRMRecord) error {
+	c.records++
+	return c.sink.AddCRM(record)
+}
+
+func firstPageURL(baseURL string, config PaginationConfig) (string, error) {
+	if config.Strategy != PaginationPage {
+		return baseURL, nil
+	}
+	return withQueryParams(baseURL, map[string]string{
+		config.PageParam:  strconv.Itoa(config.StartPage),
+		config.LimitParam: strconv.Itoa(config.PageSize),
+	})
+}
+
+// withQueryParams agrega o reemplaza parámetros conservando el resto de la query
+func withQueryParams(rawURL string, params map[string]string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	query := parsed.Query()
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// extractCursor lee el cursor de la ruta con puntos; null, vacío o inexistente indican el final
+func extractCursor(body []byte, path string) (string, error) {
+	var current interface{}
+	if err := json.Unmarshal(body, &current); err != nil {
+		return "", err
+	}
+
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		current = object[part]
+	}
+
+	switch value := current.(type) {
+	case string:
+		return value, nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	default:
+		return "", nil
+	}
+}
+
+// nextLinkURL busca rel="next" en un header Link y lo resuelve relativo a la página actual
+func nextLinkURL(currentURL, header string) (string, error) {
+	if header == "" {
+		return "", nil
+	}
+
+	for _, link := range strings.Split(header, ",") {
+		segments := strings.Split(link, ";")
+		target := strings.TrimSpace(segments[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		for _, param := range segments[1:] {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+				continue
+			}
+			// rel puede listar varios valores separados por espacios: rel="next last"
+			for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+				if strings.EqualFold(rel, "next") {
+					return resolveURL(currentURL, strings.Trim(target, "<>"))
+				}
+			}
+		}
+	}
+
+	return "", nil
+}
+
+func resolveURL(baseURL, reference string) (string, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(reference)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
@@ -0,0 +1,155 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// adsPage genera una página del formato de ads con n registros
+func adsPage(n int, extra string) string {
+	rows := ""
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			rows += ","
+		}
+		rows += `{"date": "2025-01-15", "campaign_id": "C1", "clicks": 1, "cost": 1.0, "utm_campaign": "sale", "utm_source": "google", "utm_medium": "cpc"}`
+	}
+	return `{"external": {"ads": {"performance": [` + rows + `]}}` + extra + `}`
+}
+
+func newPaginatedSource(t *testing.T, url string, pagination PaginationConfig) *HTTPSource {
+	t.Helper()
+	source, err := NewHTTPSource(SourceConfig{Name: "ads", Kind: SourceKindAds, URL: url, Pagination: &pagination})
+	if err != nil {
+		t.Fatalf("NewHTTPSource() error: %v", err)
+	}
+	return source
+}
+
+func TestFetchPagesPageStrategy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("limit") != "2" || r.URL.Query().Get("account") != "42" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("p"))
+		// Páginas 1 y 2 completas, la 3 incompleta
+		sizes := map[int]int{1: 2, 2: 2, 3: 1}
+		fmt.Fprint(w, adsPage(sizes[page], ""))
+	}))
+	defer server.Close()
+
+	source := newPaginatedSource(t, server.URL+"?account=42", PaginationConfig{Strategy: PaginationPage, PageParam: "p", PageSize: 2})
+	records := &Records{}
+	if err := source.Extract(context.Background(), nil, records); err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+	if len(records.Ads) != 5 {
+		t.Errorf("Expected 5 records across pages, got %d", len(records.Ads))
+	}
+}
+
+func TestFetchPagesCursorStrategy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("after") {
+		case "":
+			fmt.Fprint(w, adsPage(2, `, "meta": {"next": "abc"}`))
+		case "abc":
+			fmt.Fprint(w, adsPage(1, `, "meta": {"next": null}`))
+		default:
+			t.Errorf("unexpected cursor %q", r.URL.Query().Get("after"))
+		}
+	}))
+	defer server.Close()
+
+	source := newPaginatedSource(t, server.URL, PaginationConfig{Strategy: PaginationCursor, CursorParam: "after", CursorPath: "meta.next"})
+	records := &Records{}
+	if err := source.Extract(context.Background(), nil, records); err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+	if len(records.Ads) != 3 {
+		t.Errorf("Expected 3 records across pages, got %d", len(records.Ads))
+	}
+}
+
+func TestFetchPagesLinkStrategyRetriesEachPage(t *testing.T) {
+	failures := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ads":
+			w.Header().Set("Link", `</ads/2>; rel="next", </ads/2>; rel="last"`)
+			fmt.Fprint(w, adsPage(1, ""))
+		case "/ads/2":
+			// La segunda página falla una vez y se reintenta sin repetir la primera
+			if failures == 0 {
+				failures++
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, adsPage(1, ""))
+		}
+	}))
+	defer server.Close()
+
+	original := defaultRetryConfig
+	defaultRetryConfig.baseDelay = 0
+	defer func() { defaultRetryConfig = original }()
+
+	source := newPaginatedSource(t, server.URL+"/ads", PaginationConfig{Strategy: PaginationLink})
+	records := &Records{}
+	if err := source.Extract(context.Background(), nil, records); err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+	if len(records.Ads) != 2 {
+		t.Errorf("Expected 2 records across pages, got %d", len(records.Ads))
+	}
+}
+
+func TestFetchPagesStopsAtMaxPages(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// Una fuente que nunca termina de paginar
+		w.Header().Set("Link", fmt.Sprintf(`<%s?n=%d>; rel="next"`, r.URL.Path, requests))
+		fmt.Fprint(w, adsPage(1, ""))
+	}))
+	defer server.Close()
+
+	source := newPaginatedSource(t, server.URL, PaginationConfig{Strategy: PaginationLink, MaxPages: 3})
+	records := &Records{}
+	if err := source.Extract(context.Background(), nil, records); err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+	if requests != 3 || len(records.Ads) != 3 {
+		t.Errorf("Expected 3 pages, got %d requests and %d records", requests, len(records.Ads))
+	}
+}
+
+func TestNextLinkURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "Sin header", header: "", expected: ""},
+		{name: "URL absoluta", header: `<https://api.example.com/ads?page=2>; rel="next"`, expected: "https://api.example.com/ads?page=2"},
+		{name: "URL relativa", header: `</ads?page=3>; rel="next"`, expected: "https://api.example.com/ads?page=3"},
+		{name: "Varios rel", header: `</ads?page=1>; rel="first", </ads?page=2>; rel="next last"`, expected: "https://api.example.com/ads?page=2"},
+		{name: "Sin next", header: `</ads?page=1>; rel="prev"`, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := nextLinkURL("https://api.example.com/ads?page=1", tt.header)
+			if err != nil {
+				t.Fatalf("nextLinkURL() error: %v", err)
+			}
+			if next != tt.expected {
+				t.Errorf("nextLinkURL() = %q, expected %q", next, tt.expected)
+			}
+		})
+	}
+}
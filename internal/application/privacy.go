@@ -0,0 +1,100 @@
+package application
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+)
+
+// minContactHashKeyLength exige al menos 128 bits de clave
+const minContactHashKeyLength = 16
+
+// ContactHasher seudonimiza emails de contacto con HMAC-SHA256: el mismo email da siempre el mismo
+// hash con la misma clave, y sin la clave no puede recuperarse ni comprobarse por fuerza bruta
+type ContactHasher struct {
+	key       []byte
+	ephemeral bool
+}
+
+// NewContactHasher valida la clave del HMAC
+func NewContactHasher(key string) (*ContactHasher, error) {
+	if len(key) < minContactHashKeyLength {
+		return nil, fmt.Errorf("contact hash key must be at least %d bytes long", minContactHashKeyLength)
+	}
+	return &ContactHasher{key: []byte(key)}, nil
+}
+
+// newEphemeralContactHasher usa una clave aleatoria que se pierde al reiniciar
+func newEphemeralContactHasher() *ContactHasher {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("crypto/rand: %v", err))
+	}
+	return &ContactHasher{key: key, ephemeral: true}
+}
+
+// Hash devuelve el HMAC en hexadecimal del email normalizado (sin espacios y en minúsculas); vacío queda vacío
+func (h *ContactHasher) Hash(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(email))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Ephemeral indica si la clave es aleatoria: los hashes no coinciden entre reinicios
+func (h *ContactHasher) Ephemeral() bool { return h.ephemeral }
+
+// LoadContactHasher lee CONTACT_HASH_KEY. Sin variable usa una clave aleatoria por proceso, salvo que el
+// repositorio persista: tras reiniciar, los hashes guardados no coincidirían y una purga no los encontraría
+func LoadContactHasher(persistent bool) (*ContactHasher, error) {
+	key := os.Getenv("CONTACT_HASH_KEY")
+	if key == "" {
+		if persistent {
+			return nil, fmt.Errorf("CONTACT_HASH_KEY is required with a persistent repository")
+		}
+		return newEphemeralContactHasher(), nil
+	}
+	return NewContactHasher(key)
+}
+
+// activeHasher seudonimiza los contactos al extraer las oportunidades
+var activeHasher atomic.Pointer[ContactHasher]
+
+func init() {
+	activeHasher.Store(newEphemeralContactHasher())
+}
+
+// SetContactHasher reemplaza la clave vigente; los contactos ya guardados conservan su hash anterior
+func SetContactHasher(hasher *ContactHasher) {
+	activeHasher.Store(hasher)
+}
+
+// HashContact seudonimiza un email con la clave vigente
+func HashContact(email string) string {
+	return activeHasher.Load().Hash(email)
+}
+
+// IsContactHash distingue un hash de HashContact de un email guardado antes de seudonimizar
+func IsContactHash(value string) bool {
+	if len(value) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// pseudonymizeCRMRecord reemplaza el email por su hash, para que el registro pueda guardarse en cuarentena
+func pseudonymizeCRMRecord(record models.CRMRecord) models.CRMRecord {
+	record.ContactEmail = HashContact(record.ContactEmail)
+	return record
+}
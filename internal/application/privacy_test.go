@@ -0,0 +1,90 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+)
+
+func setTestContactHasher(t *testing.T, key string) *ContactHasher {
+	t.Helper()
+	hasher, err := NewContactHasher(key)
+	if err != nil {
+		t.Fatalf("NewContactHasher() unexpected error: %v", err)
+	}
+	previous := activeHasher.Load()
+	SetContactHasher(hasher)
+	t.Cleanup(func() { SetContactHasher(previous) })
+	return hasher
+}
+
+func TestContactHasher(t *testing.T) {
+	if _, err := NewContactHasher("short"); err == nil {
+		t.Error("NewContactHasher() with a short key expected error")
+	}
+
+	hasher := setTestContactHasher(t, "0123456789abcdef-test")
+	hash := hasher.Hash("ana@example.com")
+	if !IsContactHash(hash) {
+		t.Errorf("Hash() = %q, want 64 hex characters", hash)
+	}
+	if got := hasher.Hash("  Ana@Example.COM "); got != hash {
+		t.Errorf("Hash() should normalize case and spaces: %q != %q", got, hash)
+	}
+	if hasher.Hash("") != "" {
+		t.Error("Hash(\"\") should stay empty")
+	}
+
+	other, _ := NewContactHasher("another-secret-key-0001")
+	if other.Hash("ana@example.com") == hash {
+		t.Error("different keys should produce different hashes")
+	}
+	if IsContactHash("ana@example.com") {
+		t.Error("IsContactHash() should reject plaintext emails")
+	}
+}
+
+func TestLoadContactHasher(t *testing.T) {
+	t.Setenv("CONTACT_HASH_KEY", "")
+	if _, err := LoadContactHasher(true); err == nil {
+		t.Error("LoadContactHasher(persistent) without a key expected error")
+	}
+	hasher, err := LoadContactHasher(false)
+	if err != nil || !hasher.Ephemeral() {
+		t.Errorf("LoadContactHasher(in memory) = %v, %v; want an ephemeral hasher", hasher, err)
+	}
+
+	t.Setenv("CONTACT_HASH_KEY", "0123456789abcdef-test")
+	hasher, err = LoadContactHasher(true)
+	if err != nil || hasher.Ephemeral() {
+		t.Errorf("LoadContactHasher(persistent) with a key = %v, %v; want a keyed hasher", hasher, err)
+	}
+}
+
+func TestRunETLPseudonymizesContacts(t *testing.T) {
+	setTestContactHasher(t, "0123456789abcdef-test")
+
+	crm := NewStaticSource("crm", SourceKindCRM, Records{CRM: []models.CRMRecord{
+		{OpportunityID: "O1", ContactEmail: "Ana@Example.com", Stage: "lead", CreatedAt: "2025-01-15", UTMCampaign: "sale"},
+		// Rechazado por la etapa: la cuarentena guarda el hash, no el email
+		{OpportunityID: "O2", ContactEmail: "luis@example.com", Stage: "unknown", CreatedAt: "2025-01-15", UTMCampaign: "sale"},
+	}})
+
+	result, err := RunETL(context.Background(), []Source{crm}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+
+	if len(result.Opportunities) != 1 || result.Opportunities[0].ContactHash != HashContact("ana@example.com") {
+		t.Fatalf("Unexpected opportunities: %+v", result.Opportunities)
+	}
+	if len(result.Rejections) != 1 {
+		t.Fatalf("Rejections = %d, want 1", len(result.Rejections))
+	}
+	record := string(result.Rejections[0].Record)
+	if strings.Contains(record, "luis@") || !strings.Contains(record, HashContact("luis@example.com")) {
+		t.Errorf("Quarantined record = %s, want the contact hash instead of the email", record)
+	}
+}
@@ -0,0 +1,164 @@
+package application
+
+import (
+	"sort"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+const (
+	// maxKeySuggestions limita las sugerencias por clave huérfana
+	maxKeySuggestions = 3
+	// minKeySimilarity descarta sugerencias demasiado distintas para ser útiles
+	minKeySimilarity = 0.5
+	// maxSimilarityComparisons acota el costo de las sugerencias (huérfanas × candidatas)
+	maxSimilarityComparisons = 1_000_000
+)
+
+// reconcileKeys clasifica cada clave UTM como matched, ads_only o crm_only y sugiere, para cada
+// huérfana, las claves del otro lado más parecidas. Devuelve las huérfanas primero, ordenadas por
+// gasto o revenue sin contraparte, y luego las matched.
+func reconcileKeys(ads, crm map[models.UTMKey]models.AggregatedMetrics) ([]models.KeyReconciliation, models.ReconciliationSummary) {
+	var summary models.ReconciliationSummary
+	keys := make([]models.KeyReconciliation, 0, len(ads)+len(crm))
+
+	add := func(key models.UTMKey, status string, m models.AggregatedMetrics) {
+		keys = append(keys, models.KeyReconciliation{
+			UTMCampaign:   key.Campaign,
+			UTMSource:     key.Source,
+			UTMMedium:     key.Medium,
+			Status:        status,
+			Clicks:        m.Clicks,
+			Cost:          m.Cost,
+			Opportunities: m.Opportunities,
+			Revenue:       m.Revenue,
+		})
+	}
+
+	for key, m := range ads {
+		if crmMetrics, ok := crm[key]; ok {
+			add(key, models.MatchStatusMatched, m.Add(crmMetrics))
+			summary.Matched++
+			continue
+		}
+		add(key, models.MatchStatusAdsOnly, m)
+		summary.AdsOnly++
+	}
+	for key, m := range crm {
+		if _, ok := ads[key]; !ok {
+			add(key, models.MatchStatusCRMOnly, m)
+			summary.CRMOnly++
+		}
+	}
+
+	orphans := summary.AdsOnly*len(crm) + summary.CRMOnly*len(ads)
+	if orphans > maxSimilarityComparisons {
+		logger.GlobalLogger.Warn("Demasiadas claves huérfanas, se omiten las sugerencias", "system", map[string]interface{}{
+			"ads_only":    summary.AdsOnly,
+			"crm_only":    summary.CRMOnly,
+			"comparisons": orphans,
+		})
+	} else {
+		adsCandidates, crmCandidates := sortedKeys(ads), sortedKeys(crm)
+		for i := range keys {
+			switch keys[i].Status {
+			case models.MatchStatusAdsOnly:
+				keys[i].Suggestions = suggestKeys(keys[i].UTM(), crmCandidates)
+			case models.MatchStatusCRMOnly:
+				keys[i].Suggestions = suggestKeys(keys[i].UTM(), adsCandidates)
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		orphanI, orphanJ := keys[i].Status != models.MatchStatusMatched, keys[j].Status != models.MatchStatusMatched
+		if orphanI != orphanJ {
+			return orphanI
+		}
+		impactI, impactJ := keys[i].Cost.Add(keys[i].Revenue), keys[j].Cost.Add(keys[j].Revenue)
+		if c := impactI.Cmp(impactJ); c != 0 {
+			return c > 0
+		}
+		return keyLess(keys[i].UTM(), keys[j].UTM())
+	})
+	return keys, summary
+}
+
+// suggestKeys devuelve las candidatas más parecidas a key (promedio de similitud por campo)
+func suggestKeys(key models.UTMKey, candidates []models.UTMKey) []models.KeySuggestion {
+	var suggestions []models.KeySuggestion
+	for _, candidate := range candidates {
+		similarity := (stringSimilarity(key.Campaign, candidate.Campaign) +
+			stringSimilarity(key.Source, candidate.Source) +
+			stringSimilarity(key.Medium, candidate.Medium)) / 3
+		if similarity < minKeySimilarity {
+			continue
+		}
+		suggestions = append(suggestions, models.KeySuggestion{
+			UTMCampaign: candidate.Campaign,
+			UTMSource:   candidate.Source,
+			UTMMedium:   candidate.Medium,
+			Similarity:  similarity,
+		})
+	}
+
+	// Orden estable: las candidatas ya vienen ordenadas, así los empates se resuelven por clave
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Similarity > suggestions[j].Similarity })
+	if len(suggestions) > maxKeySuggestions {
+		suggestions = suggestions[:maxKeySuggestions]
+	}
+	return suggestions
+}
+
+// stringSimilarity es 1 - distancia de Levenshtein / longitud mayor (1 para cadenas iguales)
+func stringSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func sortedKeys(metrics map[models.UTMKey]models.AggregatedMetrics) []models.UTMKey {
+	keys := make([]models.UTMKey, 0, len(metrics))
+	for key := range metrics {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+	return keys
+}
+
+func keyLess(a, b models.UTMKey) bool {
+	if a.Campaign != b.Campaign {
+		return a.Campaign < b.Campaign
+	}
+	if a.Source != b.Source {
+		return a.Source < b.Source
+	}
+	return a.Medium < b.Medium
+}
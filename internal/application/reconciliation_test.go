@@ -0,0 +1,107 @@
+package application
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestRunETLReconcilesKeys(t *testing.T) {
+	ads := NewStaticSource("ads", SourceKindAds, Records{Ads: []models.AdRecord{
+		{Date: "2025-01-15", Clicks: 10, Cost: decimal.NewFromFloat(20), UTMCampaign: "sale", UTMSource: "google", UTMMedium: "cpc"},
+		{Date: "2025-01-15", Clicks: 5, Cost: decimal.NewFromFloat(80), UTMCampaign: "spring_sale", UTMSource: "google", UTMMedium: "cpc"},
+	}})
+	crm := NewStaticSource("crm", SourceKindCRM, Records{CRM: []models.CRMRecord{
+		{OpportunityID: "O1", Stage: "lead", CreatedAt: "2025-01-15", UTMCampaign: "sale", UTMSource: "google", UTMMedium: "cpc"},
+		// Campaña mal escrita en el CRM: no cruza con el gasto de spring_sale
+		{OpportunityID: "O2", Stage: "closed_won", Amount: decimal.NewFromFloat(500), CreatedAt: "2025-01-15", UTMCampaign: "sprng_sale", UTMSource: "google", UTMMedium: "cpc"},
+	}})
+
+	result, err := RunETL(context.Background(), []Source{ads, crm}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+
+	want := models.ReconciliationSummary{Matched: 1, AdsOnly: 1, CRMOnly: 1}
+	if result.ReconciliationSummary == nil || *result.ReconciliationSummary != want {
+		t.Fatalf("ReconciliationSummary = %+v, want %+v", result.ReconciliationSummary, want)
+	}
+	if len(result.Reconciliation) != 3 {
+		t.Fatalf("Reconciliation = %+v", result.Reconciliation)
+	}
+
+	// Las huérfanas van primero, de mayor impacto (revenue 500 antes que gasto 80)
+	crmOnly, adsOnly, matched := result.Reconciliation[0], result.Reconciliation[1], result.Reconciliation[2]
+	if crmOnly.Status != models.MatchStatusCRMOnly || crmOnly.UTMCampaign != "sprng_sale" || !crmOnly.Revenue.Equal(decimal.NewFromFloat(500)) || crmOnly.Opportunities != 1 {
+		t.Errorf("Unexpected crm_only key: %+v", crmOnly)
+	}
+	if adsOnly.Status != models.MatchStatusAdsOnly || adsOnly.UTMCampaign != "spring_sale" || !adsOnly.Cost.Equal(decimal.NewFromFloat(80)) || adsOnly.Clicks != 5 {
+		t.Errorf("Unexpected ads_only key: %+v", adsOnly)
+	}
+	if matched.Status != models.MatchStatusMatched || !matched.Cost.Equal(decimal.NewFromFloat(20)) || matched.Opportunities != 0 || len(matched.Suggestions) != 0 {
+		t.Errorf("Unexpected matched key: %+v", matched)
+	}
+
+	// La sugerencia más parecida de cada huérfana es su contraparte mal escrita
+	if len(crmOnly.Suggestions) == 0 || crmOnly.Suggestions[0].UTMCampaign != "spring_sale" {
+		t.Errorf("crm_only suggestions = %+v", crmOnly.Suggestions)
+	}
+	if len(adsOnly.Suggestions) == 0 || adsOnly.Suggestions[0].UTMCampaign != "sprng_sale" {
+		t.Errorf("ads_only suggestions = %+v", adsOnly.Suggestions)
+	}
+}
+
+func TestRunETLSkipsReconciliationForSingleKind(t *testing.T) {
+	ads := NewStaticSource("ads", SourceKindAds, Records{Ads: []models.AdRecord{
+		{Date: "2025-01-15", Clicks: 10, Cost: decimal.NewFromFloat(20), UTMCampaign: "sale"},
+	}})
+
+	result, err := RunETL(context.Background(), []Source{ads}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+	// Sin CRM en la ejecución todo el gasto sería ads_only: no se concilia
+	if result.ReconciliationSummary != nil || result.Reconciliation != nil {
+		t.Errorf("Expected no reconciliation, got %+v", result.ReconciliationSummary)
+	}
+}
+
+func TestSuggestKeys(t *testing.T) {
+	candidates := []models.UTMKey{
+		{Campaign: "black_friday", Source: "meta", Medium: "paid_social"},
+		{Campaign: "spring_sale", Source: "google", Medium: "cpc"},
+		{Campaign: "spring_sale", Source: "google", Medium: "display"},
+	}
+
+	got := suggestKeys(models.UTMKey{Campaign: "spring-sale", Source: "google", Medium: "cpc"}, candidates)
+	if len(got) != 2 {
+		t.Fatalf("suggestKeys() = %+v, want 2 suggestions", got)
+	}
+	if got[0].UTMMedium != "cpc" || got[1].UTMMedium != "display" {
+		t.Errorf("suggestKeys() order = %+v", got)
+	}
+	if got[0].Similarity <= got[1].Similarity || got[0].Similarity >= 1 {
+		t.Errorf("Unexpected similarities: %+v", got)
+	}
+}
+
+func TestStringSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"cpc", "cpc", 1},
+		{"", "", 1},
+		{"cpc", "", 0},
+		{"sprng_sale", "spring_sale", 1 - 1.0/11},
+		{"kitten", "sitting", 1 - 3.0/7},
+	}
+	for _, tt := range tests {
+		if got := stringSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("stringSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
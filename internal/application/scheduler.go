@@ -0,0 +1,226 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// Estados de la última ejecución de una programación
+const (
+	ScheduleRunCompleted = "completed"
+	ScheduleRunFailed    = "failed"
+)
+
+// ScheduleConfig declara una ingestión recurrente
+type ScheduleConfig struct {
+	Name string `json:"name"`
+	// Cron acepta expresiones de 5 campos ("0 * * * *") y descriptores ("@hourly", "@every 30m")
+	Cron string `json:"cron"`
+	// SinceWindowDays filtra cada ejecución a los últimos N días; 0 ingiere todo
+	SinceWindowDays int `json:"since_window_days,omitempty"`
+}
+
+// ScheduledIngestFunc ejecuta una ingestión programada y devuelve el ID del lote procesado
+type ScheduledIngestFunc func(ctx context.Context, scheduleName, sinceParam string, tick time.Time) (string, error)
+
+type schedule struct {
+	config  ScheduleConfig
+	entryID cron.EntryID
+
+	mu      sync.Mutex
+	running bool
+	status  models.ScheduleStatus
+}
+
+// Scheduler dispara ingestiones según expresiones cron, sin solapar ejecuciones de una misma programación
+type Scheduler struct {
+	cron      *cron.Cron
+	run       ScheduledIngestFunc
+	schedules []*schedule
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewScheduler valida las expresiones cron y registra cada programación
+func NewScheduler(configs []ScheduleConfig, run ScheduledIngestFunc) (*Scheduler, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		cron:   cron.New(),
+		run:    run,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	names := make(map[string]bool)
+	for _, config := range configs {
+		if config.Name == "" {
+			cancel()
+			return nil, fmt.Errorf("schedule name is required")
+		}
+		if names[config.Name] {
+			cancel()
+			return nil, fmt.Errorf("schedule %s already registered", config.Name)
+		}
+		if config.SinceWindowDays < 0 {
+			cancel()
+			return nil, fmt.Errorf("schedule %s: since_window_days must be >= 0", config.Name)
+		}
+		names[config.Name] = true
+
+		sch := &schedule{
+			config: config,
+			status: models.ScheduleStatus{
+				Name:            config.Name,
+				Cron:            config.Cron,
+				SinceWindowDays: config.SinceWindowDays,
+			},
+		}
+		entryID, err := s.cron.AddFunc(config.Cron, func() { s.fire(sch) })
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("schedule %s: invalid cron expression %q: %w", config.Name, config.Cron, err)
+		}
+		sch.entryID = entryID
+		s.schedules = append(s.schedules, sch)
+	}
+
+	return s, nil
+}
+
+// Start inicia el reloj del scheduler en segundo plano
+func (s *Scheduler) Start() {
+	s.cron.Start()
+	for _, status := range s.Status() {
+		logger.GlobalLogger.Info("Ingestión programada registrada", "system", map[string]interface{}{
+			"schedule":          status.Name,
+			"cron":              status.Cron,
+			"since_window_days": status.SinceWindowDays,
+			"next_run":          status.NextRun,
+		})
+	}
+}
+
+// Stop detiene nuevos disparos y cancela las ejecuciones en curso
+func (s *Scheduler) Stop() {
+	s.cancel()
+	<-s.cron.Stop().Done()
+}
+
+// Status devuelve cada programación con su próxima ejecución y el resultado de la última
+func (s *Scheduler) Status() []models.ScheduleStatus {
+	statuses := make([]models.ScheduleStatus, 0, len(s.schedules))
+	for _, sch := range s.schedules {
+		sch.mu.Lock()
+		status := sch.status
+		status.Running = sch.running
+		sch.mu.Unlock()
+
+		if next := s.cron.Entry(sch.entryID).Next; !next.IsZero() {
+			status.NextRun = &next
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// fire ejecuta una programación; si la ejecución anterior sigue en curso el tick se omite
+func (s *Scheduler) fire(sch *schedule) {
+	tick := time.Now()
+
+	sch.mu.Lock()
+	if sch.running {
+		sch.status.SkippedTicks++
+		sch.mu.Unlock()
+		logger.GlobalLogger.Warn("Ejecución programada omitida: la anterior sigue en curso", "system", map[string]interface{}{
+			"schedule": sch.config.Name,
+			"tick":     tick,
+		})
+		return
+	}
+	sch.running = true
+	sch.mu.Unlock()
+
+	sinceParam := sinceWindowParam(tick, sch.config.SinceWindowDays)
+	logger.GlobalLogger.Info("Ejecución programada iniciada", "system", map[string]interface{}{
+		"schedule": sch.config.Name,
+		"since":    sinceParam,
+	})
+
+	batchID, err := s.run(s.ctx, sch.config.Name, sinceParam, tick)
+
+	sch.mu.Lock()
+	sch.running = false
+	sch.status.LastRun = &tick
+	sch.status.LastBatchID = batchID
+	if err != nil {
+		sch.status.LastStatus = ScheduleRunFailed
+		sch.status.LastError = err.Error()
+	} else {
+		sch.status.LastStatus = ScheduleRunCompleted
+		sch.status.LastError = ""
+	}
+	sch.mu.Unlock()
+
+	extra := map[string]interface{}{
+		"schedule":    sch.config.Name,
+		"batch_id":    batchID,
+		"duration_ms": time.Since(tick).Milliseconds(),
+	}
+	if err != nil {
+		extra["error"] = err.Error()
+		logger.GlobalLogger.Error("Ejecución programada falló", "system", extra)
+		return
+	}
+	logger.GlobalLogger.Info("Ejecución programada completada", "system", extra)
+}
+
+// sinceWindowParam calcula la fecha 'since' (YYYY-MM-DD) de una ventana móvil de N días,
+// contados desde el día del tick en la zona de reporte
+func sinceWindowParam(tick time.Time, windowDays int) string {
+	if windowDays <= 0 {
+		return ""
+	}
+	return tick.In(currentTimezone()).AddDate(0, 0, -windowDays).Format("2006-01-02")
+}
+
+// LoadScheduleConfigs lee las programaciones del archivo JSON indicado en SCHEDULES_CONFIG.
+// Sin archivo usa INGEST_SCHEDULE (y opcionalmente INGEST_SINCE_WINDOW_DAYS) como programación "default".
+func LoadScheduleConfigs() ([]ScheduleConfig, error) {
+	if path := os.Getenv("SCHEDULES_CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading schedules config: %w", err)
+		}
+
+		var configs []ScheduleConfig
+		if err := json.Unmarshal(data, &configs); err != nil {
+			return nil, fmt.Errorf("error parsing schedules config: %w", err)
+		}
+		return configs, nil
+	}
+
+	expression := os.Getenv("INGEST_SCHEDULE")
+	if expression == "" {
+		return nil, nil
+	}
+
+	config := ScheduleConfig{Name: "default", Cron: expression}
+	if window := os.Getenv("INGEST_SINCE_WINDOW_DAYS"); window != "" {
+		days, err := strconv.Atoi(window)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INGEST_SINCE_WINDOW_DAYS: %w", err)
+		}
+		config.SinceWindowDays = days
+	}
+	return []ScheduleConfig{config}, nil
+}
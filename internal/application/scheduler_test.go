@@ -0,0 +1,175 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSinceWindowParam(t *testing.T) {
+	tick := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		windowDays int
+		expected   string
+	}{
+		{name: "Sin ventana", windowDays: 0, expected: ""},
+		{name: "Ventana de un día", windowDays: 1, expected: "2025-02-28"},
+		{name: "Ventana de una semana", windowDays: 7, expected: "2025-02-22"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := sinceWindowParam(tick, tt.windowDays); result != tt.expected {
+				t.Errorf("sinceWindowParam() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+
+	// La ventana se cuenta desde el día del tick en la zona de reporte: 03:00 UTC aún es el día anterior en Ciudad de México
+	mexico, _ := time.LoadLocation("America/Mexico_City")
+	SetReportingTimezone(mexico)
+	t.Cleanup(func() { SetReportingTimezone(time.UTC) })
+	if result := sinceWindowParam(time.Date(2025, 3, 1, 3, 0, 0, 0, time.UTC), 1); result != "2025-02-27" {
+		t.Errorf("sinceWindowParam() in America/Mexico_City = %q, want 2025-02-27", result)
+	}
+}
+
+func TestNewSchedulerValidation(t *testing.T) {
+	noop := func(ctx context.Context, name, since string, tick time.Time) (string, error) { return "", nil }
+
+	tests := []struct {
+		name        string
+		configs     []ScheduleConfig
+		expectError bool
+	}{
+		{name: "Expresión de 5 campos", configs: []ScheduleConfig{{Name: "hourly", Cron: "0 * * * *", SinceWindowDays: 3}}},
+		{name: "Descriptor", configs: []ScheduleConfig{{Name: "every", Cron: "@every 30m"}}},
+		{name: "Expresión inválida", configs: []ScheduleConfig{{Name: "bad", Cron: "not a cron"}}, expectError: true},
+		{name: "Sin nombre", configs: []ScheduleConfig{{Cron: "@hourly"}}, expectError: true},
+		{name: "Nombre duplicado", configs: []ScheduleConfig{{Name: "a", Cron: "@hourly"}, {Name: "a", Cron: "@daily"}}, expectError: true},
+		{name: "Ventana negativa", configs: []ScheduleConfig{{Name: "a", Cron: "@hourly", SinceWindowDays: -1}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewScheduler(tt.configs, noop)
+			if tt.expectError && err == nil {
+				t.Errorf("NewScheduler() expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("NewScheduler() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSchedulerSkipsTickWhilePreviousRunIsGoing(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan string, 1)
+
+	scheduler, err := NewScheduler([]ScheduleConfig{{Name: "hourly", Cron: "@hourly", SinceWindowDays: 2}},
+		func(ctx context.Context, name, since string, tick time.Time) (string, error) {
+			started <- since
+			<-release
+			return "batch-1", nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sch := scheduler.schedules[0]
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.fire(sch)
+		close(done)
+	}()
+	since := <-started
+
+	// Segundo tick mientras el primero sigue corriendo: debe omitirse sin bloquear
+	scheduler.fire(sch)
+
+	status := scheduler.Status()[0]
+	if !status.Running || status.SkippedTicks != 1 {
+		t.Errorf("Expected running schedule with 1 skipped tick, got %+v", status)
+	}
+
+	close(release)
+	<-done
+
+	status = scheduler.Status()[0]
+	if status.Running || status.LastBatchID != "batch-1" || status.LastStatus != ScheduleRunCompleted || status.LastRun == nil {
+		t.Errorf("Unexpected status after run: %+v", status)
+	}
+	if expected := sinceWindowParam(*status.LastRun, 2); since != expected {
+		t.Errorf("since = %q, want %q", since, expected)
+	}
+}
+
+func TestSchedulerRecordsFailureAndNextRun(t *testing.T) {
+	scheduler, err := NewScheduler([]ScheduleConfig{{Name: "daily", Cron: "@daily"}},
+		func(ctx context.Context, name, since string, tick time.Time) (string, error) {
+			return "batch-2", errors.New("ETL failed")
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	scheduler.fire(scheduler.schedules[0])
+
+	status := scheduler.Status()[0]
+	if status.LastStatus != ScheduleRunFailed || status.LastError != "ETL failed" || status.LastBatchID != "batch-2" {
+		t.Errorf("Unexpected status after failure: %+v", status)
+	}
+	if status.NextRun == nil || !status.NextRun.After(time.Now()) {
+		t.Errorf("Expected next run in the future, got %v", status.NextRun)
+	}
+}
+
+func TestLoadScheduleConfigs(t *testing.T) {
+	t.Run("Sin configuración", func(t *testing.T) {
+		t.Setenv("SCHEDULES_CONFIG", "")
+		t.Setenv("INGEST_SCHEDULE", "")
+
+		configs, err := LoadScheduleConfigs()
+		if err != nil || len(configs) != 0 {
+			t.Errorf("LoadScheduleConfigs() = %v, %v; want no schedules", configs, err)
+		}
+	})
+
+	t.Run("Desde INGEST_SCHEDULE", func(t *testing.T) {
+		t.Setenv("SCHEDULES_CONFIG", "")
+		t.Setenv("INGEST_SCHEDULE", "0 * * * *")
+		t.Setenv("INGEST_SINCE_WINDOW_DAYS", "3")
+
+		configs, err := LoadScheduleConfigs()
+		if err != nil {
+			t.Fatalf("LoadScheduleConfigs() unexpected error: %v", err)
+		}
+		expected := ScheduleConfig{Name: "default", Cron: "0 * * * *", SinceWindowDays: 3}
+		if len(configs) != 1 || configs[0] != expected {
+			t.Errorf("LoadScheduleConfigs() = %v, want %v", configs, expected)
+		}
+	})
+
+	t.Run("Desde archivo SCHEDULES_CONFIG", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "schedules.json")
+		config := `[{"name": "hourly", "cron": "0 * * * *", "since_window_days": 2}, {"name": "nightly", "cron": "@daily"}]`
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("SCHEDULES_CONFIG", path)
+
+		configs, err := LoadScheduleConfigs()
+		if err != nil || len(configs) != 2 {
+			t.Errorf("LoadScheduleConfigs() = %v, %v; want 2 schedules", configs, err)
+		}
+	})
+}
@@ -0,0 +1,328 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+)
+
+// SourceKind indica qué tipo de registros aporta una fuente
+type SourceKind string
+
+const (
+	SourceKindAds SourceKind = "ads" // Gasto publicitario (AdRecord)
+	SourceKindCRM SourceKind = "crm" // Oportunidades de CRM (CRMRecord)
+)
+
+// RecordSink recibe los registros a medida que se decodifican, sin acumular la respuesta completa
+type RecordSink interface {
+	AddAd(record models.AdRecord) error
+	AddCRM(record models.CRMRecord) error
+}
+
+// Records acumula los registros en memoria; útil para fuentes pequeñas y pruebas
+type Records struct {
+	Ads []models.AdRecord
+	CRM []models.CRMRecord
+}
+
+func (r *Records) AddAd(record models.AdRecord) error {
+	r.Ads = append(r.Ads, record)
+	return nil
+}
+
+func (r *Records) AddCRM(record models.CRMRecord) error {
+	r.CRM = append(r.CRM, record)
+	return nil
+}
+
+// Source es un extractor de datos que RunETL puede ejecutar
+type Source interface {
+	Name() string
+	Kind() SourceKind
+	URL() string
+	// Extract entrega cada registro a sink y debe abortar en cuanto ctx se cancele
+	Extract(ctx context.Context, sinceDate *time.Time, sink RecordSink) error
+}
+
+// Decoder lee el cuerpo de una respuesta y entrega sus registros a sink
+type Decoder func(body io.Reader, sink RecordSink) error
+
+// decoders contiene los decodificadores disponibles por nombre para la configuración de fuentes
+var decoders = map[string]Decoder{
+	"ads_performance":   decodeAdsPerformance,
+	"crm_opportunities": decodeCRMOpportunities,
+}
+
+// defaultDecoders se usa cuando la configuración de la fuente no especifica decoder
+var defaultDecoders = map[SourceKind]string{
+	SourceKindAds: "ads_performance",
+	SourceKindCRM: "crm_opportunities",
+}
+
+// RegisterDecoder agrega un decodificador para nuevas plataformas; debe llamarse antes de cargar la configuración
+func RegisterDecoder(name string, decoder Decoder) {
+	decoders[name] = decoder
+}
+
+// decodeAdsPerformance lee el formato {"external": {"ads": {"performance": [...]}}} registro a registro
+func decodeAdsPerformance(body io.Reader, sink RecordSink) error {
+	return streamArray(json.NewDecoder(body), []string{"external", "ads", "performance"}, func(dec *json.Decoder) error {
+		var record models.AdRecord
+		if err := dec.Decode(&record); err != nil {
+			return err
+		}
+		return sink.AddAd(record)
+	})
+}
+
+// decodeCRMOpportunities lee el formato {"external": {"crm": {"opportunities": [...]}}} registro a registro
+func decodeCRMOpportunities(body io.Reader, sink RecordSink) error {
+	return streamArray(json.NewDecoder(body), []string{"external", "crm", "opportunities"}, func(dec *json.Decoder) error {
+		var record models.CRMRecord
+		if err := dec.Decode(&record); err != nil {
+			return err
+		}
+		return sink.AddCRM(record)
+	})
+}
+
+// SourceConfig declara una fuente HTTP en el archivo de configuración
+type SourceConfig struct {
+	Name    string     `json:"name"`
+	Kind    SourceKind `json:"kind"`
+	URL     string     `json:"url"`
+	Decoder string     `json:"decoder,omitempty"`
+	// Mapping reemplaza al decoder para formatos declarados en configuración
+	Mapping *FieldMapping `json:"mapping,omitempty"`
+	// MaxBodyBytes limita el tamaño de cada respuesta; 0 usa defaultMaxBodyBytes
+	MaxBodyBytes int64 `json:"max_body_bytes,omitempty"`
+	// Pagination es opcional; sin ella la fuente se lee en una sola petición
+	Pagination *PaginationConfig `json:"pagination,omitempty"`
+	// Timezone (nombre IANA) interpreta los timestamps sin zona de la fuente; vacío usa la zona de reporte
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// HTTPSource extrae registros de una API HTTP con reintentos
+type HTTPSource struct {
+	name    string
+	kind    SourceKind
+	url     string
+	decoder Decoder
+	// maxBodyBytes se aplica a cada respuesta (a cada página si la fuente pagina)
+	maxBodyBytes int64
+	// pagination es nil para fuentes de una sola página
+	pagination *PaginationConfig
+	timezone   *time.Location
+}
+
+// NewHTTPSource valida la configuración y resuelve el decoder declarado
+func NewHTTPSource(config SourceConfig) (*HTTPSource, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("source name is required")
+	}
+	if config.Kind != SourceKindAds && config.Kind != SourceKindCRM {
+		return nil, fmt.Errorf("source %s: unknown kind %q (expected %q or %q)", config.Name, config.Kind, SourceKindAds, SourceKindCRM)
+	}
+	if config.URL == "" {
+		return nil, fmt.Errorf("source %s: url is required", config.Name)
+	}
+
+	decoder, err := resolveDecoder(config)
+	if err != nil {
+		return nil, fmt.Errorf("source %s: %w", config.Name, err)
+	}
+
+	if config.MaxBodyBytes < 0 {
+		return nil, fmt.Errorf("source %s: max_body_bytes must be positive", config.Name)
+	}
+	maxBodyBytes := config.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+
+	var pagination *PaginationConfig
+	if config.Pagination != nil {
+		resolved, err := config.Pagination.withDefaults()
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", config.Name, err)
+		}
+		pagination = &resolved
+	}
+
+	timezone, err := LoadTimezone(config.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("source %s: %w", config.Name, err)
+	}
+
+	return &HTTPSource{
+		name:         config.Name,
+		kind:         config.Kind,
+		url:          config.URL,
+		decoder:      decoder,
+		maxBodyBytes: maxBodyBytes,
+		pagination:   pagination,
+		timezone:     timezone,
+	}, nil
+}
+
+// resolveDecoder usa el mapeo declarado o, en su defecto, el decoder registrado por nombre
+func resolveDecoder(config SourceConfig) (Decoder, error) {
+	if config.Mapping != nil {
+		if config.Decoder != "" {
+			return nil, fmt.Errorf("decoder and mapping are mutually exclusive")
+		}
+		return newMappedDecoder(config.Kind, *config.Mapping)
+	}
+
+	decoderName := config.Decoder
+	if decoderName == "" {
+		decoderName = defaultDecoders[config.Kind]
+	}
+	decoder, ok := decoders[decoderName]
+	if !ok {
+		return nil, fmt.Errorf("unknown decoder %q", decoderName)
+	}
+	return decoder, nil
+}
+
+func (s *HTTPSource) Name() string     { return s.name }
+func (s *HTTPSource) Kind() SourceKind { return s.kind }
+func (s *HTTPSource) URL() string      { return s.url }
+
+// Timezone es la zona declarada de los timestamps sin zona; nil si la fuente usa la de reporte
+func (s *HTTPSource) Timezone() *time.Location { return s.timezone }
+
+func (s *HTTPSource) Extract(ctx context.Context, sinceDate *time.Time, sink RecordSink) error {
+	if s.pagination != nil {
+		return fetchPages(ctx, s.url, s.decoder, s.name, s.maxBodyBytes, *s.pagination, sink)
+	}
+	return fetchData(ctx, s.url, s.decoder, s.name, s.maxBodyBytes, sink)
+}
+
+// StaticSource entrega registros ya cargados en memoria, p.ej. los recibidos por webhook
+type StaticSource struct {
+	name    string
+	kind    SourceKind
+	records Records
+}
+
+func NewStaticSource(name string, kind SourceKind, records Records) *StaticSource {
+	return &StaticSource{name: name, kind: kind, records: records}
+}
+
+func (s *StaticSource) Name() string     { return s.name }
+func (s *StaticSource) Kind() SourceKind { return s.kind }
+func (s *StaticSource) URL() string      { return "static://" + s.name }
+
+func (s *StaticSource) Extract(ctx context.Context, sinceDate *time.Time, sink RecordSink) error {
+	for _, record := range s.records.Ads {
+		if err := sink.AddAd(record); err != nil {
+			return err
+		}
+	}
+	for _, record := range s.records.CRM {
+		if err := sink.AddCRM(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SourceRegistry mantiene las fuentes configuradas en el orden en que se registraron
+type SourceRegistry struct {
+	sources []Source
+}
+
+func NewSourceRegistry() *SourceRegistry {
+	return &SourceRegistry{}
+}
+
+// Register agrega una fuente; los nombres deben ser únicos
+func (r *SourceRegistry) Register(source Source) error {
+	for _, existing := range r.sources {
+		if existing.Name() == source.Name() {
+			return fmt.Errorf("source %s already registered", source.Name())
+		}
+	}
+	r.sources = append(r.sources, source)
+	return nil
+}
+
+// Sources devuelve una copia de las fuentes registradas
+func (r *SourceRegistry) Sources() []Source {
+	sources := make([]Source, len(r.sources))
+	copy(sources, r.sources)
+	return sources
+}
+
+// NewSourceRegistryFromConfig crea una fuente HTTP por cada configuración
+func NewSourceRegistryFromConfig(configs []SourceConfig) (*SourceRegistry, error) {
+	registry := NewSourceRegistry()
+	for _, config := range configs {
+		source, err := NewHTTPSource(config)
+		if err != nil {
+			return nil, err
+		}
+		if err := registry.Register(source); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// LoadSourceRegistry lee las fuentes del archivo JSON indicado en SOURCES_CONFIG.
+// Sin archivo configurado usa ADS_API_URL y CRM_API_URL como las fuentes "ads" y "crm".
+// SOURCE_MAX_BODY_BYTES define el límite de respuesta de las fuentes que no declaran max_body_bytes.
+func LoadSourceRegistry() (*SourceRegistry, error) {
+	configs, err := loadSourceConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	if value := os.Getenv("SOURCE_MAX_BODY_BYTES"); value != "" {
+		maxBodyBytes, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || maxBodyBytes <= 0 {
+			return nil, fmt.Errorf("invalid SOURCE_MAX_BODY_BYTES %q", value)
+		}
+		for i := range configs {
+			if configs[i].MaxBodyBytes == 0 {
+				configs[i].MaxBodyBytes = maxBodyBytes
+			}
+		}
+	}
+
+	return NewSourceRegistryFromConfig(configs)
+}
+
+func loadSourceConfigs() ([]SourceConfig, error) {
+	if path := os.Getenv("SOURCES_CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading sources config: %w", err)
+		}
+
+		var configs []SourceConfig
+		if err := json.Unmarshal(data, &configs); err != nil {
+			return nil, fmt.Errorf("error parsing sources config: %w", err)
+		}
+		return configs, nil
+	}
+
+	adsURL := os.Getenv("ADS_API_URL")
+	crmURL := os.Getenv("CRM_API_URL")
+	if adsURL == "" || crmURL == "" {
+		return nil, fmt.Errorf("SOURCES_CONFIG o ADS_API_URL y CRM_API_URL deben estar configuradas")
+	}
+
+	return []SourceConfig{
+		{Name: "ads", Kind: SourceKindAds, URL: adsURL},
+		{Name: "crm", Kind: SourceKindCRM, URL: crmURL},
+	}, nil
+}
@@ -0,0 +1,215 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const adsPayload = `{"external": {"ads": {"performance": [
+	{"date": "2025-01-15", "campaign_id": "C1", "channel": "google_ads", "clicks": 100, "impressions": 1000, "cost": 50.0,
+	 "utm_campaign": "sale", "utm_source": "google", "utm_medium": "cpc"}
+]}}}`
+
+const crmPayload = `{"external": {"crm": {"opportunities": [
+	{"opportunity_id": "O1", "contact_email": "a@example.com", "stage": "closed_won", "amount": 500.0,
+	 "created_at": "2025-01-15T10:00:00Z", "utm_campaign": "sale", "utm_source": "google", "utm_medium": "cpc"}
+]}}}`
+
+func TestNewHTTPSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      SourceConfig
+		expectError bool
+	}{
+		{name: "Fuente de ads con decoder por defecto", config: SourceConfig{Name: "google", Kind: SourceKindAds, URL: "http://x"}},
+		{name: "Fuente de CRM con decoder explícito", config: SourceConfig{Name: "hubspot", Kind: SourceKindCRM, URL: "http://x", Decoder: "crm_opportunities"}},
+		{name: "Sin nombre", config: SourceConfig{Kind: SourceKindAds, URL: "http://x"}, expectError: true},
+		{name: "Tipo desconocido", config: SourceConfig{Name: "x", Kind: "erp", URL: "http://x"}, expectError: true},
+		{name: "Sin URL", config: SourceConfig{Name: "x", Kind: SourceKindAds}, expectError: true},
+		{name: "Decoder desconocido", config: SourceConfig{Name: "x", Kind: SourceKindAds, URL: "http://x", Decoder: "nope"}, expectError: true},
+		{name: "Mapeo declarativo", config: SourceConfig{Name: "x", Kind: SourceKindCRM, URL: "http://x", Mapping: &FieldMapping{RecordsPath: "deals", Fields: map[string]string{"amount": "value"}}}},
+		{name: "Decoder y mapeo a la vez", config: SourceConfig{Name: "x", Kind: SourceKindCRM, URL: "http://x", Decoder: "crm_opportunities", Mapping: &FieldMapping{}}, expectError: true},
+		{name: "Paginación con cursor", config: SourceConfig{Name: "x", Kind: SourceKindAds, URL: "http://x", Pagination: &PaginationConfig{Strategy: PaginationCursor}}},
+		{name: "Estrategia de paginación desconocida", config: SourceConfig{Name: "x", Kind: SourceKindAds, URL: "http://x", Pagination: &PaginationConfig{Strategy: "offset"}}, expectError: true},
+		{name: "Zona horaria de la fuente", config: SourceConfig{Name: "x", Kind: SourceKindAds, URL: "http://x", Timezone: "America/Mexico_City"}},
+		{name: "Zona horaria desconocida", config: SourceConfig{Name: "x", Kind: SourceKindAds, URL: "http://x", Timezone: "Mars/Olympus"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPSource(tt.config)
+			if tt.expectError && err == nil {
+				t.Errorf("NewHTTPSource() expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("NewHTTPSource() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSourceRegistryRejectsDuplicateNames(t *testing.T) {
+	_, err := NewSourceRegistryFromConfig([]SourceConfig{
+		{Name: "ads", Kind: SourceKindAds, URL: "http://a"},
+		{Name: "ads", Kind: SourceKindAds, URL: "http://b"},
+	})
+	if err == nil {
+		t.Error("Expected error for duplicate source names")
+	}
+}
+
+func TestLoadSourceRegistry(t *testing.T) {
+	t.Run("Desde variables ADS_API_URL y CRM_API_URL", func(t *testing.T) {
+		t.Setenv("SOURCES_CONFIG", "")
+		t.Setenv("ADS_API_URL", "http://ads")
+		t.Setenv("CRM_API_URL", "http://crm")
+
+		registry, err := LoadSourceRegistry()
+		if err != nil {
+			t.Fatalf("LoadSourceRegistry() unexpected error: %v", err)
+		}
+		sources := registry.Sources()
+		if len(sources) != 2 || sources[0].Kind() != SourceKindAds || sources[1].URL() != "http://crm" {
+			t.Errorf("Unexpected sources: %v", sources)
+		}
+	})
+
+	t.Run("Desde archivo SOURCES_CONFIG", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "sources.json")
+		config := `[
+			{"name": "google", "kind": "ads", "url": "http://google"},
+			{"name": "meta", "kind": "ads", "url": "http://meta", "decoder": "ads_performance"},
+			{"name": "hubspot", "kind": "crm", "url": "http://hubspot"}
+		]`
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("SOURCES_CONFIG", path)
+
+		registry, err := LoadSourceRegistry()
+		if err != nil {
+			t.Fatalf("LoadSourceRegistry() unexpected error: %v", err)
+		}
+		if len(registry.Sources()) != 3 {
+			t.Errorf("Expected 3 sources, got %d", len(registry.Sources()))
+		}
+	})
+
+	t.Run("SOURCE_MAX_BODY_BYTES inválido", func(t *testing.T) {
+		t.Setenv("SOURCES_CONFIG", "")
+		t.Setenv("ADS_API_URL", "http://ads")
+		t.Setenv("CRM_API_URL", "http://crm")
+		t.Setenv("SOURCE_MAX_BODY_BYTES", "10MB")
+
+		if _, err := LoadSourceRegistry(); err == nil {
+			t.Error("Expected error for invalid SOURCE_MAX_BODY_BYTES")
+		}
+	})
+
+	t.Run("Sin configuración", func(t *testing.T) {
+		t.Setenv("SOURCES_CONFIG", "")
+		t.Setenv("ADS_API_URL", "")
+		t.Setenv("CRM_API_URL", "")
+
+		if _, err := LoadSourceRegistry(); err == nil {
+			t.Error("Expected error when no sources are configured")
+		}
+	})
+}
+
+func TestRunETLWithMultipleSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ads":
+			fmt.Fprint(w, adsPayload)
+		case "/crm":
+			fmt.Fprint(w, crmPayload)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	registry, err := NewSourceRegistryFromConfig([]SourceConfig{
+		{Name: "google", Kind: SourceKindAds, URL: server.URL + "/ads"},
+		{Name: "meta", Kind: SourceKindAds, URL: server.URL + "/ads"},
+		{Name: "hubspot", Kind: SourceKindCRM, URL: server.URL + "/crm"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := RunETL(context.Background(), registry.Sources(), nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() unexpected error: %v", err)
+	}
+
+	if result.AdsRecords != 2 || result.CRMRecords != 1 {
+		t.Errorf("Expected 2 ads and 1 crm records, got %d and %d", result.AdsRecords, result.CRMRecords)
+	}
+	if result.SourceRecords["meta"] != 1 || result.SourceRecords["hubspot"] != 1 {
+		t.Errorf("Unexpected per-source counts: %v", result.SourceRecords)
+	}
+
+	if len(result.Metrics) != 1 {
+		t.Fatalf("Expected 1 daily key, got %d", len(result.Metrics))
+	}
+	for _, m := range result.Metrics {
+		if m.Clicks != 200 || m.ClosedWon != 1 || !m.Revenue.Equal(decimal.NewFromFloat(500.0)) {
+			t.Errorf("Unexpected aggregated metrics: %+v", m)
+		}
+	}
+}
+
+func TestRunETLFailsOnSourceError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	registry, _ := NewSourceRegistryFromConfig([]SourceConfig{
+		{Name: "broken", Kind: SourceKindAds, URL: server.URL},
+	})
+
+	_, err := RunETL(context.Background(), registry.Sources(), nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected error naming the failed source, got %v", err)
+	}
+}
+
+func TestRunETLCancelsSiblingSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// Fuente lenta: responde solo cuando el cliente cancela la petición
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	registry, _ := NewSourceRegistryFromConfig([]SourceConfig{
+		{Name: "slow", Kind: SourceKindAds, URL: server.URL + "/slow"},
+		{Name: "broken", Kind: SourceKindCRM, URL: server.URL + "/broken"},
+	})
+
+	start := time.Now()
+	_, err := RunETL(context.Background(), registry.Sources(), nil, nil)
+	duration := time.Since(start)
+
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected error from the broken source, got %v", err)
+	}
+	if duration > 5*time.Second {
+		t.Errorf("Expected slow source to be canceled, took %v", duration)
+	}
+}
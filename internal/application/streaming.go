@@ -0,0 +1,112 @@
+package application
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// defaultMaxBodyBytes limita cada respuesta cuando la fuente no configura max_body_bytes (256 MiB)
+const defaultMaxBodyBytes int64 = 256 << 20
+
+// ErrBodyTooLarge indica que una respuesta superó el tamaño máximo configurado para la fuente
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// limitBody envuelve el cuerpo para fallar con ErrBodyTooLarge en lugar de leer sin límite
+func limitBody(body io.Reader, limit int64) io.Reader {
+	return &limitedBody{reader: body, remaining: limit, limit: limit}
+}
+
+type limitedBody struct {
+	reader    io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Un byte extra distingue un cuerpo de exactamente limit bytes de uno más grande
+		var probe [1]byte
+		n, err := l.reader.Read(probe[:])
+		if n > 0 {
+			return 0, fmt.Errorf("%w: exceeds %d bytes", ErrBodyTooLarge, l.limit)
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// streamArray recorre el documento siguiendo path (claves de objetos anidados) y llama a fn
+// una vez por cada elemento del arreglo final, sin cargar el arreglo completo en memoria.
+// Un path inexistente o null equivale a un arreglo vacío.
+func streamArray(dec *json.Decoder, path []string, fn func(dec *json.Decoder) error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+
+	if len(path) == 0 {
+		if token != json.Delim('[') {
+			return fmt.Errorf("expected array, got %v", token)
+		}
+		for dec.More() {
+			if err := fn(dec); err != nil {
+				return err
+			}
+		}
+		_, err := dec.Token()
+		return err
+	}
+
+	if token != json.Delim('{') {
+		return fmt.Errorf("expected object at %q, got %v", strings.Join(path, "."), token)
+	}
+	for dec.More() {
+		keyToken, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key, _ := keyToken.(string); key == path[0] {
+			err = streamArray(dec, path[1:], fn)
+		} else {
+			err = skipValue(dec)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Consumir el cierre del objeto también detecta cuerpos truncados después del arreglo
+	_, err = dec.Token()
+	return err
+}
+
+// skipValue descarta el siguiente valor JSON token a token, sin decodificarlo
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
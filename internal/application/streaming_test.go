@@ -0,0 +1,78 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeAdsPerformanceStreaming(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expected    int
+		expectError bool
+	}{
+		{name: "Claves ajenas antes y después del arreglo", body: `{"meta": {"page": [1, {"x": 2}]}, "external": {"crm": {}, "ads": {"performance": [{"clicks": 1}, {"clicks": 2}], "total": 2}}}`, expected: 2},
+		{name: "Arreglo null", body: `{"external": {"ads": {"performance": null}}}`, expected: 0},
+		{name: "Ruta inexistente", body: `{"external": {}}`, expected: 0},
+		{name: "Arreglo truncado", body: `{"external": {"ads": {"performance": [{"clicks": 1}, {"cli`, expectError: true},
+		{name: "Tipo inesperado", body: `{"external": {"ads": {"performance": {"clicks": 1}}}}`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := &Records{}
+			err := decodeAdsPerformance(strings.NewReader(tt.body), records)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected decode error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeAdsPerformance() error: %v", err)
+			}
+			if len(records.Ads) != tt.expected {
+				t.Errorf("Expected %d records, got %d", tt.expected, len(records.Ads))
+			}
+		})
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	body := adsPage(3, "")
+
+	// Un límite exacto no debe fallar
+	records := &Records{}
+	if err := decodeAdsPerformance(limitBody(strings.NewReader(body), int64(len(body))), records); err != nil {
+		t.Fatalf("Expected body of exactly the limit to decode, got %v", err)
+	}
+
+	err := decodeAdsPerformance(limitBody(strings.NewReader(body), int64(len(body)-1)), &Records{})
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Expected ErrBodyTooLarge, got %v", err)
+	}
+}
+
+func TestFetchDataRejectsLargeBodies(t *testing.T) {
+	body := adsPage(50, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Sin Content-Length con ?chunked para forzar el corte durante la lectura
+		if r.URL.Query().Has("chunked") {
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	for _, url := range []string{server.URL, server.URL + "?chunked"} {
+		err := fetchData(t.Context(), url, decodeAdsPerformance, "ads", 1024, &Records{})
+		if !errors.Is(err, ErrBodyTooLarge) {
+			t.Errorf("%s: expected ErrBodyTooLarge, got %v", url, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+)
+
+// StageTaxonomy traduce las etapas crudas de los CRM a pasos del embudo, sin distinguir mayúsculas
+type StageTaxonomy struct {
+	stages map[string]models.FunnelStep
+}
+
+// defaultStageAliases reproduce las etapas que el pipeline entendía antes de la taxonomía configurable
+var defaultStageAliases = map[models.FunnelStep][]string{
+	models.StepLead:        {"lead"},
+	models.StepMQL:         {"mql"},
+	models.StepSQL:         {"sql"},
+	models.StepOpportunity: {"opportunity"},
+	models.StepWon:         {"closed_won"},
+	models.StepLost:        {"closed_lost"},
+}
+
+// NewStageTaxonomy construye la taxonomía a partir de paso → etapas crudas.
+// Falla ante pasos desconocidos o una misma etapa asignada a dos pasos.
+func NewStageTaxonomy(aliases map[models.FunnelStep][]string) (*StageTaxonomy, error) {
+	known := make(map[models.FunnelStep]bool, len(models.FunnelSteps))
+	for _, step := range models.FunnelSteps {
+		known[step] = true
+	}
+
+	stages := make(map[string]models.FunnelStep)
+	for step, rawStages := range aliases {
+		if !known[step] {
+			return nil, fmt.Errorf("unknown funnel step %q (expected one of %v)", step, models.FunnelSteps)
+		}
+		for _, raw := range rawStages {
+			stage := normalizeStage(raw)
+			if stage == "" {
+				return nil, fmt.Errorf("empty stage for funnel step %q", step)
+			}
+			if previous, exists := stages[stage]; exists && previous != step {
+				return nil, fmt.Errorf("stage %q mapped to both %q and %q", raw, previous, step)
+			}
+			stages[stage] = step
+		}
+	}
+	return &StageTaxonomy{stages: stages}, nil
+}
+
+// DefaultStageTaxonomy mapea lead, mql, sql, opportunity, closed_won y closed_lost a su paso
+func DefaultStageTaxonomy() *StageTaxonomy {
+	taxonomy, _ := NewStageTaxonomy(defaultStageAliases)
+	return taxonomy
+}
+
+// Step devuelve el paso de una etapa cruda; false si la etapa no está en la taxonomía
+func (t *StageTaxonomy) Step(stage string) (models.FunnelStep, bool) {
+	step, ok := t.stages[normalizeStage(stage)]
+	return step, ok
+}
+
+func normalizeStage(stage string) string {
+	return strings.ToLower(strings.TrimSpace(stage))
+}
+
+// LoadStageTaxonomy lee STAGE_TAXONOMY_CONFIG (JSON paso → etapas crudas); sin variable usa la taxonomía por defecto
+func LoadStageTaxonomy() (*StageTaxonomy, error) {
+	path := os.Getenv("STAGE_TAXONOMY_CONFIG")
+	if path == "" {
+		return DefaultStageTaxonomy(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading stage taxonomy config: %w", err)
+	}
+
+	var aliases map[models.FunnelStep][]string
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("error parsing stage taxonomy config: %w", err)
+	}
+	return NewStageTaxonomy(aliases)
+}
+
+// activeTaxonomy es la taxonomía que usan la validación y la agregación de CRM
+var activeTaxonomy atomic.Pointer[StageTaxonomy]
+
+func init() {
+	activeTaxonomy.Store(DefaultStageTaxonomy())
+}
+
+// SetStageTaxonomy reemplaza la taxonomía vigente; afecta a las ejecuciones que empiecen después
+func SetStageTaxonomy(taxonomy *StageTaxonomy) {
+	activeTaxonomy.Store(taxonomy)
+}
+
+func currentTaxonomy() *StageTaxonomy {
+	return activeTaxonomy.Load()
+}
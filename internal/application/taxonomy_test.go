@@ -0,0 +1,108 @@
+package application
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestNewStageTaxonomy(t *testing.T) {
+	taxonomy, err := NewStageTaxonomy(map[models.FunnelStep][]string{
+		models.StepSQL:         {"Qualified"},
+		models.StepOpportunity: {"proposal", "negotiation"},
+		models.StepLost:        {"closed_lost", "disqualified"},
+	})
+	if err != nil {
+		t.Fatalf("NewStageTaxonomy() error: %v", err)
+	}
+
+	tests := []struct {
+		stage string
+		want  models.FunnelStep
+		ok    bool
+	}{
+		{stage: "qualified", want: models.StepSQL, ok: true},
+		{stage: " PROPOSAL ", want: models.StepOpportunity, ok: true},
+		{stage: "disqualified", want: models.StepLost, ok: true},
+		{stage: "lead", ok: false},
+	}
+	for _, tt := range tests {
+		step, ok := taxonomy.Step(tt.stage)
+		if step != tt.want || ok != tt.ok {
+			t.Errorf("Step(%q) = %q, %v; want %q, %v", tt.stage, step, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, err := NewStageTaxonomy(map[models.FunnelStep][]string{"proposal": {"proposal"}}); err == nil {
+		t.Error("Expected error for unknown funnel step")
+	}
+	if _, err := NewStageTaxonomy(map[models.FunnelStep][]string{
+		models.StepWon:  {"closed"},
+		models.StepLost: {"Closed"},
+	}); err == nil {
+		t.Error("Expected error for stage mapped to two steps")
+	}
+}
+
+func TestLoadStageTaxonomy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stages.json")
+	if err := os.WriteFile(path, []byte(`{"won": ["closed_won", "Won"], "lead": ["new"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("STAGE_TAXONOMY_CONFIG", path)
+
+	taxonomy, err := LoadStageTaxonomy()
+	if err != nil {
+		t.Fatalf("LoadStageTaxonomy() error: %v", err)
+	}
+	if step, ok := taxonomy.Step("won"); !ok || step != models.StepWon {
+		t.Errorf("Step(won) = %q, %v", step, ok)
+	}
+	// La configuración reemplaza a la taxonomía por defecto
+	if _, ok := taxonomy.Step("lead"); ok {
+		t.Error("Expected lead to be unmapped when the config does not list it")
+	}
+}
+
+func TestRunETLUsesStageTaxonomy(t *testing.T) {
+	taxonomy, err := NewStageTaxonomy(map[models.FunnelStep][]string{
+		models.StepLead:        {"new"},
+		models.StepSQL:         {"qualified"},
+		models.StepOpportunity: {"proposal"},
+		models.StepWon:         {"won"},
+		models.StepLost:        {"lost"},
+	})
+	if err != nil {
+		t.Fatalf("NewStageTaxonomy() error: %v", err)
+	}
+	SetStageTaxonomy(taxonomy)
+	t.Cleanup(func() { SetStageTaxonomy(DefaultStageTaxonomy()) })
+
+	source := NewStaticSource("crm", SourceKindCRM, Records{CRM: []models.CRMRecord{
+		{OpportunityID: "O1", Stage: "new", CreatedAt: "2025-01-15"},
+		{OpportunityID: "O2", Stage: "qualified", CreatedAt: "2025-01-15"},
+		{OpportunityID: "O3", Stage: "proposal", CreatedAt: "2025-01-15"},
+		{OpportunityID: "O4", Stage: "Won", Amount: decimal.NewFromFloat(900), CreatedAt: "2025-01-15"},
+		{OpportunityID: "O5", Stage: "lost", CreatedAt: "2025-01-15"},
+		// closed_won no está en esta taxonomía: se rechaza con known_stage
+		{OpportunityID: "O6", Stage: "closed_won", Amount: decimal.NewFromFloat(100), CreatedAt: "2025-01-15"},
+	}})
+
+	result, err := RunETL(context.Background(), []Source{source}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+	if result.Quality.RejectionsByRule[RuleKnownStage] != 1 {
+		t.Errorf("Expected 1 known_stage rejection, got %+v", result.Quality)
+	}
+
+	key := models.DailyKey{Date: "2025-01-15", UTMKey: BuildUTMKey("", "", "")}
+	want := models.AggregatedMetrics{Leads: 5, MQLs: 4, SQLs: 4, Opportunities: 3, ClosedWon: 1, ClosedLost: 1, Revenue: decimal.NewFromFloat(900)}
+	if got := result.Metrics[key]; !got.Equal(want) {
+		t.Errorf("Metrics[%v] = %+v, want %+v", key, got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package application
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// defaultReportingTimezone es la zona de reporte sin REPORTING_TIMEZONE
+const defaultReportingTimezone = "UTC"
+
+// naiveTimestampFormats son los formatos de fecha y hora sin zona horaria: su instante depende
+// de la zona de la fuente (o de la de reporte si la fuente no declara una)
+var naiveTimestampFormats = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// LoadTimezone resuelve un nombre IANA (p.ej. "America/Mexico_City"); vacío devuelve nil
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return location, nil
+}
+
+// LoadReportingTimezone lee REPORTING_TIMEZONE (UTC por defecto)
+func LoadReportingTimezone() (*time.Location, error) {
+	name := os.Getenv("REPORTING_TIMEZONE")
+	if name == "" {
+		name = defaultReportingTimezone
+	}
+	return LoadTimezone(name)
+}
+
+// activeTimezone es la zona en la que se agrupan los registros por día y se interpretan since, from y to
+var activeTimezone atomic.Pointer[time.Location]
+
+func init() {
+	activeTimezone.Store(time.UTC)
+}
+
+// SetReportingTimezone reemplaza la zona de reporte; afecta a los registros que se agreguen después
+func SetReportingTimezone(location *time.Location) {
+	activeTimezone.Store(location)
+}
+
+func currentTimezone() *time.Location {
+	return activeTimezone.Load()
+}
+
+// ReportingTimezone es la zona de reporte vigente
+func ReportingTimezone() *time.Location {
+	return currentTimezone()
+}
+
+// ParseReportingDate interpreta un día YYYY-MM-DD como su medianoche en la zona de reporte
+func ParseReportingDate(value string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", value, currentTimezone())
+}
+
+// localizeTimestamp fija la zona de un timestamp sin zona horaria expresándolo en RFC 3339 con el
+// desfase de location en ese instante. Las fechas sin hora son días calendario y, como los timestamps
+// con zona, no se modifican; location nil deja el valor para interpretarlo en la zona de reporte.
+func localizeTimestamp(value string, location *time.Location) string {
+	if location == nil {
+		return value
+	}
+	for _, format := range naiveTimestampFormats {
+		if t, err := time.ParseInLocation(format, value, location); err == nil {
+			return t.Format(time.RFC3339)
+		}
+	}
+	return value
+}
+
+// sourceTimezone devuelve la zona de los timestamps sin zona de la fuente, o nil si no declara una
+func sourceTimezone(source Source) *time.Location {
+	if timezoned, ok := source.(interface{ Timezone() *time.Location }); ok {
+		return timezoned.Timezone()
+	}
+	return nil
+}
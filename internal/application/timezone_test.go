@@ -0,0 +1,123 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+)
+
+func setTestTimezone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q) error: %v", name, err)
+	}
+	SetReportingTimezone(location)
+	t.Cleanup(func() { SetReportingTimezone(time.UTC) })
+	return location
+}
+
+func TestRecordDayInReportingTimezone(t *testing.T) {
+	setTestTimezone(t, "America/Mexico_City")
+
+	tests := []struct {
+		name     string
+		dateStr  string
+		expected string
+	}{
+		{name: "Fecha sin hora es un día calendario", dateStr: "2025-01-15", expected: "2025-01-15"},
+		{name: "UTC después de medianoche es el día anterior", dateStr: "2025-01-16T03:30:00Z", expected: "2025-01-15"},
+		{name: "Con desfase explícito", dateStr: "2025-01-15T23:30:00-06:00", expected: "2025-01-15"},
+		{name: "Sin zona se interpreta en la de reporte", dateStr: "2025-01-15 23:30:00", expected: "2025-01-15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := recordDay(tt.dateStr); result != tt.expected {
+				t.Errorf("recordDay(%q) = %q, want %q", tt.dateStr, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLocalizeTimestamp(t *testing.T) {
+	mexico, _ := time.LoadLocation("America/Mexico_City")
+
+	tests := []struct {
+		name     string
+		value    string
+		location *time.Location
+		expected string
+	}{
+		{name: "Sin zona de fuente", value: "2025-01-15 23:30:00", location: nil, expected: "2025-01-15 23:30:00"},
+		{name: "Timestamp sin zona", value: "2025-01-15 23:30:00", location: mexico, expected: "2025-01-15T23:30:00-06:00"},
+		{name: "Formato ISO sin zona", value: "2025-07-15T23:30:00", location: mexico, expected: "2025-07-15T23:30:00-06:00"},
+		{name: "Fecha sin hora", value: "2025-01-15", location: mexico, expected: "2025-01-15"},
+		{name: "Con zona no se modifica", value: "2025-01-16T03:30:00Z", location: mexico, expected: "2025-01-16T03:30:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := localizeTimestamp(tt.value, tt.location); result != tt.expected {
+				t.Errorf("localizeTimestamp(%q) = %q, want %q", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadReportingTimezone(t *testing.T) {
+	t.Setenv("REPORTING_TIMEZONE", "")
+	if location, err := LoadReportingTimezone(); err != nil || location != time.UTC {
+		t.Errorf("LoadReportingTimezone() default = %v, %v; want UTC", location, err)
+	}
+
+	t.Setenv("REPORTING_TIMEZONE", "Mars/Olympus")
+	if _, err := LoadReportingTimezone(); err == nil {
+		t.Error("Expected error for unknown timezone")
+	}
+}
+
+// timezonedSource es una fuente estática con zona horaria declarada
+type timezonedSource struct {
+	*StaticSource
+	location *time.Location
+}
+
+func (s timezonedSource) Timezone() *time.Location { return s.location }
+
+func TestRunETLBucketsBySourceAndReportingTimezone(t *testing.T) {
+	mexico := setTestTimezone(t, "America/Mexico_City")
+
+	// La fuente reporta en hora de Madrid sin zona; el reporte es en Ciudad de México
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+	ads := timezonedSource{
+		StaticSource: NewStaticSource("ads", SourceKindAds, Records{Ads: []models.AdRecord{
+			{Date: "2025-01-16 02:00:00", Clicks: 1, UTMCampaign: "sale"}, // 2025-01-15 19:00 en México
+			{Date: "2025-01-16 09:00:00", Clicks: 2, UTMCampaign: "sale"}, // 2025-01-16 02:00 en México
+		}}),
+		location: madrid,
+	}
+	crm := NewStaticSource("crm", SourceKindCRM, Records{CRM: []models.CRMRecord{
+		// Sin zona declarada en la fuente: UTC explícito se agrupa en el día de México
+		{OpportunityID: "O1", Stage: "lead", CreatedAt: "2025-01-16T03:30:00Z", UTMCampaign: "sale"},
+	}})
+
+	since, _ := ParseReportingDate("2025-01-15")
+	if since.Location() != mexico {
+		t.Fatalf("ParseReportingDate() location = %v, want America/Mexico_City", since.Location())
+	}
+	result, err := RunETL(context.Background(), []Source{ads, crm}, &since, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+
+	key := BuildUTMKey("sale", "", "")
+	day15 := result.Metrics[models.DailyKey{Date: "2025-01-15", UTMKey: key}]
+	day16 := result.Metrics[models.DailyKey{Date: "2025-01-16", UTMKey: key}]
+	if day15.Clicks != 1 || day15.Leads != 1 || day16.Clicks != 2 {
+		t.Errorf("Metrics = %+v, want 1 click and 1 lead on 2025-01-15 and 2 clicks on 2025-01-16", result.Metrics)
+	}
+	if result.Timezone != "America/Mexico_City" {
+		t.Errorf("Timezone = %q, want America/Mexico_City", result.Timezone)
+	}
+}
@@ -0,0 +1,148 @@
+package application
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+// AttributionModel indica cómo se reparte cada oportunidad entre los puntos de contacto de su contacto
+type AttributionModel string
+
+const (
+	AttributionLastTouch  AttributionModel = "last_touch"  // Todo a la UTM más reciente hasta la creación
+	AttributionFirstTouch AttributionModel = "first_touch" // Todo a la UTM por la que llegó el contacto
+	AttributionLinear     AttributionModel = "linear"      // Partes iguales entre los puntos de contacto
+	AttributionTimeDecay  AttributionModel = "time_decay"  // Más peso a los puntos de contacto cercanos a la creación
+)
+
+// timeDecayHalfLife es la antigüedad con la que un punto de contacto pesa la mitad en time_decay
+const timeDecayHalfLife = 7 * 24 * time.Hour
+
+// ParseAttributionModel valida el parámetro model; vacío deja cada oportunidad en su propia UTM
+func ParseAttributionModel(value string) (AttributionModel, error) {
+	switch model := AttributionModel(value); model {
+	case "", AttributionLastTouch, AttributionFirstTouch, AttributionLinear, AttributionTimeDecay:
+		return model, nil
+	}
+	return "", fmt.Errorf("invalid attribution model %q: use last_touch, first_touch, linear or time_decay", value)
+}
+
+// touchpoint es la llegada de un contacto por una UTM: cada oportunidad suya, en su created_at
+type touchpoint struct {
+	id  string
+	utm models.UTMKey
+	at  time.Time
+}
+
+// contactTouchpoints agrupa por contacto las oportunidades con created_at interpretable
+func contactTouchpoints(history []models.Opportunity) map[string][]touchpoint {
+	touchpoints := make(map[string][]touchpoint)
+	for _, opportunity := range history {
+		if opportunity.ContactHash == "" {
+			continue
+		}
+		at, err := parseRecordDate(opportunity.CreatedAt)
+		if err != nil {
+			continue
+		}
+		touchpoints[opportunity.ContactHash] = append(touchpoints[opportunity.ContactHash],
+			touchpoint{id: opportunity.ID, utm: opportunity.UTM, at: at})
+	}
+	return touchpoints
+}
+
+// AttributeByModel reparte los contadores de CRM de cada conversión entre los puntos de contacto de su
+// contacto: las oportunidades de history con el mismo ContactHash creadas hasta la conversión, incluida
+// ella misma. Con windowDays > 0 se ignoran los puntos de contacto de más de windowDays días antes.
+// Una conversión sin contacto o sin created_at interpretable queda entera en su UTM.
+func AttributeByModel(conversions, history []models.Opportunity, model AttributionModel, windowDays int) map[models.UTMKey]models.CRMCredit {
+	byContact := contactTouchpoints(history)
+	credit := make(map[models.UTMKey]models.CRMCredit)
+	for _, conversion := range conversions {
+		contribution := conversion.Contribution().CRMCredit()
+		if contribution.IsZero() {
+			continue
+		}
+		at, err := parseRecordDate(conversion.CreatedAt)
+		if conversion.ContactHash == "" || err != nil {
+			credit[conversion.UTM] = credit[conversion.UTM].Add(contribution)
+			continue
+		}
+
+		// Cada parte se redondea a la escala de los montos y el último punto de contacto (la propia
+		// conversión) se queda con el resto, así el crédito repartido suma exactamente lo guardado
+		path := conversionPath(conversion, at, byContact[conversion.ContactHash], windowDays)
+		remainder := contribution
+		weights := touchpointWeights(path, at, model)
+		for i, weight := range weights[:len(weights)-1] {
+			share := contribution.Mul(weight).Round()
+			remainder = remainder.Sub(share)
+			credit[path[i].utm] = credit[path[i].utm].Add(share)
+		}
+		last := path[len(path)-1].utm
+		credit[last] = credit[last].Add(remainder)
+	}
+	return credit
+}
+
+// conversionPath devuelve los puntos de contacto que preceden a la conversión, del más antiguo al más
+// reciente. La conversión es siempre el último: a igual instante, otro punto de contacto va antes.
+func conversionPath(conversion models.Opportunity, at time.Time, touchpoints []touchpoint, windowDays int) []touchpoint {
+	var path []touchpoint
+	for _, tp := range touchpoints {
+		if tp.id == conversion.ID || tp.at.After(at) {
+			continue
+		}
+		if windowDays > 0 && tp.at.Before(at.AddDate(0, 0, -windowDays)) {
+			continue
+		}
+		path = append(path, tp)
+	}
+	sort.SliceStable(path, func(i, j int) bool {
+		if !path[i].at.Equal(path[j].at) {
+			return path[i].at.Before(path[j].at)
+		}
+		return path[i].id < path[j].id
+	})
+	return append(path, touchpoint{id: conversion.ID, utm: conversion.UTM, at: at})
+}
+
+// touchpointWeights devuelve la fracción de crédito de cada punto de contacto. En los modelos
+// repartidos el último recibe 1 menos la suma de los demás, así los pesos suman exactamente 1.
+func touchpointWeights(path []touchpoint, at time.Time, model AttributionModel) []decimal.Decimal {
+	weights := make([]decimal.Decimal, len(path))
+	for i := range weights {
+		weights[i] = decimal.Zero
+	}
+	last := len(path) - 1
+	switch model {
+	case AttributionFirstTouch:
+		weights[0] = decimal.NewFromInt(1)
+		return weights
+	case AttributionLinear:
+		share := decimal.NewFromInt(1).Div(decimal.NewFromInt(int64(len(path))))
+		for i := range weights[:last] {
+			weights[i] = share
+		}
+	case AttributionTimeDecay:
+		decay := make([]float64, len(path))
+		total := 0.0
+		for i, tp := range path {
+			decay[i] = math.Exp2(-float64(at.Sub(tp.at)) / float64(timeDecayHalfLife))
+			total += decay[i]
+		}
+		for i := range weights[:last] {
+			weights[i] = decimal.NewFromFloat(decay[i] / total)
+		}
+	}
+	weights[last] = decimal.NewFromInt(1)
+	for _, weight := range weights[:last] {
+		weights[last] = weights[last].Sub(weight)
+	}
+	return weights
+}
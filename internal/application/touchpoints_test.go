@@ -0,0 +1,86 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestParseAttributionModel(t *testing.T) {
+	for _, value := range []string{"", "last_touch", "first_touch", "linear", "time_decay"} {
+		if _, err := ParseAttributionModel(value); err != nil {
+			t.Errorf("ParseAttributionModel(%q) unexpected error: %v", value, err)
+		}
+	}
+	if _, err := ParseAttributionModel("position_based"); err == nil {
+		t.Error("ParseAttributionModel(position_based) expected error")
+	}
+}
+
+func TestAttributeByModel(t *testing.T) {
+	search := models.UTMKey{Campaign: "search", Source: "google", Medium: "cpc"}
+	social := models.UTMKey{Campaign: "retargeting", Source: "facebook", Medium: "paid_social"}
+	email := models.UTMKey{Campaign: "newsletter", Source: "mailchimp", Medium: "email"}
+
+	opportunity := func(id, contact, createdAt string, step models.FunnelStep, amount float64, utm models.UTMKey) models.Opportunity {
+		return models.Opportunity{ID: id, Step: step, Amount: decimal.NewFromFloat(amount), ContactHash: contact,
+			CreatedAt: createdAt, Day: createdAt[:10], UTM: utm}
+	}
+	// El contacto llega por search, vuelve por social 7 días después y compra por email 7 días más tarde
+	history := []models.Opportunity{
+		opportunity("O1", "ana@example.com", "2025-01-01T10:00:00Z", models.StepLead, 0, search),
+		opportunity("O2", "ana@example.com", "2025-01-08T10:00:00Z", models.StepLead, 0, social),
+		opportunity("O3", "ana@example.com", "2025-01-15T10:00:00Z", models.StepWon, 700, email),
+		// Posterior a la conversión: no es un punto de contacto de O3
+		opportunity("O4", "ana@example.com", "2025-01-20T10:00:00Z", models.StepLead, 0, search),
+	}
+	conversions := []models.Opportunity{history[2], opportunity("O5", "", "2025-01-15T12:00:00Z", models.StepLead, 0, social)}
+
+	revenue := func(credit map[models.UTMKey]models.CRMCredit, utm models.UTMKey) string {
+		return credit[utm].Revenue.String()
+	}
+
+	tests := []struct {
+		model   AttributionModel
+		window  int
+		revenue map[models.UTMKey]string
+		leads   map[models.UTMKey]string
+	}{
+		{model: AttributionLastTouch, revenue: map[models.UTMKey]string{email: "700", search: "0", social: "0"},
+			leads: map[models.UTMKey]string{email: "1", social: "1"}},
+		{model: AttributionFirstTouch, revenue: map[models.UTMKey]string{search: "700", email: "0"},
+			leads: map[models.UTMKey]string{search: "1", social: "1"}},
+		{model: AttributionLinear, revenue: map[models.UTMKey]string{search: "233.333333", social: "233.333333", email: "233.333334"},
+			leads: map[models.UTMKey]string{search: "0.333333", social: "1.333333"}},
+		// Pesos 1/4, 1/2 y 1 (vida media de 7 días), normalizados
+		{model: AttributionTimeDecay, revenue: map[models.UTMKey]string{search: "100", social: "200", email: "400"},
+			leads: map[models.UTMKey]string{search: "0.142857", email: "0.571429"}},
+		// La ventana deja fuera el punto de contacto de search
+		{model: AttributionLinear, window: 10, revenue: map[models.UTMKey]string{search: "0", social: "350", email: "350"},
+			leads: map[models.UTMKey]string{social: "1.5"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.model), func(t *testing.T) {
+			credit := AttributeByModel(conversions, history, tt.model, tt.window)
+			for utm, want := range tt.revenue {
+				if got := revenue(credit, utm); got != want {
+					t.Errorf("%s revenue = %s, want %s", utm.Campaign, got, want)
+				}
+			}
+			for utm, want := range tt.leads {
+				if got := credit[utm].Leads.String(); got != want {
+					t.Errorf("%s leads = %s, want %s", utm.Campaign, got, want)
+				}
+			}
+			// El reparto no crea ni pierde micro-unidades respecto de lo guardado
+			total, leads := decimal.Zero, decimal.Zero
+			for _, c := range credit {
+				total, leads = total.Add(c.Revenue), leads.Add(c.Leads)
+			}
+			if !total.Equal(decimal.NewFromInt(700)) || !leads.Equal(decimal.NewFromInt(2)) {
+				t.Errorf("total revenue = %s, leads = %s, want 700 and 2", total, leads)
+			}
+		})
+	}
+}
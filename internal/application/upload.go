@@ -0,0 +1,276 @@
+package application
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// UploadFormat es el formato de un archivo subido a /ingest/upload
+type UploadFormat string
+
+const (
+	UploadFormatCSV    UploadFormat = "csv"
+	UploadFormatNDJSON UploadFormat = "ndjson"
+)
+
+const (
+	// maxRowErrors limita los errores por fila que se conservan para la respuesta
+	maxRowErrors = 100
+	// maxNDJSONLine es el tamaño máximo de una línea NDJSON
+	maxNDJSONLine = 1 << 20
+)
+
+// ErrNoValidRows indica que ninguna fila del archivo pudo convertirse en registro
+var ErrNoValidRows = errors.New("no valid rows in uploaded file")
+
+// RowError describe una fila del archivo que no pudo convertirse en registro
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// FileSource es una fuente respaldada por un archivo subido; implementa Source para reutilizar
+// RunETL, la agregación y la bitácora de lotes. Extract solo puede llamarse una vez.
+type FileSource struct {
+	name   string
+	kind   SourceKind
+	format UploadFormat
+	fields map[string]string
+	reader io.Reader
+	// timezone interpreta los timestamps sin zona del archivo; nil usa la zona de reporte
+	timezone *time.Location
+
+	// Estado de CSV: el encabezado se lee al construir la fuente para validar el mapeo antes de ejecutar el lote
+	csvReader *csv.Reader
+	columns   map[string]int
+	width     int // Columnas del encabezado; cada fila debe tener las mismas
+
+	rowErrors    []RowError
+	rejectedRows int
+}
+
+// NewFileSource valida el tipo, el formato y el mapeo de columnas (campo destino → columna o ruta NDJSON)
+func NewFileSource(name string, kind SourceKind, format UploadFormat, fields map[string]string, reader io.Reader) (*FileSource, error) {
+	var err error
+	switch kind {
+	case SourceKindAds:
+		err = validateFieldMapping(fields, adFieldSetters)
+	case SourceKindCRM:
+		err = validateFieldMapping(fields, crmFieldSetters)
+	default:
+		return nil, fmt.Errorf("unknown kind %q (expected %q or %q)", kind, SourceKindAds, SourceKindCRM)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	source := &FileSource{
+		name:   name,
+		kind:   kind,
+		format: format,
+		fields: fields,
+		reader: reader,
+	}
+
+	switch format {
+	case UploadFormatCSV:
+		if err := source.readCSVHeader(); err != nil {
+			return nil, err
+		}
+	case UploadFormatNDJSON:
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected %q or %q)", format, UploadFormatCSV, UploadFormatNDJSON)
+	}
+
+	return source, nil
+}
+
+func (s *FileSource) Name() string     { return s.name }
+func (s *FileSource) Kind() SourceKind { return s.kind }
+func (s *FileSource) URL() string      { return "upload://" + s.name }
+
+// SetTimezone declara la zona de los timestamps sin zona del archivo
+func (s *FileSource) SetTimezone(location *time.Location) { s.timezone = location }
+
+// Timezone es la zona declarada del archivo; nil si usa la de reporte
+func (s *FileSource) Timezone() *time.Location { return s.timezone }
+
+// RowErrors devuelve los primeros errores por fila, con su número de línea
+func (s *FileSource) RowErrors() []RowError { return s.rowErrors }
+
+// RejectedRows devuelve el total de filas descartadas, aunque superen maxRowErrors
+func (s *FileSource) RejectedRows() int { return s.rejectedRows }
+
+// Extract entrega a sink las filas válidas y registra las inválidas sin detener la lectura
+func (s *FileSource) Extract(ctx context.Context, sinceDate *time.Time, sink RecordSink) error {
+	var accepted int
+	var err error
+	switch s.format {
+	case UploadFormatCSV:
+		accepted, err = s.extractCSV(ctx, sink)
+	case UploadFormatNDJSON:
+		accepted, err = s.extractNDJSON(ctx, sink)
+	}
+	if err != nil {
+		return err
+	}
+
+	if accepted == 0 && s.rejectedRows > 0 {
+		return fmt.Errorf("%w: %d rows rejected", ErrNoValidRows, s.rejectedRows)
+	}
+	return nil
+}
+
+// readCSVHeader indexa las columnas sin distinguir mayúsculas y verifica que existan las columnas mapeadas.
+// Dos columnas que se normalizan igual ("Cost" y "cost") son ambiguas y rechazan el archivo.
+func (s *FileSource) readCSVHeader() error {
+	s.csvReader = csv.NewReader(s.reader)
+	s.csvReader.FieldsPerRecord = -1
+	s.csvReader.TrimLeadingSpace = true
+
+	header, err := s.csvReader.Read()
+	if err == io.EOF {
+		return fmt.Errorf("empty CSV file")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	s.columns = make(map[string]int, len(header))
+	for i, column := range header {
+		// Excel antepone un BOM UTF-8 a la primera columna
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		name := normalizeColumn(column)
+		if previous, ok := s.columns[name]; ok {
+			return fmt.Errorf("duplicate CSV column %q: columns %d and %d have the same name ignoring case and spaces", column, previous+1, i+1)
+		}
+		s.columns[name] = i
+	}
+	s.width = len(header)
+
+	for target, column := range s.fields {
+		if _, ok := s.columns[normalizeColumn(column)]; !ok {
+			return fmt.Errorf("column %q mapped to %s not found in CSV header", column, target)
+		}
+	}
+	return nil
+}
+
+func (s *FileSource) extractCSV(ctx context.Context, sink RecordSink) (int, error) {
+	accepted := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return accepted, err
+		}
+
+		row, err := s.csvReader.Read()
+		if err == io.EOF {
+			return accepted, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return accepted, err
+			}
+			s.rejectRow(parseErr.StartLine, err)
+			continue
+		}
+		line, _ := s.csvReader.FieldPos(0)
+		if len(row) != s.width {
+			s.rejectRow(line, fmt.Errorf("expected %d columns, got %d", s.width, len(row)))
+			continue
+		}
+
+		lookup := func(column string) interface{} {
+			index, ok := s.columns[normalizeColumn(column)]
+			if !ok {
+				return nil
+			}
+			return row[index]
+		}
+		if err := s.emit(lookup, sink); err != nil {
+			s.rejectRow(line, err)
+			continue
+		}
+		accepted++
+	}
+}
+
+func (s *FileSource) extractNDJSON(ctx context.Context, sink RecordSink) (int, error) {
+	scanner := bufio.NewScanner(s.reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+
+	accepted := 0
+	line := 0
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return accepted, err
+		}
+		line++
+
+		content := bytes.TrimSpace(scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		var element map[string]interface{}
+		if err := dec.Decode(&element); err != nil {
+			s.rejectRow(line, err)
+			continue
+		}
+
+		lookup := func(path string) interface{} {
+			return lookupPath(element, splitPath(path))
+		}
+		if err := s.emit(lookup, sink); err != nil {
+			s.rejectRow(line, err)
+			continue
+		}
+		accepted++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return accepted, fmt.Errorf("line %d: %w", line+1, err)
+	}
+	return accepted, nil
+}
+
+func (s *FileSource) emit(lookup func(path string) interface{}, sink RecordSink) error {
+	switch s.kind {
+	case SourceKindAds:
+		record, err := mapRecord(s.fields, adFieldSetters, lookup)
+		if err != nil {
+			return err
+		}
+		return sink.AddAd(record)
+	default:
+		record, err := mapRecord(s.fields, crmFieldSetters, lookup)
+		if err != nil {
+			return err
+		}
+		return sink.AddCRM(record)
+	}
+}
+
+func (s *FileSource) rejectRow(line int, err error) {
+	s.rejectedRows++
+	if len(s.rowErrors) < maxRowErrors {
+		s.rowErrors = append(s.rowErrors, RowError{Line: line, Error: err.Error()})
+	}
+}
+
+func normalizeColumn(column string) string {
+	return strings.ToLower(strings.TrimSpace(column))
+}
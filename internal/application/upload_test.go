@@ -0,0 +1,128 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFileSourceCSV(t *testing.T) {
+	csvFile := "\ufeffDay,Campaign,Spend,Clicks,utm_source,utm_medium\n" +
+		"2025-01-15,Sale,45.50,12,google,cpc\n" +
+		"2025-01-15,Sale,abc,3,google,cpc\n" +
+		"2025-01-16,Sale,10,1\n" +
+		"2025-01-16,\"Black\nFriday\",5,1,google,cpc\n"
+
+	source, err := NewFileSource("spend.csv", SourceKindAds, UploadFormatCSV, map[string]string{
+		"date":         "day",
+		"utm_campaign": "Campaign",
+		"cost":         "SPEND",
+	}, strings.NewReader(csvFile))
+	if err != nil {
+		t.Fatalf("NewFileSource() error: %v", err)
+	}
+
+	records := &Records{}
+	if err := source.Extract(context.Background(), nil, records); err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+
+	if len(records.Ads) != 2 {
+		t.Fatalf("Expected 2 valid records, got %d", len(records.Ads))
+	}
+	if !records.Ads[0].Cost.Equal(decimal.NewFromFloat(45.5)) || records.Ads[0].Clicks != 12 || records.Ads[0].Date != "2025-01-15" || records.Ads[0].UTMCampaign != "Sale" {
+		t.Errorf("Unexpected first record: %+v", records.Ads[0])
+	}
+	if records.Ads[1].UTMCampaign != "Black\nFriday" {
+		t.Errorf("Expected quoted multi-line campaign, got %q", records.Ads[1].UTMCampaign)
+	}
+
+	rowErrors := source.RowErrors()
+	if source.RejectedRows() != 2 || len(rowErrors) != 2 {
+		t.Fatalf("Expected 2 rejected rows, got %d (%v)", source.RejectedRows(), rowErrors)
+	}
+	if rowErrors[0].Line != 3 || rowErrors[1].Line != 4 {
+		t.Errorf("Expected errors on lines 3 and 4, got %v", rowErrors)
+	}
+}
+
+func TestFileSourceNDJSON(t *testing.T) {
+	ndjson := `{"opportunity_id": "O1", "stage": "closed_won", "deal": {"amount": "500"}, "created_at": "2025-01-15"}
+
+{"opportunity_id": "O2", "stage": "lead", "deal": {"amount": "n/a"}}
+{not json}
+`
+	source, err := NewFileSource("crm.ndjson", SourceKindCRM, UploadFormatNDJSON, map[string]string{"amount": "deal.amount"}, strings.NewReader(ndjson))
+	if err != nil {
+		t.Fatalf("NewFileSource() error: %v", err)
+	}
+
+	records := &Records{}
+	if err := source.Extract(context.Background(), nil, records); err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+	if len(records.CRM) != 1 || !records.CRM[0].Amount.Equal(decimal.NewFromFloat(500)) {
+		t.Errorf("Unexpected records: %+v", records.CRM)
+	}
+	rowErrors := source.RowErrors()
+	if len(rowErrors) != 2 || rowErrors[0].Line != 3 || rowErrors[1].Line != 4 {
+		t.Errorf("Expected errors on lines 3 and 4, got %v", rowErrors)
+	}
+}
+
+func TestFileSourceErrors(t *testing.T) {
+	t.Run("Columna mapeada inexistente", func(t *testing.T) {
+		_, err := NewFileSource("a.csv", SourceKindAds, UploadFormatCSV, map[string]string{"cost": "Amount"}, strings.NewReader("date,cost\n"))
+		if err == nil {
+			t.Error("Expected error for missing mapped column")
+		}
+	})
+
+	t.Run("Columnas duplicadas", func(t *testing.T) {
+		_, err := NewFileSource("a.csv", SourceKindAds, UploadFormatCSV, nil, strings.NewReader("date,Cost,cost \n2025-01-15,1,2\n"))
+		if err == nil || !strings.Contains(err.Error(), "duplicate CSV column") {
+			t.Errorf("Expected duplicate column error, got %v", err)
+		}
+	})
+
+	t.Run("Formato desconocido", func(t *testing.T) {
+		if _, err := NewFileSource("a.xlsx", SourceKindAds, "xlsx", nil, strings.NewReader("")); err == nil {
+			t.Error("Expected error for unknown format")
+		}
+	})
+
+	t.Run("Ninguna fila válida", func(t *testing.T) {
+		source, err := NewFileSource("a.csv", SourceKindAds, UploadFormatCSV, nil, strings.NewReader("date,clicks\n2025-01-15,x\n"))
+		if err != nil {
+			t.Fatalf("NewFileSource() error: %v", err)
+		}
+		err = source.Extract(context.Background(), nil, &Records{})
+		if !errors.Is(err, ErrNoValidRows) {
+			t.Errorf("Expected ErrNoValidRows, got %v", err)
+		}
+	})
+}
+
+func TestRunETLWithFileSource(t *testing.T) {
+	source, err := NewFileSource("spend.csv", SourceKindAds, UploadFormatCSV, nil,
+		strings.NewReader("date,clicks,cost,utm_campaign,utm_source,utm_medium\n2025-01-15,10,5,sale,google,cpc\n2025-01-15,5,2.5,SALE,google,cpc\n"))
+	if err != nil {
+		t.Fatalf("NewFileSource() error: %v", err)
+	}
+
+	result, err := RunETL(context.Background(), []Source{source}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+	if len(result.Metrics) != 1 || result.AdsRecords != 2 {
+		t.Fatalf("Expected 1 combination from 2 records, got %d from %d", len(result.Metrics), result.AdsRecords)
+	}
+	for _, metrics := range result.Metrics {
+		if metrics.Clicks != 15 || !metrics.Cost.Equal(decimal.NewFromFloat(7.5)) {
+			t.Errorf("Unexpected aggregated metrics: %+v", metrics)
+		}
+	}
+}
@@ -2,44 +2,32 @@ package application
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/m4ck-y/ETL_go/internal/domain/models"
 	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
 )
 
-// normalizeUTM normaliza y asigna valores por defecto a UTMs
-func normalizeUTM(value, defaultValue string) string {
-	if value == "" {
-		return defaultValue
-	}
-	return strings.ToLower(strings.TrimSpace(value))
-}
-
+// BuildUTMKey normaliza los tres campos UTM con las reglas vigentes (ver UTMRules)
 func BuildUTMKey(campaign, source, medium string) models.UTMKey {
-	return models.UTMKey{
-		Campaign: normalizeUTM(campaign, "unknown_campaign"),
-		Source:   normalizeUTM(source, "unknown_source"),
-		Medium:   normalizeUTM(medium, "unknown_medium"),
-	}
+	return currentUTMRules().Key(campaign, source, medium)
 }
 
-// parseRecordDate intenta parsear diferentes formatos de fecha
+// parseRecordDate intenta parsear diferentes formatos de fecha. Las fechas sin hora y los timestamps
+// sin zona se interpretan en la zona de reporte (ver localizeTimestamp para la zona de cada fuente).
 func parseRecordDate(dateStr string) (time.Time, error) {
 	if dateStr == "" {
 		return time.Time{}, fmt.Errorf("empty date")
 	}
 
-	// Formatos más comunes primero
-	formats := []string{
+	// Formatos más comunes primero; RFC 3339 cubre también el sufijo Z (UTC)
+	formats := append([]string{
 		"2006-01-02",
-		"2006-01-02T15:04:05Z",
 		time.RFC3339,
-	}
+	}, naiveTimestampFormats...)
 
 	for _, format := range formats {
-		if t, err := time.Parse(format, dateStr); err == nil {
+		if t, err := time.ParseInLocation(format, dateStr, currentTimezone()); err == nil {
 			return t, nil
 		}
 	}
@@ -47,6 +35,25 @@ func parseRecordDate(dateStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unsupported date format: %s", dateStr)
 }
 
+// recordDay devuelve el día (YYYY-MM-DD) del registro en la zona de reporte, o "" si la fecha no se puede interpretar
+func recordDay(dateStr string) string {
+	recordDate, err := parseRecordDate(dateStr)
+	if err != nil {
+		return ""
+	}
+	return recordDate.In(currentTimezone()).Format("2006-01-02")
+}
+
+// isBeforeSince indica si la fecha es válida y anterior al filtro; una fecha inválida no se considera
+// fuera de rango para que la regla parseable_date la registre
+func isBeforeSince(recordDateStr string, sinceDate *time.Time) bool {
+	if sinceDate == nil || sinceDate.IsZero() {
+		return false
+	}
+	recordDate, err := parseRecordDate(recordDateStr)
+	return err == nil && recordDate.Before(*sinceDate)
+}
+
 // isRecordInDateRange verifica si el registro está dentro del rango de fechas
 func isRecordInDateRange(recordDateStr string, filterDate *time.Time) bool {
 	// Si no hay filtro de fecha, incluir todos los registros
@@ -0,0 +1,276 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+)
+
+// Campos UTM a los que se aplican las reglas
+const (
+	UTMFieldCampaign = "campaign"
+	UTMFieldSource   = "source"
+	UTMFieldMedium   = "medium"
+)
+
+// defaultSeparatorChars son los caracteres que se unifican cuando se configura un separador
+const defaultSeparatorChars = " _-"
+
+// UTMRulesConfig es el formato del archivo UTM_RULES_CONFIG
+type UTMRulesConfig struct {
+	// Separator, si se define, reemplaza cada secuencia de SeparatorChars (y espacios) por este valor,
+	// p.ej. "facebook ads", "facebook-ads" y "facebook__ads" → "facebook_ads"
+	Separator      string             `json:"separator,omitempty"`
+	SeparatorChars string             `json:"separator_chars,omitempty"`
+	Campaign       UTMFieldRuleConfig `json:"campaign"`
+	Source         UTMFieldRuleConfig `json:"source"`
+	Medium         UTMFieldRuleConfig `json:"medium"`
+}
+
+// UTMFieldRuleConfig declara las reglas de un campo UTM
+type UTMFieldRuleConfig struct {
+	// Aliases: valor canónico → valores crudos equivalentes
+	Aliases map[string][]string `json:"aliases,omitempty"`
+	// Rewrites se aplican en orden antes de buscar el alias
+	Rewrites []UTMRewrite `json:"rewrites,omitempty"`
+	// Default es el valor cuando el campo queda vacío; por defecto unknown_<campo>
+	Default string `json:"default,omitempty"`
+}
+
+// UTMRewrite reemplaza las coincidencias de Pattern (regexp de Go) por Replacement ($1 para grupos)
+type UTMRewrite struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+type compiledRewrite struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+type utmFieldRules struct {
+	aliases  map[string]string
+	rewrites []compiledRewrite
+	fallback string
+}
+
+// UTMRules normaliza los valores UTM: minúsculas y trim, separadores, rewrites, alias y valor por defecto
+type UTMRules struct {
+	separator  string
+	separators *regexp.Regexp
+	campaign   utmFieldRules
+	source     utmFieldRules
+	medium     utmFieldRules
+	loadedAt   time.Time
+}
+
+// NewUTMRules compila la configuración; falla ante regexps inválidas o alias contradictorios
+func NewUTMRules(config UTMRulesConfig) (*UTMRules, error) {
+	rules := &UTMRules{separator: config.Separator, loadedAt: time.Now().UTC()}
+	if config.Separator != "" {
+		chars := config.SeparatorChars
+		if chars == "" {
+			chars = defaultSeparatorChars
+		}
+		rules.separators = regexp.MustCompile(`[\s` + regexp.QuoteMeta(chars) + `]+`)
+	}
+
+	var err error
+	if rules.campaign, err = rules.compileField(UTMFieldCampaign, config.Campaign); err != nil {
+		return nil, err
+	}
+	if rules.source, err = rules.compileField(UTMFieldSource, config.Source); err != nil {
+		return nil, err
+	}
+	if rules.medium, err = rules.compileField(UTMFieldMedium, config.Medium); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *UTMRules) compileField(field string, config UTMFieldRuleConfig) (utmFieldRules, error) {
+	compiled := utmFieldRules{
+		aliases:  make(map[string]string),
+		fallback: config.Default,
+	}
+	if compiled.fallback == "" {
+		compiled.fallback = "unknown_" + field
+	}
+
+	for _, rewrite := range config.Rewrites {
+		pattern, err := regexp.Compile(rewrite.Pattern)
+		if err != nil {
+			return compiled, fmt.Errorf("%s: invalid rewrite pattern %q: %w", field, rewrite.Pattern, err)
+		}
+		compiled.rewrites = append(compiled.rewrites, compiledRewrite{pattern: pattern, replacement: rewrite.Replacement})
+	}
+
+	for canonical, rawValues := range config.Aliases {
+		target := r.canonicalize(canonical)
+		if target == "" {
+			return compiled, fmt.Errorf("%s: empty canonical alias", field)
+		}
+		// El canónico también es alias de sí mismo, así un rewrite no lo aleja de su propio grupo
+		for _, raw := range append([]string{canonical}, rawValues...) {
+			value := r.canonicalize(raw)
+			if previous, exists := compiled.aliases[value]; exists && previous != target {
+				return compiled, fmt.Errorf("%s: value %q is an alias of both %q and %q", field, raw, previous, target)
+			}
+			compiled.aliases[value] = target
+		}
+	}
+	return compiled, nil
+}
+
+// DefaultUTMRules solo pasa a minúsculas, recorta espacios y asigna unknown_<campo> a los vacíos
+func DefaultUTMRules() *UTMRules {
+	rules, _ := NewUTMRules(UTMRulesConfig{})
+	return rules
+}
+
+// LoadedAt indica cuándo se cargaron las reglas
+func (r *UTMRules) LoadedAt() time.Time { return r.loadedAt }
+
+// canonicalize pasa a minúsculas, recorta y unifica separadores
+func (r *UTMRules) canonicalize(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if r.separators != nil {
+		value = r.separators.ReplaceAllString(value, r.separator)
+		value = strings.Trim(value, r.separator)
+	}
+	return value
+}
+
+func (r *UTMRules) normalize(field utmFieldRules, value string) string {
+	value = r.canonicalize(value)
+	for _, rewrite := range field.rewrites {
+		value = rewrite.pattern.ReplaceAllString(value, rewrite.replacement)
+	}
+	value = strings.TrimSpace(value)
+	if canonical, ok := field.aliases[value]; ok {
+		value = canonical
+	}
+	if value == "" {
+		return field.fallback
+	}
+	return value
+}
+
+// Key normaliza los tres campos UTM
+func (r *UTMRules) Key(campaign, source, medium string) models.UTMKey {
+	return models.UTMKey{
+		Campaign: r.normalize(r.campaign, campaign),
+		Source:   r.normalize(r.source, source),
+		Medium:   r.normalize(r.medium, medium),
+	}
+}
+
+// LoadUTMRules lee UTM_RULES_CONFIG; sin variable usa las reglas por defecto
+func LoadUTMRules() (*UTMRules, error) {
+	path := os.Getenv("UTM_RULES_CONFIG")
+	if path == "" {
+		return DefaultUTMRules(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading UTM rules config: %w", err)
+	}
+
+	var config UTMRulesConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing UTM rules config: %w", err)
+	}
+	return NewUTMRules(config)
+}
+
+// ReloadUTMRules vuelve a leer UTM_RULES_CONFIG; si el archivo es inválido se conservan las reglas vigentes
+func ReloadUTMRules() (*UTMRules, error) {
+	rules, err := LoadUTMRules()
+	if err != nil {
+		return nil, err
+	}
+	SetUTMRules(rules)
+	return rules, nil
+}
+
+// activeUTMRules son las reglas que aplica BuildUTMKey
+var activeUTMRules atomic.Pointer[UTMRules]
+
+func init() {
+	activeUTMRules.Store(DefaultUTMRules())
+}
+
+// SetUTMRules reemplaza las reglas vigentes; afecta a los registros que se agreguen después
+func SetUTMRules(rules *UTMRules) {
+	activeUTMRules.Store(rules)
+}
+
+func currentUTMRules() *UTMRules {
+	return activeUTMRules.Load()
+}
+
+// utmRawValue identifica un valor crudo de un campo UTM
+type utmRawValue struct {
+	field string
+	raw   string
+}
+
+// utmObserver registra a qué valor canónico se normalizó cada valor crudo durante una ejecución
+type utmObserver map[utmRawValue]*models.UTMMapping
+
+// observe cuenta un registro; el valor canónico solo se calcula la primera vez que aparece un valor crudo
+func (o utmObserver) observe(campaign, source, medium string) {
+	rules := currentUTMRules()
+	o.add(UTMFieldCampaign, campaign, func() string { return rules.normalize(rules.campaign, campaign) })
+	o.add(UTMFieldSource, source, func() string { return rules.normalize(rules.source, source) })
+	o.add(UTMFieldMedium, medium, func() string { return rules.normalize(rules.medium, medium) })
+}
+
+func (o utmObserver) add(field, raw string, canonical func() string) {
+	key := utmRawValue{field: field, raw: raw}
+	if mapping, exists := o[key]; exists {
+		mapping.Records++
+		return
+	}
+	o[key] = &models.UTMMapping{Field: field, Raw: raw, Canonical: canonical(), Records: 1}
+}
+
+// merge suma las observaciones de otra fuente
+func (o utmObserver) merge(other utmObserver) {
+	for key, mapping := range other {
+		if existing, exists := o[key]; exists {
+			existing.Records += mapping.Records
+			continue
+		}
+		copied := *mapping
+		o[key] = &copied
+	}
+}
+
+// mappings devuelve las observaciones ordenadas por campo, valor canónico y valor crudo
+func (o utmObserver) mappings(seenAt time.Time) []models.UTMMapping {
+	result := make([]models.UTMMapping, 0, len(o))
+	for _, mapping := range o {
+		m := *mapping
+		m.LastSeen = seenAt
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Field != result[j].Field {
+			return result[i].Field < result[j].Field
+		}
+		if result[i].Canonical != result[j].Canonical {
+			return result[i].Canonical < result[j].Canonical
+		}
+		return result[i].Raw < result[j].Raw
+	})
+	return result
+}
@@ -0,0 +1,145 @@
+package application
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+)
+
+func TestUTMRulesKey(t *testing.T) {
+	rules, err := NewUTMRules(UTMRulesConfig{
+		Separator: "_",
+		Source: UTMFieldRuleConfig{
+			Aliases:  map[string][]string{"facebook": {"fb", "Facebook Ads", "meta"}},
+			Rewrites: []UTMRewrite{{Pattern: `^(www\.)?([a-z]+)\.com$`, Replacement: "$2"}},
+			Default:  "direct",
+		},
+		Medium: UTMFieldRuleConfig{
+			Aliases: map[string][]string{"paid_social": {"social-paid", "paidsocial"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewUTMRules() error: %v", err)
+	}
+
+	tests := []struct {
+		name                     string
+		campaign, source, medium string
+		want                     models.UTMKey
+	}{
+		{
+			name:     "Alias directo",
+			campaign: "Summer Sale", source: "fb", medium: "Social-Paid",
+			want: models.UTMKey{Campaign: "summer_sale", Source: "facebook", Medium: "paid_social"},
+		},
+		{
+			name:     "Separadores antes del alias",
+			campaign: " black--friday ", source: "FACEBOOK   ADS", medium: "paidsocial",
+			want: models.UTMKey{Campaign: "black_friday", Source: "facebook", Medium: "paid_social"},
+		},
+		{
+			name:     "Rewrite seguido de alias implícito del canónico",
+			campaign: "sale", source: "www.facebook.com", medium: "cpc",
+			want: models.UTMKey{Campaign: "sale", Source: "facebook", Medium: "cpc"},
+		},
+		{
+			name:     "Valores por defecto configurables",
+			campaign: "", source: "  ", medium: "",
+			want: models.UTMKey{Campaign: "unknown_campaign", Source: "direct", Medium: "unknown_medium"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.Key(tt.campaign, tt.source, tt.medium); got != tt.want {
+				t.Errorf("Key() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewUTMRulesErrors(t *testing.T) {
+	if _, err := NewUTMRules(UTMRulesConfig{Source: UTMFieldRuleConfig{
+		Rewrites: []UTMRewrite{{Pattern: "("}},
+	}}); err == nil {
+		t.Error("Expected error for invalid rewrite pattern")
+	}
+	if _, err := NewUTMRules(UTMRulesConfig{Source: UTMFieldRuleConfig{
+		Aliases: map[string][]string{"facebook": {"meta"}, "instagram": {"Meta"}},
+	}}); err == nil {
+		t.Error("Expected error for value aliased to two canonical values")
+	}
+}
+
+func TestReloadUTMRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "utm.json")
+	t.Setenv("UTM_RULES_CONFIG", path)
+	t.Cleanup(func() { SetUTMRules(DefaultUTMRules()) })
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"source": {"aliases": {"google": ["adwords"]}}}`)
+	if _, err := ReloadUTMRules(); err != nil {
+		t.Fatalf("ReloadUTMRules() error: %v", err)
+	}
+	if got := BuildUTMKey("", "AdWords", "").Source; got != "google" {
+		t.Errorf("Source after reload = %q, want google", got)
+	}
+
+	// Un archivo inválido no reemplaza las reglas vigentes
+	write(`{"source": {"rewrites": [{"pattern": "["}]}}`)
+	if _, err := ReloadUTMRules(); err == nil {
+		t.Fatal("Expected error for invalid rules file")
+	}
+	if got := BuildUTMKey("", "adwords", "").Source; got != "google" {
+		t.Errorf("Source after failed reload = %q, want google", got)
+	}
+}
+
+func TestRunETLRecordsUTMMappings(t *testing.T) {
+	rules, err := NewUTMRules(UTMRulesConfig{Source: UTMFieldRuleConfig{
+		Aliases: map[string][]string{"facebook": {"fb", "facebook.com"}},
+	}})
+	if err != nil {
+		t.Fatalf("NewUTMRules() error: %v", err)
+	}
+	SetUTMRules(rules)
+	t.Cleanup(func() { SetUTMRules(DefaultUTMRules()) })
+
+	ads := NewStaticSource("ads", SourceKindAds, Records{Ads: []models.AdRecord{
+		{Date: "2025-01-15", UTMCampaign: "sale", UTMSource: "fb", UTMMedium: "cpc", Clicks: 10},
+		{Date: "2025-01-15", UTMCampaign: "sale", UTMSource: "fb", UTMMedium: "cpc", Clicks: 5},
+	}})
+	crm := NewStaticSource("crm", SourceKindCRM, Records{CRM: []models.CRMRecord{
+		{OpportunityID: "O1", Stage: "lead", CreatedAt: "2025-01-15", UTMCampaign: "sale", UTMSource: "facebook.com", UTMMedium: "cpc"},
+	}})
+
+	result, err := RunETL(context.Background(), []Source{ads, crm}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+
+	// Ads y CRM se unen bajo la misma clave canónica
+	if len(result.Metrics) != 1 {
+		t.Errorf("Expected ads and CRM under one key, got %v", result.Metrics)
+	}
+
+	var sources []models.UTMMapping
+	for _, mapping := range result.UTMMappings {
+		if mapping.Field == UTMFieldSource {
+			sources = append(sources, mapping)
+		}
+	}
+	if len(sources) != 2 ||
+		sources[0].Raw != "facebook.com" || sources[0].Canonical != "facebook" || sources[0].Records != 1 ||
+		sources[1].Raw != "fb" || sources[1].Canonical != "facebook" || sources[1].Records != 2 {
+		t.Errorf("Unexpected source mappings: %+v", sources)
+	}
+}
@@ -0,0 +1,225 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+// Nombres de las reglas de calidad; se guardan con cada registro en cuarentena
+const (
+	RuleParseableDate          = "parseable_date"
+	RuleNonNegativeClicks      = "non_negative_clicks"
+	RuleNonNegativeImpressions = "non_negative_impressions"
+	RuleNonNegativeCost        = "non_negative_cost"
+	RuleNonNegativeAmount      = "non_negative_amount"
+	RuleRequiredOpportunity    = "required_opportunity_id"
+	RuleKnownStage             = "known_stage"
+	RuleValidEmail             = "valid_email"
+	RuleConvertibleCurrency    = "convertible_currency"
+)
+
+// maxRejectionsPerSource limita los registros en cuarentena por fuente y ejecución
+const maxRejectionsPerSource = 10000
+
+// ValidationRule es una regla de calidad con nombre; Check devuelve el motivo del rechazo o nil
+type ValidationRule[T any] struct {
+	Name  string
+	Check func(record T) error
+}
+
+// adRules devuelve las reglas de ads con la tabla de tipos de cambio fx; se evalúan en orden y el
+// registro se rechaza con la primera regla que falla
+func adRules(fx *FXTable) []ValidationRule[models.AdRecord] {
+	return []ValidationRule[models.AdRecord]{
+		{Name: RuleParseableDate, Check: func(r models.AdRecord) error { return checkDate("date", r.Date) }},
+		{Name: RuleNonNegativeClicks, Check: func(r models.AdRecord) error { return checkNonNegative("clicks", float64(r.Clicks)) }},
+		{Name: RuleNonNegativeImpressions, Check: func(r models.AdRecord) error { return checkNonNegative("impressions", float64(r.Impressions)) }},
+		{Name: RuleNonNegativeCost, Check: func(r models.AdRecord) error { return checkNonNegativeMoney("cost", r.Cost) }},
+		{Name: RuleConvertibleCurrency, Check: func(r models.AdRecord) error { return checkCurrency(fx, r.Currency, r.Date) }},
+	}
+}
+
+func crmRules(fx *FXTable) []ValidationRule[models.CRMRecord] {
+	return []ValidationRule[models.CRMRecord]{
+		{Name: RuleRequiredOpportunity, Check: func(r models.CRMRecord) error {
+			if strings.TrimSpace(r.OpportunityID) == "" {
+				return fmt.Errorf("opportunity_id is required")
+			}
+			return nil
+		}},
+		{Name: RuleParseableDate, Check: func(r models.CRMRecord) error { return checkDate("created_at", r.CreatedAt) }},
+		{Name: RuleNonNegativeAmount, Check: func(r models.CRMRecord) error { return checkNonNegativeMoney("amount", r.Amount) }},
+		{Name: RuleConvertibleCurrency, Check: func(r models.CRMRecord) error { return checkCurrency(fx, r.Currency, r.CreatedAt) }},
+		{Name: RuleKnownStage, Check: func(r models.CRMRecord) error {
+			if _, ok := currentTaxonomy().Step(r.Stage); !ok {
+				return fmt.Errorf("stage %q is not mapped in the stage taxonomy", r.Stage)
+			}
+			return nil
+		}},
+		{Name: RuleValidEmail, Check: func(r models.CRMRecord) error {
+			// Un email vacío se acepta; el formato se valida cuando está presente
+			if r.ContactEmail == "" {
+				return nil
+			}
+			address, err := mail.ParseAddress(r.ContactEmail)
+			if err != nil || address.Address != strings.TrimSpace(r.ContactEmail) {
+				// Sin el valor: el motivo se guarda en la cuarentena y se devuelve en las respuestas
+				return fmt.Errorf("invalid contact_email")
+			}
+			return nil
+		}},
+	}
+}
+
+func checkDate(field, value string) error {
+	if _, err := parseRecordDate(value); err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	return nil
+}
+
+// checkCurrency exige que la moneda (vacía = moneda de reporte) tenga tipo de cambio el día del registro
+func checkCurrency(fx *FXTable, currency, date string) error {
+	_, err := fx.convertOnRecordDay(decimal.Zero, currency, date)
+	return err
+}
+
+func checkNonNegative(field string, value float64) error {
+	if value < 0 {
+		return fmt.Errorf("%s must not be negative, got %v", field, value)
+	}
+	return nil
+}
+
+func checkNonNegativeMoney(field string, value decimal.Decimal) error {
+	if value.IsNegative() {
+		return fmt.Errorf("%s must not be negative, got %s", field, value)
+	}
+	return nil
+}
+
+// RuleViolation identifica la regla que rechazó un registro y el motivo
+type RuleViolation struct {
+	Rule   string
+	Reason string
+}
+
+func (v *RuleViolation) Error() string {
+	return v.Rule + ": " + v.Reason
+}
+
+func evaluateRules[T any](rules []ValidationRule[T], record T) *RuleViolation {
+	for _, rule := range rules {
+		if err := rule.Check(record); err != nil {
+			return &RuleViolation{Rule: rule.Name, Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// ValidateAdRecord aplica las reglas de ads; devuelve *RuleViolation si alguna falla
+func ValidateAdRecord(record models.AdRecord) error {
+	if violation := evaluateRules(adRules(currentFX()), record); violation != nil {
+		return violation
+	}
+	return nil
+}
+
+// ValidateCRMRecord aplica las reglas de CRM; devuelve *RuleViolation si alguna falla
+func ValidateCRMRecord(record models.CRMRecord) error {
+	if violation := evaluateRules(crmRules(currentFX()), record); violation != nil {
+		return violation
+	}
+	return nil
+}
+
+// RecordError describe un registro rechazado por su posición en el lote recibido
+type RecordError struct {
+	Index int    `json:"index"`
+	Rule  string `json:"rule"`
+	Error string `json:"error"`
+}
+
+// ValidateAdRecords devuelve un error por cada registro inválido
+func ValidateAdRecords(records []models.AdRecord) []RecordError {
+	var errs []RecordError
+	rules := adRules(currentFX())
+	for i, record := range records {
+		if violation := evaluateRules(rules, record); violation != nil {
+			errs = append(errs, RecordError{Index: i, Rule: violation.Rule, Error: violation.Reason})
+		}
+	}
+	return errs
+}
+
+// ValidateCRMRecords devuelve un error por cada oportunidad inválida
+func ValidateCRMRecords(records []models.CRMRecord) []RecordError {
+	var errs []RecordError
+	rules := crmRules(currentFX())
+	for i, record := range records {
+		if violation := evaluateRules(rules, record); violation != nil {
+			errs = append(errs, RecordError{Index: i, Rule: violation.Rule, Error: violation.Reason})
+		}
+	}
+	return errs
+}
+
+// qualityTracker acumula el resumen de calidad y los registros en cuarentena de una fuente
+type qualityTracker struct {
+	source     string
+	kind       SourceKind
+	summary    models.QualitySummary
+	rejections []models.Rejection
+}
+
+func newQualityTracker(source string, kind SourceKind) *qualityTracker {
+	return &qualityTracker{
+		source:  source,
+		kind:    kind,
+		summary: models.QualitySummary{RejectionsByRule: make(map[string]int)},
+	}
+}
+
+// check registra el resultado de validar un registro; devuelve false si debe descartarse
+func (q *qualityTracker) check(record interface{}, violation *RuleViolation) bool {
+	q.summary.RecordsChecked++
+	if violation == nil {
+		q.summary.RecordsAccepted++
+		return true
+	}
+
+	q.summary.RecordsRejected++
+	q.summary.RejectionsByRule[violation.Rule]++
+	// El resumen cuenta todos los rechazos; la cuarentena conserva como máximo maxRejectionsPerSource
+	if len(q.rejections) < maxRejectionsPerSource {
+		raw, _ := json.Marshal(record)
+		q.rejections = append(q.rejections, models.Rejection{
+			Source:     q.source,
+			Kind:       string(q.kind),
+			Rule:       violation.Rule,
+			Reason:     violation.Reason,
+			Record:     raw,
+			RejectedAt: time.Now().UTC(),
+		})
+	}
+	return false
+}
+
+// mergeQuality suma el resumen de una fuente al de la ejecución
+func mergeQuality(total *models.QualitySummary, partial models.QualitySummary) {
+	total.RecordsChecked += partial.RecordsChecked
+	total.RecordsAccepted += partial.RecordsAccepted
+	total.RecordsRejected += partial.RecordsRejected
+	for rule, count := range partial.RejectionsByRule {
+		if total.RejectionsByRule == nil {
+			total.RejectionsByRule = make(map[string]int)
+		}
+		total.RejectionsByRule[rule] += count
+	}
+}
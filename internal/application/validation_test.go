@@ -0,0 +1,112 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestValidateAdRecords(t *testing.T) {
+	records := []models.AdRecord{
+		{Date: "2025-01-15", Clicks: 10, Cost: decimal.NewFromFloat(5)},
+		{Date: "15/01/2025", Clicks: 10},
+		{Date: "2025-01-15", Clicks: -1},
+		{Date: "2025-01-15T10:00:00Z", Cost: decimal.NewFromFloat(-0.5)},
+		{Date: "2025-01-15", Clicks: 1, Impressions: -10},
+	}
+
+	errs := ValidateAdRecords(records)
+	if len(errs) != 4 {
+		t.Fatalf("Expected 4 invalid records, got %v", errs)
+	}
+	expected := []RecordError{
+		{Index: 1, Rule: RuleParseableDate},
+		{Index: 2, Rule: RuleNonNegativeClicks},
+		{Index: 3, Rule: RuleNonNegativeCost},
+		{Index: 4, Rule: RuleNonNegativeImpressions},
+	}
+	for i, want := range expected {
+		if errs[i].Index != want.Index || errs[i].Rule != want.Rule {
+			t.Errorf("Expected %s on index %d, got %s on %d", want.Rule, want.Index, errs[i].Rule, errs[i].Index)
+		}
+	}
+}
+
+func TestRunETLQuarantinesInvalidRecords(t *testing.T) {
+	since := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	source := NewStaticSource("crm", SourceKindCRM, Records{CRM: []models.CRMRecord{
+		{OpportunityID: "O1", Stage: "closed_won", Amount: decimal.NewFromFloat(100), CreatedAt: "2025-01-15"},
+		{OpportunityID: "O2", Stage: "closed_won", Amount: decimal.NewFromFloat(-5), CreatedAt: "2025-01-15"},
+		{OpportunityID: "O3", Stage: "won?", CreatedAt: "2025-01-15"},
+		{OpportunityID: "O4", Stage: "lead", CreatedAt: "sometime"},
+		// Fuera de la ventana since: se omite sin validar aunque sea inválido
+		{OpportunityID: "O5", Stage: "lead", Amount: decimal.NewFromFloat(-1), CreatedAt: "2025-01-01"},
+	}})
+
+	result, err := RunETL(context.Background(), []Source{source}, &since, nil)
+	if err != nil {
+		t.Fatalf("RunETL() error: %v", err)
+	}
+
+	quality := result.Quality
+	if quality.RecordsChecked != 4 || quality.RecordsAccepted != 1 || quality.RecordsRejected != 3 {
+		t.Errorf("Unexpected quality summary: %+v", quality)
+	}
+	for _, rule := range []string{RuleNonNegativeAmount, RuleKnownStage, RuleParseableDate} {
+		if quality.RejectionsByRule[rule] != 1 {
+			t.Errorf("Expected 1 rejection for %s, got %d", rule, quality.RejectionsByRule[rule])
+		}
+	}
+
+	if len(result.Rejections) != 3 || result.Rejections[0].Source != "crm" || result.Rejections[0].Rule != RuleNonNegativeAmount {
+		t.Fatalf("Unexpected rejections: %+v", result.Rejections)
+	}
+	if !strings.Contains(string(result.Rejections[0].Record), `"opportunity_id":"O2"`) {
+		t.Errorf("Expected original record in rejection, got %s", result.Rejections[0].Record)
+	}
+
+	for _, metrics := range result.Metrics {
+		if !metrics.Revenue.Equal(decimal.NewFromFloat(100)) || metrics.Opportunities != 1 {
+			t.Errorf("Only the valid record should be aggregated, got %+v", metrics)
+		}
+	}
+}
+
+func TestValidateCRMRecord(t *testing.T) {
+	valid := models.CRMRecord{OpportunityID: "O1", Stage: "lead", CreatedAt: "2025-01-15T10:00:00Z"}
+
+	tests := []struct {
+		name        string
+		modify      func(r *models.CRMRecord)
+		expectError bool
+	}{
+		{name: "Registro válido", modify: func(r *models.CRMRecord) {}},
+		{name: "Sin opportunity_id", modify: func(r *models.CRMRecord) { r.OpportunityID = "" }, expectError: true},
+		{name: "Sin etapa", modify: func(r *models.CRMRecord) { r.Stage = "" }, expectError: true},
+		{name: "Fecha inválida", modify: func(r *models.CRMRecord) { r.CreatedAt = "ayer" }, expectError: true},
+		{name: "Monto negativo", modify: func(r *models.CRMRecord) { r.Amount = decimal.NewFromInt(-10) }, expectError: true},
+		{name: "Etapa desconocida", modify: func(r *models.CRMRecord) { r.Stage = "maybe" }, expectError: true},
+		{name: "Etapa en mayúsculas", modify: func(r *models.CRMRecord) { r.Stage = "Closed_Won" }},
+		{name: "Email válido", modify: func(r *models.CRMRecord) { r.ContactEmail = "ana@example.com" }},
+		{name: "Email inválido", modify: func(r *models.CRMRecord) { r.ContactEmail = "ana@" }, expectError: true},
+		{name: "Email con nombre", modify: func(r *models.CRMRecord) { r.ContactEmail = "Ana <ana@example.com>" }, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := valid
+			tt.modify(&record)
+			err := ValidateCRMRecord(record)
+			if tt.expectError && err == nil {
+				t.Error("Expected validation error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected validation error: %v", err)
+			}
+		})
+	}
+}
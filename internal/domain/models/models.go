@@ -1,26 +1,47 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// MoneyScale es la cantidad de decimales con que se guardan los montos (micro-unidades).
+// Cada monto se redondea a esta escala al entrar al pipeline (ver RoundMoney); las sumas posteriores son exactas.
+const MoneyScale = 6
+
+// RoundMoney redondea un monto a MoneyScale decimales con redondeo bancario (mitad al par),
+// que no sesga las sumas de muchos montos redondeados
+func RoundMoney(amount decimal.Decimal) decimal.Decimal {
+	return amount.RoundBank(MoneyScale)
+}
+
 type AdRecord struct {
-	Date        string  `json:"date"`
-	CampaignID  string  `json:"campaign_id"`
-	Channel     string  `json:"channel"`
-	Clicks      int     `json:"clicks"`
-	Impressions int     `json:"impressions"`
-	Cost        float64 `json:"cost"`
-	UTMCampaign string  `json:"utm_campaign"`
-	UTMSource   string  `json:"utm_source"`
-	UTMMedium   string  `json:"utm_medium"`
+	Date        string          `json:"date"`
+	CampaignID  string          `json:"campaign_id"`
+	Channel     string          `json:"channel"`
+	Clicks      int             `json:"clicks"`
+	Impressions int             `json:"impressions"`
+	Cost        decimal.Decimal `json:"cost"`               // Acepta número o texto en JSON
+	Currency    string          `json:"currency,omitempty"` // ISO 4217; vacío = moneda de reporte
+	UTMCampaign string          `json:"utm_campaign"`
+	UTMSource   string          `json:"utm_source"`
+	UTMMedium   string          `json:"utm_medium"`
 }
 
 type CRMRecord struct {
-	OpportunityID string  `json:"opportunity_id"`
-	ContactEmail  string  `json:"contact_email"`
-	Stage         string  `json:"stage"`
-	Amount        float64 `json:"amount"`
-	CreatedAt     string  `json:"created_at"`
-	UTMCampaign   string  `json:"utm_campaign"`
-	UTMSource     string  `json:"utm_source"`
-	UTMMedium     string  `json:"utm_medium"`
+	OpportunityID string          `json:"opportunity_id"`
+	ContactEmail  string          `json:"contact_email"`
+	Stage         string          `json:"stage"`
+	Amount        decimal.Decimal `json:"amount"`             // Acepta número o texto en JSON
+	Currency      string          `json:"currency,omitempty"` // ISO 4217; vacío = moneda de reporte
+	CreatedAt     string          `json:"created_at"`
+	UTMCampaign   string          `json:"utm_campaign"`
+	UTMSource     string          `json:"utm_source"`
+	UTMMedium     string          `json:"utm_medium"`
+	// UpdatedAt es opcional; si la fuente lo informa decide cuál snapshot de la oportunidad es el más reciente
+	UpdatedAt string `json:"updated_at,omitempty"`
 }
 
 type UTMKey struct {
@@ -29,31 +50,483 @@ type UTMKey struct {
 	Medium   string
 }
 
+// FunnelStep es un paso del embudo de CRM; la taxonomía de etapas traduce a él las etapas de cada CRM
+type FunnelStep string
+
+const (
+	StepLead        FunnelStep = "lead"
+	StepMQL         FunnelStep = "mql"
+	StepSQL         FunnelStep = "sql"
+	StepOpportunity FunnelStep = "opportunity"
+	StepWon         FunnelStep = "won"
+	StepLost        FunnelStep = "lost"
+)
+
+// FunnelSteps lista los pasos en orden
+var FunnelSteps = []FunnelStep{StepLead, StepMQL, StepSQL, StepOpportunity, StepWon, StepLost}
+
+// rank es la posición del paso en la progresión lead → opportunity (1 a 4); 0 si no es un paso conocido
+func (s FunnelStep) rank() int {
+	switch s {
+	case StepLead:
+		return 1
+	case StepMQL:
+		return 2
+	case StepSQL:
+		return 3
+	case StepOpportunity, StepWon, StepLost:
+		return 4
+	}
+	return 0
+}
+
+// Opportunity es el último estado conocido de una oportunidad de CRM, deduplicada por ID.
+// Los contadores de CRM de los hechos diarios se derivan de estas oportunidades.
+type Opportunity struct {
+	ID          string          `json:"id"`
+	Stage       string          `json:"stage"`                  // Etapa cruda del CRM, en minúsculas
+	Step        FunnelStep      `json:"step"`                   // Paso del embudo según la taxonomía vigente al ingerir
+	Amount      decimal.Decimal `json:"amount"`                 // En la moneda de reporte, redondeado a MoneyScale
+	ContactHash string          `json:"contact_hash,omitempty"` // HMAC del email del contacto; el email no se guarda
+	CreatedAt   string          `json:"created_at"`
+	Day         string          `json:"day"` // Día de CreatedAt (YYYY-MM-DD): fecha del hecho diario al que contribuye
+	UTM         UTMKey          `json:"utm"`
+	UpdatedAt   *time.Time      `json:"updated_at,omitempty"`
+	IngestedAt  time.Time       `json:"ingested_at"`
+	BatchID     string          `json:"batch_id,omitempty"`
+}
+
+// Key devuelve el hecho diario al que contribuye la oportunidad
+func (o Opportunity) Key() DailyKey {
+	return DailyKey{Date: o.Day, UTMKey: o.UTM}
+}
+
+// SupersededBy indica si other reemplaza a o como estado más reciente: gana el de UpdatedAt mayor
+// cuando ambos lo informan y, si no, el que llegó después (other)
+func (o Opportunity) SupersededBy(other Opportunity) bool {
+	if o.UpdatedAt != nil && other.UpdatedAt != nil {
+		return !other.UpdatedAt.Before(*o.UpdatedAt)
+	}
+	return true
+}
+
+// Contribution devuelve los contadores de CRM que aporta la oportunidad a su hecho diario.
+// Los pasos son acumulativos: una oportunidad cuenta en su paso y en todos los anteriores;
+// won y lost son desenlaces de opportunity.
+func (o Opportunity) Contribution() AggregatedMetrics {
+	var m AggregatedMetrics
+	reached := o.Step.rank()
+	if reached >= 1 {
+		m.Leads = 1
+	}
+	if reached >= 2 {
+		m.MQLs = 1
+	}
+	if reached >= 3 {
+		m.SQLs = 1
+	}
+	if reached >= 4 {
+		m.Opportunities = 1
+	}
+	switch o.Step {
+	case StepWon:
+		m.ClosedWon = 1
+		m.Revenue = o.Amount
+	case StepLost:
+		m.ClosedLost = 1
+	}
+	return m
+}
+
+// UTMMapping registra a qué valor canónico se normalizó un valor crudo de un campo UTM
+type UTMMapping struct {
+	Field     string    `json:"field"` // campaign, source o medium
+	Raw       string    `json:"raw"`
+	Canonical string    `json:"canonical"`
+	Records   int       `json:"records"` // Registros ingeridos con este valor crudo
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// UTMMappingGroup agrupa los valores crudos que se normalizaron al mismo valor canónico
+type UTMMappingGroup struct {
+	Field     string          `json:"field"`
+	Canonical string          `json:"canonical"`
+	Records   int             `json:"records"`
+	RawValues []UTMRawMapping `json:"raw_values"`
+}
+
+// UTMRawMapping es un valor crudo dentro de un UTMMappingGroup
+type UTMRawMapping struct {
+	Raw      string    `json:"raw"`
+	Records  int       `json:"records"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// DailyKey identifica un hecho diario: una combinación UTM en una fecha (YYYY-MM-DD).
+// Date vacío agrupa los registros cuya fecha no pudo interpretarse.
+type DailyKey struct {
+	Date string
+	UTMKey
+}
+
 type AggregatedMetrics struct {
-	Channel       string
-	Clicks        int
-	Cost          float64
+	Channel     string
+	Impressions int
+	Clicks      int
+	Cost        decimal.Decimal
+	// Contadores por paso del embudo (acumulativos, ver Opportunity.Contribution)
 	Leads         int
+	MQLs          int
+	SQLs          int
 	Opportunities int
 	ClosedWon     int
-	Revenue       float64
+	ClosedLost    int
+	Revenue       decimal.Decimal
+}
+
+// Add suma los contadores de other y conserva el primer canal no vacío
+func (m AggregatedMetrics) Add(other AggregatedMetrics) AggregatedMetrics {
+	if m.Channel == "" {
+		m.Channel = other.Channel
+	}
+	m.Impressions += other.Impressions
+	m.Clicks += other.Clicks
+	m.Cost = m.Cost.Add(other.Cost)
+	m.Leads += other.Leads
+	m.MQLs += other.MQLs
+	m.SQLs += other.SQLs
+	m.Opportunities += other.Opportunities
+	m.ClosedWon += other.ClosedWon
+	m.ClosedLost += other.ClosedLost
+	m.Revenue = m.Revenue.Add(other.Revenue)
+	return m
+}
+
+// Equal compara contadores y montos por valor; decimal.Decimal no admite ==
+func (m AggregatedMetrics) Equal(other AggregatedMetrics) bool {
+	return m.Channel == other.Channel &&
+		m.Impressions == other.Impressions &&
+		m.Clicks == other.Clicks &&
+		m.Cost.Equal(other.Cost) &&
+		m.Leads == other.Leads &&
+		m.MQLs == other.MQLs &&
+		m.SQLs == other.SQLs &&
+		m.Opportunities == other.Opportunities &&
+		m.ClosedWon == other.ClosedWon &&
+		m.ClosedLost == other.ClosedLost &&
+		m.Revenue.Equal(other.Revenue)
+}
+
+// WithCRM conserva los contadores de ads de m y toma los de CRM de crm
+func (m AggregatedMetrics) WithCRM(crm AggregatedMetrics) AggregatedMetrics {
+	m.Leads = crm.Leads
+	m.MQLs = crm.MQLs
+	m.SQLs = crm.SQLs
+	m.Opportunities = crm.Opportunities
+	m.ClosedWon = crm.ClosedWon
+	m.ClosedLost = crm.ClosedLost
+	m.Revenue = crm.Revenue
+	return m
+}
+
+// HasAdActivity indica si el hecho registra actividad publicitaria (impresiones, clics o gasto)
+func (m AggregatedMetrics) HasAdActivity() bool {
+	return m.Impressions > 0 || m.Clicks > 0 || m.Cost.IsPositive()
+}
+
+// CRMAttribution separa los contadores de CRM de una UTM según la ventana de atribución
+type CRMAttribution struct {
+	Attributed   AggregatedMetrics // Oportunidades con actividad de ads de la misma UTM dentro de la ventana
+	Unattributed AggregatedMetrics // Oportunidades sin actividad de ads que puedan haberlas generado
+}
+
+// ContactPurge resume lo borrado de un contacto por una solicitud de privacidad
+type ContactPurge struct {
+	ContactHash   string `json:"contact_hash"`
+	Opportunities int    `json:"opportunities"` // Oportunidades borradas; sus contadores de CRM se recalculan
+	Rejections    int    `json:"rejections"`    // Registros en cuarentena borrados
+}
+
+// CRMCredit son contadores de CRM que pueden ser fraccionarios: un modelo multi-touch reparte
+// cada oportunidad entre las UTMs por las que llegó su contacto
+type CRMCredit struct {
+	Leads         decimal.Decimal
+	MQLs          decimal.Decimal
+	SQLs          decimal.Decimal
+	Opportunities decimal.Decimal
+	ClosedWon     decimal.Decimal
+	ClosedLost    decimal.Decimal
+	Revenue       decimal.Decimal
+}
+
+// CRMCredit expresa los contadores de CRM de m como crédito entero
+func (m AggregatedMetrics) CRMCredit() CRMCredit {
+	return CRMCredit{
+		Leads:         decimal.NewFromInt(int64(m.Leads)),
+		MQLs:          decimal.NewFromInt(int64(m.MQLs)),
+		SQLs:          decimal.NewFromInt(int64(m.SQLs)),
+		Opportunities: decimal.NewFromInt(int64(m.Opportunities)),
+		ClosedWon:     decimal.NewFromInt(int64(m.ClosedWon)),
+		ClosedLost:    decimal.NewFromInt(int64(m.ClosedLost)),
+		Revenue:       m.Revenue,
+	}
+}
+
+// Add suma el crédito de other
+func (c CRMCredit) Add(other CRMCredit) CRMCredit {
+	return CRMCredit{
+		Leads:         c.Leads.Add(other.Leads),
+		MQLs:          c.MQLs.Add(other.MQLs),
+		SQLs:          c.SQLs.Add(other.SQLs),
+		Opportunities: c.Opportunities.Add(other.Opportunities),
+		ClosedWon:     c.ClosedWon.Add(other.ClosedWon),
+		ClosedLost:    c.ClosedLost.Add(other.ClosedLost),
+		Revenue:       c.Revenue.Add(other.Revenue),
+	}
+}
+
+// Mul escala el crédito por weight (la fracción asignada a un punto de contacto)
+func (c CRMCredit) Mul(weight decimal.Decimal) CRMCredit {
+	return CRMCredit{
+		Leads:         c.Leads.Mul(weight),
+		MQLs:          c.MQLs.Mul(weight),
+		SQLs:          c.SQLs.Mul(weight),
+		Opportunities: c.Opportunities.Mul(weight),
+		ClosedWon:     c.ClosedWon.Mul(weight),
+		ClosedLost:    c.ClosedLost.Mul(weight),
+		Revenue:       c.Revenue.Mul(weight),
+	}
+}
+
+// Sub resta el crédito de other
+func (c CRMCredit) Sub(other CRMCredit) CRMCredit {
+	return c.Add(other.Mul(decimal.NewFromInt(-1)))
+}
+
+// Round redondea cada contador a la escala de los montos
+func (c CRMCredit) Round() CRMCredit {
+	return CRMCredit{
+		Leads:         RoundMoney(c.Leads),
+		MQLs:          RoundMoney(c.MQLs),
+		SQLs:          RoundMoney(c.SQLs),
+		Opportunities: RoundMoney(c.Opportunities),
+		ClosedWon:     RoundMoney(c.ClosedWon),
+		ClosedLost:    RoundMoney(c.ClosedLost),
+		Revenue:       RoundMoney(c.Revenue),
+	}
+}
+
+// IsZero indica si el crédito no tiene ningún contador
+func (c CRMCredit) IsZero() bool {
+	return c.Leads.IsZero() && c.MQLs.IsZero() && c.SQLs.IsZero() && c.Opportunities.IsZero() &&
+		c.ClosedWon.IsZero() && c.ClosedLost.IsZero() && c.Revenue.IsZero()
 }
 
 type MetricResponse struct {
-	Channel       string  `json:"channel"`
-	UTMCampaign   string  `json:"utm_campaign"`
-	UTMSource     string  `json:"utm_source"`
-	UTMMedium     string  `json:"utm_medium"`
-	Clicks        int     `json:"clicks"`
-	Cost          float64 `json:"cost"`
-	Leads         int     `json:"leads"`
-	Opportunities int     `json:"opportunities"`
-	ClosedWon     int     `json:"closed_won"`
-	Revenue       float64 `json:"revenue"`
+	Channel     string `json:"channel"`
+	UTMCampaign string `json:"utm_campaign"`
+	UTMSource   string `json:"utm_source"`
+	UTMMedium   string `json:"utm_medium"`
+	Impressions int    `json:"impressions"`
+	Clicks      int    `json:"clicks"`
+	// Montos en texto con MoneyScale decimales como máximo, para no perder precisión en JSON
+	Cost decimal.Decimal `json:"cost" swaggertype:"string" example:"1250.5"`
+	// Contadores de CRM; enteros salvo con un modelo de atribución multi-touch, que los reparte entre UTMs
+	Leads         float64         `json:"leads"`
+	MQLs          float64         `json:"mqls"`
+	SQLs          float64         `json:"sqls"`
+	Opportunities float64         `json:"opportunities"`
+	ClosedWon     float64         `json:"closed_won"`
+	ClosedLost    float64         `json:"closed_lost"`
+	Revenue       decimal.Decimal `json:"revenue" swaggertype:"string" example:"4000"`
+	// UniqueContacts son los contactos distintos (por hash) de las oportunidades del rango con esta UTM
+	UniqueContacts int `json:"unique_contacts"`
 	// Métricas adicionales calculadas automáticamente a partir de los datos principales
+	CTR          float64 `json:"ctr"`             // Click-through rate = clicks / impressions
+	CPM          float64 `json:"cpm"`             // Costo por mil impresiones = cost / impressions * 1000
 	CPC          float64 `json:"cpc"`             // Cost por click = cost / clicks
 	CPA          float64 `json:"cpa"`             // Cost por adquisición = cost / leads
 	CVRLeadToOpp float64 `json:"cvr_lead_to_opp"` // Tasa de conversión de Lead a Opportunity
+	// Tasas de conversión entre pasos adyacentes del embudo
+	CVRLeadToMQL float64 `json:"cvr_lead_to_mql"` // MQLs / leads
+	CVRMQLToSQL  float64 `json:"cvr_mql_to_sql"`  // SQLs / MQLs
+	CVRSQLToOpp  float64 `json:"cvr_sql_to_opp"`  // Opportunities / SQLs
 	CVROppToWon  float64 `json:"cvr_opp_to_won"`  // Tasa de conversión de Opportunity a ClosedWon
+	CostPerOpp   float64 `json:"cost_per_opp"`    // Costo por oportunidad = cost / opportunities
+	CostPerWon   float64 `json:"cost_per_won"`    // Costo por venta ganada = cost / closed_won
 	ROAS         float64 `json:"roas"`            // Retorno de inversión publicitaria = revenue / cost
+	// Currency es la moneda de reporte en la que se expresan cost, revenue y las métricas monetarias
+	Currency string `json:"currency"`
+	// Timezone es la zona de reporte (IANA) en la que se agrupan los días y se interpretan from y to
+	Timezone string `json:"timezone"`
+	// AttributionWindowDays es la ventana de atribución con la que se unieron CRM y ads (0: solo por UTM y día).
+	// Con un modelo de atribución no se aplica y vale 0.
+	AttributionWindowDays int `json:"attribution_window_days"`
+	// AttributionModel es el modelo con el que se repartieron los contadores de CRM (vacío: la UTM de cada oportunidad)
+	AttributionModel string `json:"attribution_model,omitempty"`
+	// TouchpointWindowDays son los días antes de cada oportunidad en los que el modelo buscó puntos de contacto (0: sin límite)
+	TouchpointWindowDays int `json:"touchpoint_window_days,omitempty"`
+	// KPIs definidos por configuración (KPI_CONFIG), nombre → valor
+	Derived map[string]float64 `json:"derived"`
+}
+
+// Estados posibles de un lote ETL
+const (
+	BatchStatusRunning   = "running"
+	BatchStatusCompleted = "completed"
+	BatchStatusFailed    = "failed"
+	BatchStatusCanceled  = "canceled"
+)
+
+// Batch registra una ejecución del ETL para auditoría
+type Batch struct {
+	ID           string     `json:"id"`
+	Since        string     `json:"since,omitempty"`
+	Status       string     `json:"status"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	DurationMS   int64      `json:"duration_ms"`
+	AdsRecords   int        `json:"ads_records"`
+	CRMRecords   int        `json:"crm_records"`
+	Combinations int        `json:"combinations"`
+	Error        string     `json:"error,omitempty"`
+	Attempts     int        `json:"attempts"` // Ejecuciones del mismo lote (reintentos tras un fallo)
+	// Currency es la moneda de reporte en la que se guardaron costos y revenue del lote
+	Currency string `json:"currency,omitempty"`
+	// Timezone es la zona de reporte con la que se agruparon por día los registros del lote
+	Timezone string `json:"timezone,omitempty"`
+	// Quality resume la validación de registros; nil si la ejecución falló antes de agregar
+	Quality *QualitySummary `json:"quality,omitempty"`
+	// Reconciliation cuenta las claves UTM por estado; nil si el lote no incluyó ads y CRM
+	Reconciliation *ReconciliationSummary `json:"reconciliation,omitempty"`
+}
+
+// QualitySummary resume la validación de registros de una ejecución
+type QualitySummary struct {
+	RecordsChecked   int            `json:"records_checked"`
+	RecordsAccepted  int            `json:"records_accepted"`
+	RecordsRejected  int            `json:"records_rejected"`
+	RejectionsByRule map[string]int `json:"rejections_by_rule,omitempty"`
+}
+
+// Estados de una clave UTM al cruzar ads con CRM
+const (
+	MatchStatusMatched = "matched"
+	MatchStatusAdsOnly = "ads_only"
+	MatchStatusCRMOnly = "crm_only"
+)
+
+// ReconciliationSummary cuenta las claves UTM de un lote por estado
+type ReconciliationSummary struct {
+	Matched int `json:"matched"`
+	AdsOnly int `json:"ads_only"`
+	CRMOnly int `json:"crm_only"`
+}
+
+// KeyReconciliation clasifica una clave UTM de un lote según los lados en que aparece
+type KeyReconciliation struct {
+	BatchID       string          `json:"batch_id"`
+	UTMCampaign   string          `json:"utm_campaign"`
+	UTMSource     string          `json:"utm_source"`
+	UTMMedium     string          `json:"utm_medium"`
+	Status        string          `json:"status"` // matched, ads_only o crm_only
+	Clicks        int             `json:"clicks"`
+	Cost          decimal.Decimal `json:"cost" swaggertype:"string"` // Gasto sin leads atribuibles cuando la clave es ads_only
+	Opportunities int             `json:"opportunities"`
+	Revenue       decimal.Decimal `json:"revenue" swaggertype:"string"` // Revenue sin gasto atribuible cuando la clave es crm_only
+	// Suggestions son las claves del otro lado más parecidas, de mayor a menor similitud
+	Suggestions []KeySuggestion `json:"suggestions,omitempty"`
+}
+
+// UTM devuelve la clave clasificada
+func (k KeyReconciliation) UTM() UTMKey {
+	return UTMKey{Campaign: k.UTMCampaign, Source: k.UTMSource, Medium: k.UTMMedium}
+}
+
+// KeySuggestion es una clave candidata a ser la contraparte de una clave huérfana
+type KeySuggestion struct {
+	UTMCampaign string  `json:"utm_campaign"`
+	UTMSource   string  `json:"utm_source"`
+	UTMMedium   string  `json:"utm_medium"`
+	Similarity  float64 `json:"similarity"` // Entre 0 y 1
+}
+
+// ReconciliationFilter filtra las claves clasificadas. BatchID vacío usa el último lote clasificado
+// y Status vacío lista solo las huérfanas (ads_only y crm_only)
+type ReconciliationFilter struct {
+	BatchID string
+	Status  string
+}
+
+// Rejection es un registro en cuarentena: no superó la regla indicada y no se agregó
+type Rejection struct {
+	ID         int64           `json:"id"`
+	BatchID    string          `json:"batch_id"`
+	Source     string          `json:"source"`
+	Kind       string          `json:"kind"`
+	Rule       string          `json:"rule"`
+	Reason     string          `json:"reason"`
+	Record     json.RawMessage `json:"record" swaggertype:"object"` // Registro original tal como se recibió
+	RejectedAt time.Time       `json:"rejected_at"`
+}
+
+// RejectionFilter filtra la cuarentena; los campos vacíos no filtran
+type RejectionFilter struct {
+	BatchID string
+	Rule    string
+	Source  string
+}
+
+// Estados de un job asíncrono de ingestión
+const (
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCanceled  = "canceled"
+)
+
+// Fases por las que pasa un job de ingestión
+const (
+	JobPhaseQueued      = "queued"
+	JobPhaseFetchingAds = "fetching_ads"
+	JobPhaseFetchingCRM = "fetching_crm"
+	JobPhaseAggregating = "aggregating"
+	JobPhaseSaving      = "saving"
+	JobPhaseDone        = "done"
+)
+
+// JobProgress cuenta el avance de un job mientras se ejecuta
+type JobProgress struct {
+	SourcesTotal int `json:"sources_total"`
+	SourcesDone  int `json:"sources_done"`
+	AdsRecords   int `json:"ads_records"`
+	CRMRecords   int `json:"crm_records"`
+	Combinations int `json:"combinations"`
+}
+
+// Job representa una ingestión asíncrona consultable por su ID
+type Job struct {
+	ID              string      `json:"id"`
+	BatchID         string      `json:"batch_id"`
+	Status          string      `json:"status"`
+	Phase           string      `json:"phase"`
+	Progress        JobProgress `json:"progress"`
+	Error           string      `json:"error,omitempty"`
+	CancelRequested bool        `json:"cancel_requested,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+	FinishedAt      *time.Time  `json:"finished_at,omitempty"`
+}
+
+// ScheduleStatus describe una ingestión programada y el resultado de su última ejecución
+type ScheduleStatus struct {
+	Name            string     `json:"name"`
+	Cron            string     `json:"cron"`
+	SinceWindowDays int        `json:"since_window_days,omitempty"`
+	NextRun         *time.Time `json:"next_run,omitempty"`
+	Running         bool       `json:"running"`
+	LastRun         *time.Time `json:"last_run,omitempty"`
+	LastBatchID     string     `json:"last_batch_id,omitempty"`
+	LastStatus      string     `json:"last_status,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	SkippedTicks    int        `json:"skipped_ticks"`
 }
@@ -1,15 +1,63 @@
 package domain
 
 import (
+	"time"
+
 	"github.com/m4ck-y/ETL_go/internal/domain/models"
 )
 
 type MetricsRepository interface {
-	Save(metrics map[models.UTMKey]models.AggregatedMetrics) error
+	// Save reemplaza los contadores de ads (canal, clics, costo) de cada (fecha, UTM) recibida y los marca
+	// como extraídos: prevalecen sobre los de Merge, antes o después de ellos.
+	// Los contadores de CRM se ignoran: se derivan de SaveOpportunities.
+	Save(metrics map[models.DailyKey]models.AggregatedMetrics) error
+	// Merge guarda los contadores de ads de un lote de webhooks, reemplazando los que ese lote ya tuviera
+	// (un reintento no duplica). Cada (fecha, UTM) no extraída por Save suma los de todos sus lotes.
+	Merge(batchID string, metrics map[models.DailyKey]models.AggregatedMetrics) error
+	// SaveOpportunities guarda el estado más reciente de cada oportunidad (ver Opportunity.SupersededBy)
+	// y recalcula los contadores de CRM de los hechos diarios afectados, incluidos los que una
+	// oportunidad abandona al cambiar de día o UTM
+	SaveOpportunities(opportunities []models.Opportunity) error
+	// GetAll, GetByKey y GetByDateRange devuelven la suma de los días por UTM
 	GetAll() (map[models.UTMKey]models.AggregatedMetrics, error)
 	GetByKey(key models.UTMKey) (models.AggregatedMetrics, bool, error)
+	// GetByDateRange incluye ambos extremos; nil deja el extremo abierto
+	GetByDateRange(from, to *time.Time) (map[models.UTMKey]models.AggregatedMetrics, error)
+	// GetCRMAttribution suma por UTM los contadores de CRM de las oportunidades creadas en el rango,
+	// separando las que tienen actividad de ads en la misma UTM entre windowDays días antes de su día
+	// y ese mismo día de las que no; las oportunidades sin día nunca son atribuibles
+	GetCRMAttribution(from, to *time.Time, windowDays int) (map[models.UTMKey]models.CRMAttribution, error)
+	// ListOpportunities devuelve las oportunidades con día en el rango (mismo criterio que GetByDateRange)
+	ListOpportunities(from, to *time.Time) ([]models.Opportunity, error)
+	// ListOpportunitiesByContact devuelve todas las oportunidades de los contactos indicados, con cualquier día
+	ListOpportunitiesByContact(contacts []string) ([]models.Opportunity, error)
+	// CountUniqueContacts cuenta por UTM los contactos distintos de las oportunidades con día en el rango
+	CountUniqueContacts(from, to *time.Time) (map[models.UTMKey]int, error)
+	// PseudonymizeContacts reemplaza por hash(email) los emails guardados en claro (oportunidades y
+	// cuarentena de CRM) antes de que se seudonimizaran al extraer; devuelve cuántos registros cambió
+	PseudonymizeContacts(hash func(email string) string) (int, error)
+	// PurgeContact borra las oportunidades y los registros en cuarentena del contacto y recalcula los
+	// contadores de CRM de los hechos diarios afectados
+	PurgeContact(contactHash string) (models.ContactPurge, error)
 	Clear() error
 	// Idempotence methods
 	IsBatchProcessed(batchID string) (bool, error)
 	MarkBatchProcessed(batchID string) error
+	// Batch ledger methods; un lote con estado completed se considera procesado
+	SaveBatch(batch models.Batch) error
+	GetBatch(batchID string) (models.Batch, bool, error)
+	// ListBatches devuelve los lotes del más reciente al más antiguo
+	ListBatches(limit, offset int) ([]models.Batch, error)
+	// Cuarentena de registros rechazados; SaveRejections reemplaza las del lote (un reintento no duplica)
+	SaveRejections(batchID string, rejections []models.Rejection) error
+	// ListRejections devuelve los rechazos del más reciente al más antiguo
+	ListRejections(filter models.RejectionFilter, limit, offset int) ([]models.Rejection, error)
+	// SaveReconciliation reemplaza la clasificación de claves UTM del lote
+	SaveReconciliation(batchID string, keys []models.KeyReconciliation) error
+	// ListReconciliation devuelve las claves del lote ordenadas como se guardaron (huérfanas de mayor impacto primero)
+	ListReconciliation(filter models.ReconciliationFilter, limit, offset int) ([]models.KeyReconciliation, error)
+	// SaveUTMMappings suma los registros de cada valor UTM crudo y actualiza su valor canónico
+	SaveUTMMappings(mappings []models.UTMMapping) error
+	// ListUTMMappings devuelve los valores crudos ordenados por campo, canónico y valor crudo; field vacío lista todos
+	ListUTMMappings(field string) ([]models.UTMMapping, error)
 }
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/m4ck-y/ETL_go/internal/application"
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// ListBatchesHandler lista el historial de ejecuciones del ETL
+// @Summary Lista los lotes ETL ejecutados
+// @Description Retorna la bitácora de lotes (más recientes primero) con estado, duración, conteos de registros y error si lo hubo
+// @Tags batches
+// @Accept json
+// @Produce json
+// @Param limit query int false "Límite de resultados" default(50)
+// @Param offset query int false "Offset para paginación" default(0)
+// @Success 200 {array} models.Batch
+// @Failure 500 {object} map[string]string
+// @Router /batches [get]
+func (h *APIHandler) ListBatchesHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	batches, err := h.Repo.ListBatches(limit, offset)
+	if err != nil {
+		logger.GlobalLogger.Error("Error obteniendo lotes", requestID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get batches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, batches)
+}
+
+// GetBatchHandler obtiene el detalle de un lote
+// @Summary Obtiene un lote ETL
+// @Description Retorna el registro de auditoría de un lote por su ID
+// @Tags batches
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del lote"
+// @Success 200 {object} models.Batch
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /batches/{id} [get]
+func (h *APIHandler) GetBatchHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+	batchID := c.Param("id")
+
+	batch, found, err := h.Repo.GetBatch(batchID)
+	if err != nil {
+		logger.GlobalLogger.Error("Error obteniendo lote", requestID, map[string]interface{}{
+			"batch_id": batchID,
+			"error":    err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get batch"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found", "batch_id": batchID})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// errSaveResults distingue los fallos al guardar de los fallos de extracción
+var errSaveResults = errors.New("failed to save ETL results")
+
+// errBatchAlreadyProcessed indica que otro job completó el lote antes de que este lo reservara
+var errBatchAlreadyProcessed = errors.New("batch already processed")
+
+// ingestRun agrupa los parámetros de una ejecución del ETL para un lote
+type ingestRun struct {
+	requestID  string
+	batchID    string
+	sinceParam string
+	sinceDate  *time.Time
+	sources    []application.Source
+	// merge suma los hechos del lote a los existentes en lugar de reemplazarlos (webhooks y archivos)
+	merge bool
+}
+
+// runBatch ejecuta extracción, agregación y guardado de un lote y lo registra en la bitácora.
+// Lo usan tanto la ingestión síncrona como los jobs asíncronos; progress puede ser nil.
+func (h *APIHandler) runBatch(ctx context.Context, run ingestRun, progress application.ProgressReporter) (*application.ETLResult, error) {
+	// Se verifica otra vez dentro del job: otro job del mismo lote pudo terminar entre la
+	// verificación de la petición y la reserva, y el lote no debe volver a ejecutarse
+	processed, err := h.Repo.IsBatchProcessed(run.batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check batch status: %w", err)
+	}
+	if processed {
+		return nil, errBatchAlreadyProcessed
+	}
+
+	batch := h.startBatch(run.batchID, run.sinceParam)
+
+	result, err := application.RunETL(ctx, run.sources, run.sinceDate, progress)
+	if err != nil {
+		logger.GlobalLogger.Error("Proceso ETL falló", run.requestID, map[string]interface{}{
+			"batch_id": run.batchID,
+			"error":    err.Error(),
+		})
+		h.finishBatch(&batch, nil, err)
+		return nil, err
+	}
+
+	if progress != nil {
+		progress.Saving(len(result.Metrics))
+	}
+
+	if err := h.saveResults(run, result); err != nil {
+		logger.GlobalLogger.Error("Error guardando resultados", run.requestID, map[string]interface{}{
+			"batch_id": run.batchID,
+			"error":    err.Error(),
+		})
+		h.finishBatch(&batch, result, err)
+		return result, fmt.Errorf("%w: %v", errSaveResults, err)
+	}
+
+	// La cuarentena no bloquea el lote: los hechos válidos ya se guardaron
+	if err := h.Repo.SaveRejections(run.batchID, result.Rejections); err != nil {
+		logger.GlobalLogger.Warn("Error guardando registros en cuarentena", run.requestID, map[string]interface{}{
+			"batch_id":   run.batchID,
+			"rejections": len(result.Rejections),
+			"error":      err.Error(),
+		})
+	}
+	if result.ReconciliationSummary != nil {
+		if err := h.Repo.SaveReconciliation(run.batchID, result.Reconciliation); err != nil {
+			logger.GlobalLogger.Warn("Error guardando la conciliación de claves UTM", run.requestID, map[string]interface{}{
+				"batch_id": run.batchID,
+				"error":    err.Error(),
+			})
+		}
+	}
+	if err := h.Repo.SaveUTMMappings(result.UTMMappings); err != nil {
+		logger.GlobalLogger.Warn("Error guardando valores UTM observados", run.requestID, map[string]interface{}{
+			"batch_id": run.batchID,
+			"mappings": len(result.UTMMappings),
+			"error":    err.Error(),
+		})
+	}
+
+	h.finishBatch(&batch, result, nil)
+
+	fields := map[string]interface{}{
+		"batch_id":               run.batchID,
+		"processed_combinations": len(result.Metrics),
+		"rejected_records":       result.Quality.RecordsRejected,
+		"duration_ms":            batch.DurationMS,
+	}
+	if summary := result.ReconciliationSummary; summary != nil && summary.AdsOnly+summary.CRMOnly > 0 {
+		// Una clave huérfana suele indicar un tracking roto: se deja visible en los logs
+		fields["ads_only_keys"] = summary.AdsOnly
+		fields["crm_only_keys"] = summary.CRMOnly
+	}
+	logger.GlobalLogger.Info("ETL completado exitosamente", run.requestID, fields)
+
+	return result, nil
+}
+
+// startBatch registra el inicio de un lote; si el lote ya existía (p.ej. falló antes) suma un intento
+func (h *APIHandler) startBatch(batchID, sinceParam string) models.Batch {
+	attempts := 1
+	if previous, found, err := h.Repo.GetBatch(batchID); err == nil && found {
+		attempts = previous.Attempts + 1
+	}
+
+	batch := models.Batch{
+		ID:        batchID,
+		Since:     sinceParam,
+		Status:    models.BatchStatusRunning,
+		StartedAt: time.Now().UTC(),
+		Attempts:  attempts,
+	}
+	h.saveBatch(batch)
+	return batch
+}
+
+// saveResults guarda los contadores de ads y luego las oportunidades, que recalculan los de CRM
+func (h *APIHandler) saveResults(run ingestRun, result *application.ETLResult) error {
+	save := h.Repo.Save
+	if run.merge {
+		save = func(metrics map[models.DailyKey]models.AggregatedMetrics) error {
+			return h.Repo.Merge(run.batchID, metrics)
+		}
+	}
+	if err := save(result.Metrics); err != nil {
+		return err
+	}
+
+	for i := range result.Opportunities {
+		result.Opportunities[i].BatchID = run.batchID
+	}
+	return h.Repo.SaveOpportunities(result.Opportunities)
+}
+
+// finishBatch cierra el lote con el resultado (puede ser nil) y el error de la ejecución
+func (h *APIHandler) finishBatch(batch *models.Batch, result *application.ETLResult, runErr error) {
+	finishedAt := time.Now().UTC()
+	batch.FinishedAt = &finishedAt
+	batch.DurationMS = finishedAt.Sub(batch.StartedAt).Milliseconds()
+
+	if result != nil {
+		batch.AdsRecords = result.AdsRecords
+		batch.CRMRecords = result.CRMRecords
+		batch.Combinations = len(result.Metrics)
+		batch.Currency = result.Currency
+		batch.Timezone = result.Timezone
+		quality := result.Quality
+		batch.Quality = &quality
+		batch.Reconciliation = result.ReconciliationSummary
+	}
+
+	switch {
+	case runErr == nil:
+		batch.Status = models.BatchStatusCompleted
+		batch.Error = ""
+	case errors.Is(runErr, context.Canceled):
+		batch.Status = models.BatchStatusCanceled
+		batch.Error = runErr.Error()
+	default:
+		batch.Status = models.BatchStatusFailed
+		batch.Error = runErr.Error()
+	}
+	h.saveBatch(*batch)
+}
+
+func (h *APIHandler) saveBatch(batch models.Batch) {
+	if err := h.Repo.SaveBatch(batch); err != nil {
+		logger.GlobalLogger.Warn("Error guardando lote en la bitácora", "system", map[string]interface{}{
+			"batch_id": batch.ID,
+			"status":   batch.Status,
+			"error":    err.Error(),
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/m4ck-y/ETL_go/internal/application"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// GetFXRatesHandler muestra la moneda de reporte y la tabla de tipos de cambio vigente
+// @Summary Obtiene la tabla de tipos de cambio
+// @Description Retorna la moneda de reporte, la moneda base de la tabla y las tasas por fecha (unidades de cada moneda por 1 de la base). Cada monto se convierte con la tasa más reciente en o antes del día del registro.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "reporting_currency, base, rates, source y loaded_at"
+// @Router /admin/fx-rates [get]
+func (h *APIHandler) GetFXRatesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, fxTableResponse(application.CurrentFXTable()))
+}
+
+// ReloadFXRatesHandler vuelve a cargar la tabla de tipos de cambio
+// @Summary Recarga la tabla de tipos de cambio
+// @Description Vuelve a leer FX_RATES_FILE o a descargar FX_RATES_URL. Las tasas nuevas aplican a las ingestas posteriores; los hechos ya guardados conservan su conversión. Si la tabla es inválida se conserva la vigente.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Tabla recargada"
+// @Failure 500 {object} map[string]string "Tabla de tipos de cambio inválida o inaccesible"
+// @Router /admin/fx-rates/reload [post]
+func (h *APIHandler) ReloadFXRatesHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+
+	table, err := application.ReloadFXTable(c.Request.Context())
+	if err != nil {
+		logger.GlobalLogger.Error("Error recargando tipos de cambio", requestID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload FX rates", "details": err.Error()})
+		return
+	}
+
+	logger.GlobalLogger.Info("Tipos de cambio recargados", requestID, map[string]interface{}{
+		"source": table.Source(),
+		"dates":  len(table.Config().Rates),
+	})
+	c.JSON(http.StatusOK, fxTableResponse(table))
+}
+
+func fxTableResponse(table *application.FXTable) gin.H {
+	config := table.Config()
+	return gin.H{
+		"reporting_currency": table.ReportingCurrency(),
+		"base":               config.Base,
+		"rates":              config.Rates,
+		"source":             table.Source(),
+		"loaded_at":          table.LoadedAt(),
+	}
+}
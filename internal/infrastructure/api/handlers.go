@@ -1,37 +1,47 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"os"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/m4ck-y/ETL_go/internal/domain"
-	"github.com/m4ck-y/ETL_go/internal/domain/models"
 	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
 
 	"github.com/m4ck-y/ETL_go/internal/application"
 )
 
 type APIHandler struct {
-	Repo domain.MetricsRepository
+	Repo      domain.MetricsRepository
+	Sources   *application.SourceRegistry
+	Jobs      *application.JobManager
+	Scheduler *application.Scheduler
+
+	// webhookMu serializa las entregas de webhooks para que la deduplicación sea atómica
+	webhookMu sync.Mutex
 }
 
 // IngestHandler inicia el proceso ETL y guarda los resultados.
 // @Summary Ejecuta el proceso ETL de ingestión
-// @Description Ejecuta un proceso ETL que extrae datos de ADS y CRM y guarda los resultados. Soporta filtrado por fecha con el parámetro 'since'.
+// @Description Ejecuta un proceso ETL que extrae datos de las fuentes configuradas (Ads y CRM) y guarda los resultados. Soporta filtrado por fecha con el parámetro 'since'. Con async=true (o el header 'Prefer: respond-async') responde 202 con un job consultable en /jobs/{id}.
 // @Tags ingest
 // @Accept json
 // @Produce json
 // @Param since query string false "Fecha desde la cual filtrar datos (YYYY-MM-DD)"
+// @Param async query bool false "Ejecutar en segundo plano y devolver un job"
 // @Success 201 {object} map[string]string "ETL completado correctamente"
+// @Success 202 {object} map[string]string "Job de ingestión aceptado"
 // @Failure 400 {object} map[string]string "Parámetro de fecha inválido"
+// @Failure 409 {object} map[string]string "El lote ya se está ejecutando"
 // @Failure 500 {object} map[string]string "Error interno del servidor"
 // @Router /ingest/run [post]
 func (h *APIHandler) IngestHandler(c *gin.Context) {
 	requestID := GetRequestID(c)
 
-	if err := validateEnvironment(); err != nil {
+	if err := h.validateSources(); err != nil {
 		logger.GlobalLogger.Error("Configuración inválida", requestID, map[string]interface{}{
 			"error": err.Error(),
 		})
@@ -39,11 +49,13 @@ func (h *APIHandler) IngestHandler(c *gin.Context) {
 		return
 	}
 
-	adsURL := os.Getenv("ADS_API_URL")
-	crmURL := os.Getenv("CRM_API_URL")
-	logger.GlobalLogger.Info("Iniciando ETL con URLs configuradas", requestID, map[string]interface{}{
-		"ads_url": adsURL,
-		"crm_url": crmURL,
+	sources := h.Sources.Sources()
+	sourceURLs := make([]string, 0, len(sources))
+	for _, source := range sources {
+		sourceURLs = append(sourceURLs, source.URL())
+	}
+	logger.GlobalLogger.Info("Iniciando ETL con fuentes configuradas", requestID, map[string]interface{}{
+		"sources": sourceURLs,
 	})
 
 	sinceParam := c.Query("since")
@@ -63,7 +75,7 @@ func (h *APIHandler) IngestHandler(c *gin.Context) {
 		})
 	}
 
-	batchID := generateBatchID(adsURL, crmURL, sinceParam)
+	batchID := generateBatchID(sourceURLs, sinceParam)
 	logger.GlobalLogger.Info("ID de lote generado", requestID, map[string]interface{}{
 		"batch_id": batchID,
 	})
@@ -72,83 +84,98 @@ func (h *APIHandler) IngestHandler(c *gin.Context) {
 		return
 	}
 
-	result, err := application.RunETL(adsURL, crmURL, sinceDate)
-	if err != nil {
-		logger.GlobalLogger.Error("Proceso ETL falló", requestID, map[string]interface{}{
-			"batch_id": batchID,
-			"error":    err.Error(),
-		})
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ETL failed", "details": err.Error()})
-		return
+	run := ingestRun{
+		requestID:  requestID,
+		batchID:    batchID,
+		sinceParam: sinceParam,
+		sinceDate:  sinceDate,
+		sources:    sources,
 	}
+	if isAsyncRequest(c) {
+		job, started := h.Jobs.StartOrGet(batchID, len(sources), func(ctx context.Context, progress application.ProgressReporter) error {
+			_, err := h.runBatch(ctx, run, progress)
+			if errors.Is(err, errBatchAlreadyProcessed) {
+				return nil
+			}
+			return err
+		})
+		if !started {
+			respondBatchRunning(c, batchID, job)
+			return
+		}
 
-	if err := h.Repo.Save(result); err != nil {
-		logger.GlobalLogger.Error("Error guardando resultados", requestID, map[string]interface{}{
+		logger.GlobalLogger.Info("Job de ingestión iniciado", requestID, map[string]interface{}{
 			"batch_id": batchID,
-			"error":    err.Error(),
+			"job_id":   job.ID,
+		})
+
+		statusURL := "/jobs/" + job.ID
+		c.Header("Location", statusURL)
+		c.JSON(http.StatusAccepted, gin.H{
+			"status":     "ETL accepted",
+			"job_id":     job.ID,
+			"batch_id":   batchID,
+			"status_url": statusURL,
 		})
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save ETL results", "details": err.Error()})
 		return
 	}
 
-	h.markBatchAsProcessed(batchID)
-
-	logger.GlobalLogger.Info("ETL completado exitosamente", requestID, map[string]interface{}{
-		"batch_id":               batchID,
-		"processed_combinations": len(result),
+	// La ejecución síncrona también se registra como job del lote para no solaparse con otra.
+	// El contexto de la petición cancela la extracción si el cliente se desconecta.
+	var result *application.ETLResult
+	job, started, err := h.Jobs.RunOrGet(c.Request.Context(), batchID, len(sources), func(ctx context.Context, progress application.ProgressReporter) error {
+		var err error
+		result, err = h.runBatch(ctx, run, progress)
+		return err
 	})
+	if !started {
+		respondBatchRunning(c, batchID, job)
+		return
+	}
+	if errors.Is(err, errBatchAlreadyProcessed) {
+		respondBatchAlreadyProcessed(c, batchID)
+		return
+	}
+	if errors.Is(err, errSaveResults) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save ETL results", "details": err.Error(), "batch_id": batchID})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ETL failed", "details": err.Error(), "batch_id": batchID})
+		return
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"status":                 "ETL completed",
-		"processed_combinations": len(result),
+		"processed_combinations": len(result.Metrics),
 		"batch_id":               batchID,
+		"currency":               result.Currency,
+		"timezone":               result.Timezone,
+		"quality":                result.Quality,
 	})
 }
 
 // GetMetricsHandler obtiene todas las métricas almacenadas.
 // @Summary Obtiene métricas almacenadas con cálculos derivados
-// @Description Retorna un listado de métricas con información de campañas, clics, costo, leads, ventas y métricas calculadas (CPC, CPA, CVR, ROAS).
+// @Description Retorna un listado de métricas con información de campañas, clics, costo, leads, ventas y métricas calculadas (CPC, CPA, CVR, ROAS). Soporta filtrado por rango de fechas con 'from' y 'to'.
 // @Tags metrics
 // @Accept json
 // @Produce json
+// @Param from query string false "Fecha desde (YYYY-MM-DD)"
+// @Param to query string false "Fecha hasta (YYYY-MM-DD)"
+// @Param model query string false "Modelo de atribución multi-touch" Enums(last_touch, first_touch, linear, time_decay)
 // @Success 200 {array} models.MetricResponse "Lista de métricas con cálculos incluidos"
+// @Failure 400 {object} map[string]string "Parámetro de fecha inválido"
 // @Failure 500 {object} map[string]string "Error interno del servidor"
 // @Router /metrics [get]
 func (h *APIHandler) GetMetricsHandler(c *gin.Context) {
 	requestID := GetRequestID(c)
 
-	data, err := h.Repo.GetAll()
-	if err != nil {
-		logger.GlobalLogger.Error("Error obteniendo métricas", requestID, map[string]interface{}{
-			"error": err.Error(),
-		})
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get metrics"})
+	response, ok := h.getMetricsInRange(c)
+	if !ok {
 		return
 	}
 
-	var response []models.MetricResponse
-	for key, m := range data {
-		cpc, cpa, cvrLeadToOpp, cvrOppToWon, roas := application.CalculateDerivedMetrics(m)
-
-		response = append(response, models.MetricResponse{
-			Channel:       m.Channel,
-			UTMCampaign:   key.Campaign,
-			UTMSource:     key.Source,
-			UTMMedium:     key.Medium,
-			Clicks:        m.Clicks,
-			Cost:          m.Cost,
-			Leads:         m.Leads,
-			Opportunities: m.Opportunities,
-			ClosedWon:     m.ClosedWon,
-			Revenue:       m.Revenue,
-			CPC:           cpc,
-			CPA:           cpa,
-			CVRLeadToOpp:  cvrLeadToOpp,
-			CVROppToWon:   cvrOppToWon,
-			ROAS:          roas,
-		})
-	}
-
 	logger.GlobalLogger.Info("Métricas obtenidas exitosamente", requestID, map[string]interface{}{
 		"total_metrics": len(response),
 	})
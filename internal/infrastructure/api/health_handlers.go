@@ -52,9 +52,9 @@ func (h *APIHandler) HealthzHandler(c *gin.Context) {
 func (h *APIHandler) ReadyzHandler(c *gin.Context) {
 	checks := make(map[string]string)
 
-	// Verificar configuración de entorno
-	if err := validateEnvironment(); err != nil {
-		checks["environment"] = "failed"
+	// Verificar fuentes configuradas
+	if err := h.validateSources(); err != nil {
+		checks["sources"] = "failed"
 		response := ReadinessResponse{
 			Status:  "not ready",
 			Time:    time.Now(),
@@ -64,7 +64,7 @@ func (h *APIHandler) ReadyzHandler(c *gin.Context) {
 		c.JSON(http.StatusServiceUnavailable, response)
 		return
 	}
-	checks["environment"] = "ok"
+	checks["sources"] = "ok"
 
 	// Verificar repositorio
 	if h.Repo == nil {
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/m4ck-y/ETL_go/internal/application"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// GetJobHandler consulta el estado de un job de ingestión asíncrono
+// @Summary Obtiene el estado de un job de ingestión
+// @Description Retorna estado, fase (queued, fetching_ads, fetching_crm, aggregating, saving, done), contadores de progreso y error si lo hubo
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del job"
+// @Success 200 {object} models.Job
+// @Failure 404 {object} map[string]string
+// @Router /jobs/{id} [get]
+func (h *APIHandler) GetJobHandler(c *gin.Context) {
+	jobID := c.Param("id")
+
+	job, found := h.Jobs.Get(jobID)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found", "job_id": jobID})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJobHandler cancela un job de ingestión en ejecución
+// @Summary Cancela un job de ingestión
+// @Description Solicita la cancelación del job; las peticiones y reintentos en curso se interrumpen y el job termina con estado canceled
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del job"
+// @Success 202 {object} models.Job "Cancelación solicitada"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "El job ya terminó"
+// @Router /jobs/{id} [delete]
+func (h *APIHandler) CancelJobHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+	jobID := c.Param("id")
+
+	job, err := h.Jobs.Cancel(jobID)
+	if errors.Is(err, application.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found", "job_id": jobID})
+		return
+	}
+	if errors.Is(err, application.ErrJobFinished) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job already finished", "job_id": jobID, "status": job.Status})
+		return
+	}
+
+	logger.GlobalLogger.Info("Cancelación de job solicitada", requestID, map[string]interface{}{
+		"job_id":   jobID,
+		"batch_id": job.BatchID,
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
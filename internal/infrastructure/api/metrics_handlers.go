@@ -3,13 +3,153 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/m4ck-y/ETL_go/internal/application"
 	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
 )
 
+// getMetricsInRange lee los parámetros from/to y model y devuelve las respuestas con las métricas
+// sumadas en ese rango. Sin modelo y con ventana de atribución, los contadores de CRM son solo los de
+// oportunidades atribuidas; con modelo se reparten entre los puntos de contacto de cada contacto y la
+// ventana solo limita la antigüedad de esos puntos de contacto (touchpoint_window_days).
+// Cada respuesta incluye los contactos únicos de su UTM en el rango.
+// Si la respuesta de error ya fue escrita devuelve false.
+func (h *APIHandler) getMetricsInRange(c *gin.Context) ([]models.MetricResponse, bool) {
+	requestID := GetRequestID(c)
+
+	fromDate, toDate, err := parseDateRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	model, err := application.ParseAttributionModel(c.Query("model"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	data, err := h.Repo.GetByDateRange(fromDate, toDate)
+	if err != nil {
+		logger.GlobalLogger.Error("Error obteniendo métricas", requestID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get metrics"})
+		return nil, false
+	}
+
+	contacts, err := h.Repo.CountUniqueContacts(fromDate, toDate)
+	if err != nil {
+		logger.GlobalLogger.Error("Error contando contactos", requestID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get metrics"})
+		return nil, false
+	}
+
+	var response []models.MetricResponse
+	window := application.AttributionWindowDays()
+	switch {
+	case model != "":
+		credit, err := h.creditByModel(fromDate, toDate, model, window)
+		if err != nil {
+			logger.GlobalLogger.Error("Error obteniendo puntos de contacto", requestID, map[string]interface{}{
+				"error": err.Error(),
+				"model": model,
+			})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get metrics"})
+			return nil, false
+		}
+		response = buildCreditedResponses(data, credit, model, window)
+	case window > 0:
+		attribution, err := h.Repo.GetCRMAttribution(fromDate, toDate, window)
+		if err != nil {
+			logger.GlobalLogger.Error("Error obteniendo atribución", requestID, map[string]interface{}{
+				"error": err.Error(),
+			})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get metrics"})
+			return nil, false
+		}
+		response = buildMetricResponses(application.ApplyAttribution(data, attribution))
+	default:
+		response = buildMetricResponses(data)
+	}
+
+	for i := range response {
+		key := models.UTMKey{Campaign: response[i].UTMCampaign, Source: response[i].UTMSource, Medium: response[i].UTMMedium}
+		response[i].UniqueContacts = contacts[key]
+	}
+	return response, true
+}
+
+// creditByModel reparte las oportunidades creadas en el rango entre los puntos de contacto de sus contactos,
+// que pueden ser anteriores al rango
+func (h *APIHandler) creditByModel(from, to *time.Time, model application.AttributionModel, windowDays int) (map[models.UTMKey]models.CRMCredit, error) {
+	conversions, err := h.Repo.ListOpportunities(from, to)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var contacts []string
+	for _, conversion := range conversions {
+		if conversion.ContactHash != "" && !seen[conversion.ContactHash] {
+			seen[conversion.ContactHash] = true
+			contacts = append(contacts, conversion.ContactHash)
+		}
+	}
+	history, err := h.Repo.ListOpportunitiesByContact(contacts)
+	if err != nil {
+		return nil, err
+	}
+	return application.AttributeByModel(conversions, history, model, windowDays), nil
+}
+
+// GetUnattributedMetricsHandler obtiene las oportunidades fuera de la ventana de atribución
+// @Summary Obtiene las métricas de CRM no atribuidas
+// @Description Retorna, por UTM, los contadores de CRM de las oportunidades creadas en el rango sin actividad de ads (impresiones, clics o gasto) de su UTM en los ATTRIBUTION_WINDOW_DAYS días anteriores. Sin ventana configurada la lista es vacía.
+// @Tags metrics
+// @Accept json
+// @Produce json
+// @Param from query string false "Fecha desde (YYYY-MM-DD)"
+// @Param to query string false "Fecha hasta (YYYY-MM-DD)"
+// @Success 200 {array} models.MetricResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /metrics/unattributed [get]
+func (h *APIHandler) GetUnattributedMetricsHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+
+	fromDate, toDate, err := parseDateRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	window := application.AttributionWindowDays()
+	if window == 0 {
+		c.JSON(http.StatusOK, []models.MetricResponse{})
+		return
+	}
+
+	attribution, err := h.Repo.GetCRMAttribution(fromDate, toDate, window)
+	if err != nil {
+		logger.GlobalLogger.Error("Error obteniendo atribución", requestID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get metrics"})
+		return
+	}
+
+	metrics := buildMetricResponses(application.UnattributedMetrics(attribution))
+	if metrics == nil {
+		metrics = []models.MetricResponse{}
+	}
+	c.JSON(http.StatusOK, metrics)
+}
+
 // GetChannelMetricsHandler obtiene métricas filtradas por canal
 // @Summary Obtiene métricas por canal
 // @Description Retorna métricas filtradas por canal con soporte para fechas y paginación
@@ -18,52 +158,34 @@ import (
 // @Produce json
 // @Param from query string false "Fecha desde (YYYY-MM-DD)"
 // @Param to query string false "Fecha hasta (YYYY-MM-DD)"
+// @Param model query string false "Modelo de atribución multi-touch" Enums(last_touch, first_touch, linear, time_decay)
 // @Param channel query string false "Canal específico"
 // @Param limit query int false "Límite de resultados" default(50)
 // @Param offset query int false "Offset para paginación" default(0)
 // @Success 200 {array} models.MetricResponse
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /metrics/channel [get]
 func (h *APIHandler) GetChannelMetricsHandler(c *gin.Context) {
-	// Obtener datos base
-	data, err := h.Repo.GetAll()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get metrics"})
+	// Obtener las métricas sumando los días del rango solicitado
+	metrics, ok := h.getMetricsInRange(c)
+	if !ok {
 		return
 	}
 
-	// Convertir a slice para procesamiento
-	var metrics []models.MetricResponse
-	for key, m := range data {
-		cpc, cpa, cvrLeadToOpp, cvrOppToWon, roas := application.CalculateDerivedMetrics(m)
-		metrics = append(metrics, models.MetricResponse{
-			UTMCampaign:   key.Campaign,
-			UTMSource:     key.Source,
-			UTMMedium:     key.Medium,
-			Clicks:        m.Clicks,
-			Cost:          m.Cost,
-			Leads:         m.Leads,
-			Opportunities: m.Opportunities,
-			ClosedWon:     m.ClosedWon,
-			Revenue:       m.Revenue,
-			CPC:           cpc,
-			CPA:           cpa,
-			CVRLeadToOpp:  cvrLeadToOpp,
-			CVROppToWon:   cvrOppToWon,
-			ROAS:          roas,
-		})
-	}
-
 	// Aplicar filtros
 	channel := c.Query("channel")
 	metrics = filterMetricsByChannel(metrics, channel)
 
 	// Aplicar paginación
-	limitStr := c.DefaultQuery("limit", "50")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, _ := strconv.Atoi(limitStr)
-	offset, _ := strconv.Atoi(offsetStr)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
 
 	metrics = paginateMetrics(metrics, limit, offset)
 
@@ -78,52 +200,34 @@ func (h *APIHandler) GetChannelMetricsHandler(c *gin.Context) {
 // @Produce json
 // @Param from query string false "Fecha desde (YYYY-MM-DD)"
 // @Param to query string false "Fecha hasta (YYYY-MM-DD)"
+// @Param model query string false "Modelo de atribución multi-touch" Enums(last_touch, first_touch, linear, time_decay)
 // @Param utm_campaign query string false "Campaña específica"
 // @Param limit query int false "Límite de resultados" default(50)
 // @Param offset query int false "Offset para paginación" default(0)
 // @Success 200 {array} models.MetricResponse
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /metrics/funnel [get]
 func (h *APIHandler) GetFunnelMetricsHandler(c *gin.Context) {
-	// Obtener datos base
-	data, err := h.Repo.GetAll()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get metrics"})
+	// Obtener las métricas sumando los días del rango solicitado
+	metrics, ok := h.getMetricsInRange(c)
+	if !ok {
 		return
 	}
 
-	// Convertir a slice para procesamiento
-	var metrics []models.MetricResponse
-	for key, m := range data {
-		cpc, cpa, cvrLeadToOpp, cvrOppToWon, roas := application.CalculateDerivedMetrics(m)
-		metrics = append(metrics, models.MetricResponse{
-			UTMCampaign:   key.Campaign,
-			UTMSource:     key.Source,
-			UTMMedium:     key.Medium,
-			Clicks:        m.Clicks,
-			Cost:          m.Cost,
-			Leads:         m.Leads,
-			Opportunities: m.Opportunities,
-			ClosedWon:     m.ClosedWon,
-			Revenue:       m.Revenue,
-			CPC:           cpc,
-			CPA:           cpa,
-			CVRLeadToOpp:  cvrLeadToOpp,
-			CVROppToWon:   cvrOppToWon,
-			ROAS:          roas,
-		})
-	}
-
 	// Aplicar filtros
 	campaign := c.Query("utm_campaign")
 	metrics = filterMetricsByCampaign(metrics, campaign)
 
 	// Aplicar paginación
-	limitStr := c.DefaultQuery("limit", "50")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, _ := strconv.Atoi(limitStr)
-	offset, _ := strconv.Atoi(offsetStr)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
 
 	metrics = paginateMetrics(metrics, limit, offset)
 
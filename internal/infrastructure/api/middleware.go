@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
 	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
 )
 
@@ -67,21 +68,26 @@ func (h *APIHandler) isBatchAlreadyProcessed(c *gin.Context, batchID string) boo
 	}
 
 	if processed {
-		logger.GlobalLogger.Info("Lote ya procesado, omitiendo ETL", requestID, map[string]interface{}{
-			"batch_id": batchID,
-		})
-		c.JSON(http.StatusOK, gin.H{"status": "ETL already completed", "batch_id": batchID})
+		respondBatchAlreadyProcessed(c, batchID)
 		return true
 	}
 
 	return false
 }
 
-func (h *APIHandler) markBatchAsProcessed(batchID string) {
-	if err := h.Repo.MarkBatchProcessed(batchID); err != nil {
-		logger.GlobalLogger.Warn("Error marcando lote como procesado", "system", map[string]interface{}{
-			"batch_id": batchID,
-			"error":    err.Error(),
-		})
-	}
+// respondBatchAlreadyProcessed responde 200 sin volver a ejecutar un lote completado
+func respondBatchAlreadyProcessed(c *gin.Context, batchID string) {
+	logger.GlobalLogger.Info("Lote ya procesado, omitiendo ETL", GetRequestID(c), map[string]interface{}{
+		"batch_id": batchID,
+	})
+	c.JSON(http.StatusOK, gin.H{"status": "ETL already completed", "batch_id": batchID})
+}
+
+// respondBatchRunning responde 409 cuando otro job ya ejecuta el lote
+func respondBatchRunning(c *gin.Context, batchID string, job models.Job) {
+	logger.GlobalLogger.Info("Lote en ejecución por otro job", GetRequestID(c), map[string]interface{}{
+		"batch_id": batchID,
+		"job_id":   job.ID,
+	})
+	c.JSON(http.StatusConflict, gin.H{"error": "Batch already running", "batch_id": batchID, "job_id": job.ID})
 }
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/m4ck-y/ETL_go/internal/application"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// purgeContactRequest identifica al contacto por su email; va en el cuerpo para no quedar en URLs ni logs de acceso
+type purgeContactRequest struct {
+	Email string `json:"email" example:"ana@example.com"`
+}
+
+// PurgeContactHandler borra los datos de un contacto
+// @Summary Borra los datos de un contacto
+// @Description Atiende solicitudes de privacidad: borra las oportunidades del contacto (identificado por el HMAC de su email) y sus registros en cuarentena, y recalcula los contadores de CRM de los días afectados. Requiere la misma CONTACT_HASH_KEY con la que se ingirieron sus datos.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body purgeContactRequest true "Email del contacto"
+// @Success 200 {object} models.ContactPurge
+// @Failure 400 {object} map[string]string "Email ausente o inválido"
+// @Failure 500 {object} map[string]string "Error interno del servidor"
+// @Router /admin/contacts/purge [post]
+func (h *APIHandler) PurgeContactHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+
+	var request purgeContactRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid body; expected {\"email\": \"...\"}"})
+		return
+	}
+	email := strings.TrimSpace(request.Email)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+		return
+	}
+
+	contactHash := application.HashContact(email)
+	purge, err := h.Repo.PurgeContact(contactHash)
+	if err != nil {
+		logger.GlobalLogger.Error("Error borrando los datos del contacto", requestID, map[string]interface{}{
+			"contact_hash": contactHash,
+			"error":        err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge contact"})
+		return
+	}
+
+	logger.GlobalLogger.Info("Datos del contacto borrados", requestID, map[string]interface{}{
+		"contact_hash":  contactHash,
+		"opportunities": purge.Opportunities,
+		"rejections":    purge.Rejections,
+	})
+	c.JSON(http.StatusOK, purge)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// ListRejectionsHandler lista los registros en cuarentena
+// @Summary Lista los registros rechazados por validación
+// @Description Retorna los registros en cuarentena (más recientes primero) con la regla que no superaron (parseable_date, non_negative_clicks, non_negative_impressions, non_negative_cost, non_negative_amount, required_opportunity_id, known_stage, valid_email, convertible_currency), el motivo y el registro original
+// @Tags quality
+// @Accept json
+// @Produce json
+// @Param batch_id query string false "Filtrar por lote"
+// @Param rule query string false "Filtrar por regla"
+// @Param source query string false "Filtrar por fuente"
+// @Param limit query int false "Límite de resultados" default(50)
+// @Param offset query int false "Offset para paginación" default(0)
+// @Success 200 {array} models.Rejection
+// @Failure 500 {object} map[string]string
+// @Router /quality/rejections [get]
+func (h *APIHandler) ListRejectionsHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	filter := models.RejectionFilter{
+		BatchID: c.Query("batch_id"),
+		Rule:    c.Query("rule"),
+		Source:  c.Query("source"),
+	}
+
+	rejections, err := h.Repo.ListRejections(filter, limit, offset)
+	if err != nil {
+		logger.GlobalLogger.Error("Error obteniendo registros en cuarentena", requestID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rejections"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rejections)
+}
+
+// ListUnmatchedHandler lista las claves UTM sin contraparte entre ads y CRM
+// @Summary Lista las claves UTM huérfanas de un lote
+// @Description Retorna la conciliación de claves UTM de un lote (por defecto el último conciliado): ads_only tiene gasto sin oportunidades, crm_only tiene oportunidades sin gasto. Cada huérfana incluye su gasto o revenue y hasta 3 claves del otro lado con nombre parecido. Sin status se listan solo las huérfanas, de mayor impacto primero.
+// @Tags quality
+// @Accept json
+// @Produce json
+// @Param batch_id query string false "Lote; por defecto el último conciliado"
+// @Param status query string false "ads_only, crm_only o matched; por defecto ambas huérfanas"
+// @Param limit query int false "Límite de resultados" default(50)
+// @Param offset query int false "Offset para paginación" default(0)
+// @Success 200 {array} models.KeyReconciliation
+// @Failure 400 {object} map[string]string "Status inválido"
+// @Failure 500 {object} map[string]string
+// @Router /quality/unmatched [get]
+func (h *APIHandler) ListUnmatchedHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	filter := models.ReconciliationFilter{
+		BatchID: c.Query("batch_id"),
+		Status:  c.Query("status"),
+	}
+	switch filter.Status {
+	case "", models.MatchStatusMatched, models.MatchStatusAdsOnly, models.MatchStatusCRMOnly:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status; use ads_only, crm_only or matched"})
+		return
+	}
+
+	keys, err := h.Repo.ListReconciliation(filter, limit, offset)
+	if err != nil {
+		logger.GlobalLogger.Error("Error obteniendo la conciliación de claves UTM", requestID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get unmatched keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
@@ -9,9 +9,26 @@ func (h *APIHandler) RegisterRoutes(router *gin.Engine) {
 	router.Use(RequestIDMiddleware())
 
 	router.POST("/ingest/run", h.IngestHandler)
+	router.POST("/ingest/upload", h.UploadIngestHandler)
+	router.POST("/ingest/ads", h.IngestAdsWebhookHandler)
+	router.POST("/ingest/crm", h.IngestCRMWebhookHandler)
+	router.GET("/jobs/:id", h.GetJobHandler)
+	router.DELETE("/jobs/:id", h.CancelJobHandler)
 	router.GET("/metrics", h.GetMetricsHandler)
 	router.GET("/metrics/channel", h.GetChannelMetricsHandler)
 	router.GET("/metrics/funnel", h.GetFunnelMetricsHandler)
+	router.GET("/metrics/unattributed", h.GetUnattributedMetricsHandler)
+
+	// Bitácora de lotes
+	router.GET("/batches", h.ListBatchesHandler)
+	router.GET("/batches/:id", h.GetBatchHandler)
+
+	// Calidad de datos
+	router.GET("/quality/rejections", h.ListRejectionsHandler)
+	router.GET("/quality/unmatched", h.ListUnmatchedHandler)
+
+	// Normalización de UTMs
+	router.GET("/utm/mappings", h.ListUTMMappingsHandler)
 
 	// Health checks
 	router.GET("/healthz", h.HealthzHandler)
@@ -19,4 +36,9 @@ func (h *APIHandler) RegisterRoutes(router *gin.Engine) {
 
 	// Admin endpoints
 	router.POST("/admin/reset", h.ResetHandler)
+	router.GET("/admin/schedules", h.ListSchedulesHandler)
+	router.POST("/admin/utm-rules/reload", h.ReloadUTMRulesHandler)
+	router.GET("/admin/fx-rates", h.GetFXRatesHandler)
+	router.POST("/admin/fx-rates/reload", h.ReloadFXRatesHandler)
+	router.POST("/admin/contacts/purge", h.PurgeContactHandler)
 }
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/m4ck-y/ETL_go/internal/application"
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// ListSchedulesHandler muestra las ingestiones programadas
+// @Summary Lista las ingestiones programadas
+// @Description Retorna cada programación cron con su ventana 'since', próxima ejecución y el resultado de la última (lote, estado, error)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.ScheduleStatus
+// @Router /admin/schedules [get]
+func (h *APIHandler) ListSchedulesHandler(c *gin.Context) {
+	if h.Scheduler == nil {
+		c.JSON(http.StatusOK, []models.ScheduleStatus{})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.Scheduler.Status())
+}
+
+// RunScheduledIngest ejecuta la ingestión de un tick del scheduler y devuelve el ID del lote.
+// El lote incluye el nombre de la programación y el instante del tick, así cada tick es un lote propio.
+func (h *APIHandler) RunScheduledIngest(ctx context.Context, scheduleName, sinceParam string, tick time.Time) (string, error) {
+	requestID := "scheduler:" + scheduleName
+
+	if err := h.validateSources(); err != nil {
+		return "", err
+	}
+
+	sinceDate, err := parseSinceDate(sinceParam)
+	if err != nil {
+		return "", err
+	}
+
+	sources := h.Sources.Sources()
+	sourceURLs := make([]string, 0, len(sources))
+	for _, source := range sources {
+		sourceURLs = append(sourceURLs, source.URL())
+	}
+
+	batchID := generateScheduledBatchID(sourceURLs, sinceParam, scheduleName, tick)
+
+	processed, err := h.Repo.IsBatchProcessed(batchID)
+	if err != nil {
+		return batchID, fmt.Errorf("failed to check batch status: %w", err)
+	}
+	if processed {
+		logger.GlobalLogger.Info("Lote ya procesado, omitiendo ETL", requestID, map[string]interface{}{
+			"batch_id": batchID,
+		})
+		return batchID, nil
+	}
+
+	run := ingestRun{
+		requestID:  requestID,
+		batchID:    batchID,
+		sinceParam: sinceParam,
+		sinceDate:  sinceDate,
+		sources:    sources,
+	}
+	job, started, err := h.Jobs.RunOrGet(ctx, batchID, len(sources), func(ctx context.Context, progress application.ProgressReporter) error {
+		_, err := h.runBatch(ctx, run, progress)
+		return err
+	})
+	if !started {
+		return batchID, fmt.Errorf("batch already running in job %s", job.ID)
+	}
+	if errors.Is(err, errBatchAlreadyProcessed) {
+		logger.GlobalLogger.Info("Lote ya procesado, omitiendo ETL", requestID, map[string]interface{}{
+			"batch_id": batchID,
+		})
+		return batchID, nil
+	}
+	return batchID, err
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/m4ck-y/ETL_go/internal/application"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// UploadIngestHandler ingesta un archivo CSV o NDJSON con registros de ads o CRM.
+// @Summary Ingesta un archivo CSV o NDJSON
+// @Description Procesa un archivo subido como una fuente más: misma agregación diaria, bitácora de lotes e idempotencia que /ingest/run (el lote se identifica por el contenido del archivo, el tipo y el mapeo). El gasto del archivo se suma al de los demás archivos del mismo día y UTM. Las filas inválidas se descartan y se reportan con su número de línea.
+// @Tags ingest
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Archivo CSV (con encabezado) o NDJSON"
+// @Param kind formData string true "Tipo de registros: ads o crm"
+// @Param format formData string false "csv o ndjson; por defecto se infiere de la extensión (.csv, .ndjson, .jsonl)"
+// @Param mapping formData string false "JSON campo destino → columna (CSV) o ruta con puntos (NDJSON), p.ej. {\"cost\": \"Amount Spent\"}"
+// @Param timezone formData string false "Zona IANA de los timestamps sin zona del archivo (p.ej. America/Mexico_City); por defecto la de reporte"
+// @Success 201 {object} map[string]interface{} "Archivo ingerido; incluye rejected_rows y row_errors"
+// @Success 200 {object} map[string]string "El archivo ya fue procesado"
+// @Failure 400 {object} map[string]string "Archivo, tipo, formato o mapeo inválido"
+// @Failure 409 {object} map[string]string "El mismo archivo se está procesando en otro job"
+// @Failure 422 {object} map[string]interface{} "Ninguna fila válida"
+// @Failure 500 {object} map[string]string "Error interno del servidor"
+// @Router /ingest/upload [post]
+func (h *APIHandler) UploadIngestHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file", "details": err.Error()})
+		return
+	}
+
+	kind := application.SourceKind(strings.ToLower(c.PostForm("kind")))
+	format, err := parseUploadFormat(c.PostForm("format"), fileHeader.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var fields map[string]string
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &fields); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping", "details": err.Error()})
+			return
+		}
+	}
+
+	timezoneName := c.PostForm("timezone")
+	timezone, err := application.LoadTimezone(timezoneName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	// El lote se identifica por el contenido; el archivo se recorre dos veces para no cargarlo en memoria
+	contentHash := md5.New()
+	if _, err := io.Copy(contentHash, file); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file", "details": err.Error()})
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file", "details": err.Error()})
+		return
+	}
+
+	batchID := generateUploadBatchID(kind, format, fields, timezoneName, fmt.Sprintf("%x", contentHash.Sum(nil)))
+	logger.GlobalLogger.Info("Archivo recibido para ingestión", requestID, map[string]interface{}{
+		"filename": fileHeader.Filename,
+		"size":     fileHeader.Size,
+		"kind":     kind,
+		"format":   format,
+		"batch_id": batchID,
+	})
+
+	if h.isBatchAlreadyProcessed(c, batchID) {
+		return
+	}
+
+	source, err := application.NewFileSource(fileHeader.Filename, kind, format, fields, file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "batch_id": batchID})
+		return
+	}
+	source.SetTimezone(timezone)
+
+	run := ingestRun{
+		requestID: requestID,
+		batchID:   batchID,
+		sources:   []application.Source{source},
+		// El gasto de un archivo (p.ej. una exportación por cuenta) se suma al de los demás archivos
+		merge: true,
+	}
+
+	// Como en /ingest/run, el lote se reserva como job para que dos envíos del mismo archivo no lo ejecuten dos veces
+	var result *application.ETLResult
+	job, started, err := h.Jobs.RunOrGet(c.Request.Context(), batchID, 1, func(ctx context.Context, progress application.ProgressReporter) error {
+		var err error
+		result, err = h.runBatch(ctx, run, progress)
+		return err
+	})
+	if !started {
+		respondBatchRunning(c, batchID, job)
+		return
+	}
+	if errors.Is(err, application.ErrNoValidRows) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":         "No valid rows",
+			"batch_id":      batchID,
+			"rejected_rows": source.RejectedRows(),
+			"row_errors":    source.RowErrors(),
+		})
+		return
+	}
+	if errors.Is(err, errBatchAlreadyProcessed) {
+		respondBatchAlreadyProcessed(c, batchID)
+		return
+	}
+	if errors.Is(err, errSaveResults) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save ETL results", "details": err.Error(), "batch_id": batchID})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ETL failed", "details": err.Error(), "batch_id": batchID})
+		return
+	}
+
+	if source.RejectedRows() > 0 {
+		logger.GlobalLogger.Warn("Filas descartadas en el archivo", requestID, map[string]interface{}{
+			"batch_id":      batchID,
+			"rejected_rows": source.RejectedRows(),
+		})
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":                 "Upload ingested",
+		"batch_id":               batchID,
+		"records":                result.SourceRecords[source.Name()],
+		"processed_combinations": len(result.Metrics),
+		"rejected_rows":          source.RejectedRows(),
+		"row_errors":             source.RowErrors(),
+		"currency":               result.Currency,
+		"timezone":               result.Timezone,
+		"quality":                result.Quality,
+	})
+}
+
+// parseUploadFormat usa el formato explícito o lo infiere de la extensión del archivo
+func parseUploadFormat(formatParam, filename string) (application.UploadFormat, error) {
+	if formatParam != "" {
+		return application.UploadFormat(strings.ToLower(formatParam)), nil
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return application.UploadFormatCSV, nil
+	case ".ndjson", ".jsonl":
+		return application.UploadFormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("cannot infer the format of %q (expected format=csv or format=ndjson)", filename)
+	}
+}
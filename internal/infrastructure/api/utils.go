@@ -2,29 +2,62 @@ package api
 
 import (
 	"crypto/md5"
+	"encoding/json"
 	"fmt"
-	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
+
+	"github.com/m4ck-y/ETL_go/internal/application"
 	"github.com/m4ck-y/ETL_go/internal/domain/models"
 )
 
-// generateBatchID crea un identificador único para lotes ETL
-func generateBatchID(adsURL, crmURL, sinceParam string) string {
+// generateBatchID crea un identificador único para lotes ETL a partir de las URLs de las fuentes
+func generateBatchID(sourceURLs []string, sinceParam string) string {
 	// Incluir timestamp diario para granularidad por día
-	input := fmt.Sprintf("%s|%s|%s|%d", adsURL, crmURL, sinceParam, time.Now().Unix()/86400)
+	input := fmt.Sprintf("%s|%s|%d", strings.Join(sourceURLs, "|"), sinceParam, time.Now().Unix()/86400)
+	hash := md5.Sum([]byte(input))
+	return fmt.Sprintf("%x", hash)[:16]
+}
+
+// generateScheduledBatchID crea el identificador de un lote disparado por el scheduler
+func generateScheduledBatchID(sourceURLs []string, sinceParam, scheduleName string, tick time.Time) string {
+	input := fmt.Sprintf("%s|%s|schedule:%s|%d", strings.Join(sourceURLs, "|"), sinceParam, scheduleName, tick.Unix())
+	hash := md5.Sum([]byte(input))
+	return fmt.Sprintf("%x", hash)[:16]
+}
+
+// generateUploadBatchID crea el identificador de un lote subido como archivo; el mismo contenido
+// con el mismo tipo y mapeo se procesa una sola vez
+func generateUploadBatchID(kind application.SourceKind, format application.UploadFormat, fields map[string]string, timezone, contentHash string) string {
+	// json.Marshal ordena las claves, así el mapeo no depende del orden en que se envió
+	mapping, _ := json.Marshal(fields)
+	input := fmt.Sprintf("upload|%s|%s|%s|%s", kind, format, mapping, contentHash)
+	// La zona cambia los días de los registros; sin ella el ID es el mismo que antes de existir el parámetro
+	if timezone != "" {
+		input += "|" + timezone
+	}
 	hash := md5.Sum([]byte(input))
 	return fmt.Sprintf("%x", hash)[:16]
 }
 
-// parseSinceDate parsea el parámetro opcional de fecha
+// generateWebhookBatchID crea el identificador de una entrega de webhook a partir de su clave de idempotencia
+func generateWebhookBatchID(kind application.SourceKind, idempotencyKey string) string {
+	input := fmt.Sprintf("webhook|%s|%s", kind, idempotencyKey)
+	hash := md5.Sum([]byte(input))
+	return fmt.Sprintf("%x", hash)[:16]
+}
+
+// parseSinceDate parsea el parámetro opcional de fecha como medianoche en la zona de reporte
 func parseSinceDate(sinceParam string) (*time.Time, error) {
 	if sinceParam == "" {
 		return nil, nil
 	}
 
-	parsedDate, err := time.Parse("2006-01-02", sinceParam)
+	parsedDate, err := application.ParseReportingDate(sinceParam)
 	if err != nil {
 		return nil, fmt.Errorf("formato de fecha inválido. Use YYYY-MM-DD")
 	}
@@ -32,13 +65,18 @@ func parseSinceDate(sinceParam string) (*time.Time, error) {
 	return &parsedDate, nil
 }
 
-// validateEnvironment valida que las variables de entorno necesarias estén configuradas
-func validateEnvironment() error {
-	adsURL := os.Getenv("ADS_API_URL")
-	crmURL := os.Getenv("CRM_API_URL")
+// isAsyncRequest indica si el cliente pidió ejecución asíncrona (?async=true o Prefer: respond-async)
+func isAsyncRequest(c *gin.Context) bool {
+	if async, err := strconv.ParseBool(c.Query("async")); err == nil && async {
+		return true
+	}
+	return strings.Contains(strings.ToLower(c.GetHeader("Prefer")), "respond-async")
+}
 
-	if adsURL == "" || crmURL == "" {
-		return fmt.Errorf("ADS_API_URL y CRM_API_URL deben estar configuradas")
+// validateSources valida que haya al menos una fuente configurada
+func (h *APIHandler) validateSources() error {
+	if h.Sources == nil || len(h.Sources.Sources()) == 0 {
+		return fmt.Errorf("no hay fuentes configuradas: defina SOURCES_CONFIG o ADS_API_URL y CRM_API_URL")
 	}
 
 	return nil
@@ -48,7 +86,7 @@ func parseDateRange(fromParam, toParam string) (*time.Time, *time.Time, error) {
 	var fromDate, toDate *time.Time
 
 	if fromParam != "" {
-		parsed, err := time.Parse("2006-01-02", fromParam)
+		parsed, err := application.ParseReportingDate(fromParam)
 		if err != nil {
 			return nil, nil, fmt.Errorf("fecha 'from' inválida")
 		}
@@ -56,16 +94,106 @@ func parseDateRange(fromParam, toParam string) (*time.Time, *time.Time, error) {
 	}
 
 	if toParam != "" {
-		parsed, err := time.Parse("2006-01-02", toParam)
+		parsed, err := application.ParseReportingDate(toParam)
 		if err != nil {
 			return nil, nil, fmt.Errorf("fecha 'to' inválida")
 		}
 		toDate = &parsed
 	}
 
+	if fromDate != nil && toDate != nil && fromDate.After(*toDate) {
+		return nil, nil, fmt.Errorf("la fecha 'from' debe ser anterior o igual a 'to'")
+	}
+
 	return fromDate, toDate, nil
 }
 
+// buildMetricResponses convierte las métricas agregadas en respuestas con sus métricas derivadas
+func buildMetricResponses(data map[models.UTMKey]models.AggregatedMetrics) []models.MetricResponse {
+	var response []models.MetricResponse
+	for key, m := range data {
+		response = append(response, buildMetricResponse(key, m, m.CRMCredit(), application.CalculateDerivedMetrics(m), application.EvaluateKPIs(m)))
+	}
+	sortMetricResponses(response)
+	return response
+}
+
+// buildCreditedResponses une los contadores de ads de data con el crédito de CRM repartido por model,
+// con puntos de contacto de hasta touchpointWindowDays días antes de cada oportunidad. La ventana de
+// atribución contra los ads no se aplica con un modelo, así que attribution_window_days queda en 0.
+// Una UTM que solo recibe crédito (p.ej. la primera por la que llegó un contacto) aparece sin ads.
+func buildCreditedResponses(data map[models.UTMKey]models.AggregatedMetrics, credit map[models.UTMKey]models.CRMCredit, model application.AttributionModel, touchpointWindowDays int) []models.MetricResponse {
+	var response []models.MetricResponse
+	add := func(key models.UTMKey, m models.AggregatedMetrics, c models.CRMCredit) {
+		metric := buildMetricResponse(key, m, c, application.CalculateCreditedMetrics(m, c), application.EvaluateCreditedKPIs(m, c))
+		metric.AttributionModel = string(model)
+		metric.AttributionWindowDays = 0
+		metric.TouchpointWindowDays = touchpointWindowDays
+		response = append(response, metric)
+	}
+	for key, m := range data {
+		if c := credit[key]; m.HasAdActivity() || !c.IsZero() {
+			add(key, m, c)
+		}
+	}
+	for key, c := range credit {
+		if _, ok := data[key]; !ok {
+			add(key, models.AggregatedMetrics{}, c)
+		}
+	}
+	sortMetricResponses(response)
+	return response
+}
+
+// sortMetricResponses ordena por campaña, fuente y medio para que limit/offset den páginas estables
+func sortMetricResponses(response []models.MetricResponse) {
+	sort.Slice(response, func(i, j int) bool {
+		a, b := response[i], response[j]
+		if a.UTMCampaign != b.UTMCampaign {
+			return a.UTMCampaign < b.UTMCampaign
+		}
+		if a.UTMSource != b.UTMSource {
+			return a.UTMSource < b.UTMSource
+		}
+		return a.UTMMedium < b.UTMMedium
+	})
+}
+
+func buildMetricResponse(key models.UTMKey, m models.AggregatedMetrics, crm models.CRMCredit, derived application.DerivedMetrics, kpis map[string]float64) models.MetricResponse {
+	return models.MetricResponse{
+		Channel:               m.Channel,
+		UTMCampaign:           key.Campaign,
+		UTMSource:             key.Source,
+		UTMMedium:             key.Medium,
+		Impressions:           m.Impressions,
+		Clicks:                m.Clicks,
+		Cost:                  m.Cost,
+		Leads:                 crm.Leads.InexactFloat64(),
+		MQLs:                  crm.MQLs.InexactFloat64(),
+		SQLs:                  crm.SQLs.InexactFloat64(),
+		Opportunities:         crm.Opportunities.InexactFloat64(),
+		ClosedWon:             crm.ClosedWon.InexactFloat64(),
+		ClosedLost:            crm.ClosedLost.InexactFloat64(),
+		Revenue:               crm.Revenue,
+		CTR:                   derived.CTR,
+		CPM:                   derived.CPM,
+		CPC:                   derived.CPC,
+		CPA:                   derived.CPA,
+		CVRLeadToOpp:          derived.CVRLeadToOpp,
+		CVRLeadToMQL:          derived.CVRLeadToMQL,
+		CVRMQLToSQL:           derived.CVRMQLToSQL,
+		CVRSQLToOpp:           derived.CVRSQLToOpp,
+		CVROppToWon:           derived.CVROppToWon,
+		CostPerOpp:            derived.CostPerOpp,
+		CostPerWon:            derived.CostPerWon,
+		ROAS:                  derived.ROAS,
+		Currency:              application.ReportingCurrency(),
+		Timezone:              application.ReportingTimezone().String(),
+		AttributionWindowDays: application.AttributionWindowDays(),
+		Derived:               kpis,
+	}
+}
+
 func filterMetricsByChannel(metrics []models.MetricResponse, channel string) []models.MetricResponse {
 	if channel == "" {
 		return metrics
@@ -107,3 +235,22 @@ func paginateMetrics(metrics []models.MetricResponse, limit, offset int) []model
 
 	return metrics[offset:end]
 }
+
+// groupUTMMappings agrupa los valores crudos (ordenados por campo y canónico) bajo su valor canónico
+func groupUTMMappings(mappings []models.UTMMapping) []models.UTMMappingGroup {
+	groups := []models.UTMMappingGroup{}
+	for _, m := range mappings {
+		last := len(groups) - 1
+		if last < 0 || groups[last].Field != m.Field || groups[last].Canonical != m.Canonical {
+			groups = append(groups, models.UTMMappingGroup{Field: m.Field, Canonical: m.Canonical})
+			last++
+		}
+		groups[last].Records += m.Records
+		groups[last].RawValues = append(groups[last].RawValues, models.UTMRawMapping{
+			Raw:      m.Raw,
+			Records:  m.Records,
+			LastSeen: m.LastSeen,
+		})
+	}
+	return groups
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/m4ck-y/ETL_go/internal/application"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// ListUTMMappingsHandler muestra qué valores crudos se normalizaron a cada valor canónico
+// @Summary Lista los valores UTM crudos agrupados por valor canónico
+// @Description Retorna, por campo (campaign, source, medium) y valor canónico, los valores crudos recibidos en las ingestas y cuántos registros trajo cada uno. El valor canónico de cada valor crudo es el de su última ingesta.
+// @Tags utm
+// @Accept json
+// @Produce json
+// @Param field query string false "campaign, source o medium"
+// @Success 200 {array} models.UTMMappingGroup
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /utm/mappings [get]
+func (h *APIHandler) ListUTMMappingsHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+
+	field := c.Query("field")
+	switch field {
+	case "", application.UTMFieldCampaign, application.UTMFieldSource, application.UTMFieldMedium:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "field debe ser campaign, source o medium"})
+		return
+	}
+
+	mappings, err := h.Repo.ListUTMMappings(field)
+	if err != nil {
+		logger.GlobalLogger.Error("Error obteniendo valores UTM observados", requestID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get UTM mappings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, groupUTMMappings(mappings))
+}
+
+// ReloadUTMRulesHandler vuelve a leer las reglas de normalización de UTMs
+// @Summary Recarga las reglas de normalización de UTMs
+// @Description Vuelve a leer UTM_RULES_CONFIG. Las nuevas reglas aplican a las ingestas posteriores; los hechos ya guardados conservan su clave. Si el archivo es inválido se conservan las reglas vigentes.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Reglas recargadas"
+// @Failure 500 {object} map[string]string "Archivo de reglas inválido"
+// @Router /admin/utm-rules/reload [post]
+func (h *APIHandler) ReloadUTMRulesHandler(c *gin.Context) {
+	requestID := GetRequestID(c)
+
+	rules, err := application.ReloadUTMRules()
+	if err != nil {
+		logger.GlobalLogger.Error("Error recargando reglas de UTM", requestID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload UTM rules", "details": err.Error()})
+		return
+	}
+
+	logger.GlobalLogger.Info("Reglas de UTM recargadas", requestID, nil)
+	c.JSON(http.StatusOK, gin.H{"status": "UTM rules reloaded", "loaded_at": rules.LoadedAt()})
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/m4ck-y/ETL_go/internal/application"
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+)
+
+// maxWebhookBodyBytes limita el tamaño de una entrega de webhook (10 MiB)
+const maxWebhookBodyBytes = 10 << 20
+
+// IdempotencyKeyHeader identifica una entrega; los reintentos deben reenviar la misma clave
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IngestAdsWebhookHandler recibe registros de ads enviados por la plataforma.
+// @Summary Recibe registros de ads por webhook
+// @Description Acepta un arreglo de registros con el formato de AdRecord, los valida y suma sus métricas a las almacenadas. Las entregas repetidas con el mismo header Idempotency-Key (o, sin header, el mismo cuerpo) no se vuelven a contar.
+// @Tags ingest
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Clave de idempotencia de la entrega"
+// @Param records body []models.AdRecord true "Registros de ads"
+// @Success 201 {object} map[string]interface{} "Registros incorporados"
+// @Success 200 {object} map[string]string "Entrega ya procesada"
+// @Failure 400 {object} map[string]string "JSON inválido o arreglo vacío"
+// @Failure 413 {object} map[string]string "Cuerpo demasiado grande"
+// @Failure 422 {object} map[string]interface{} "Registros inválidos"
+// @Failure 500 {object} map[string]string "Error interno del servidor"
+// @Router /ingest/ads [post]
+func (h *APIHandler) IngestAdsWebhookHandler(c *gin.Context) {
+	var records []models.AdRecord
+	body, ok := readWebhookBody(c, &records)
+	if !ok {
+		return
+	}
+
+	h.ingestWebhook(c, webhookDelivery{
+		kind:    application.SourceKindAds,
+		records: application.Records{Ads: records},
+		count:   len(records),
+		errors:  application.ValidateAdRecords(records),
+		body:    body,
+	})
+}
+
+// IngestCRMWebhookHandler recibe oportunidades enviadas por el CRM.
+// @Summary Recibe oportunidades de CRM por webhook
+// @Description Acepta un arreglo de oportunidades con el formato de CRMRecord, las valida y suma sus métricas a las almacenadas. Las entregas repetidas con el mismo header Idempotency-Key (o, sin header, el mismo cuerpo) no se vuelven a contar.
+// @Tags ingest
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Clave de idempotencia de la entrega"
+// @Param records body []models.CRMRecord true "Oportunidades de CRM"
+// @Success 201 {object} map[string]interface{} "Registros incorporados"
+// @Success 200 {object} map[string]string "Entrega ya procesada"
+// @Failure 400 {object} map[string]string "JSON inválido o arreglo vacío"
+// @Failure 413 {object} map[string]string "Cuerpo demasiado grande"
+// @Failure 422 {object} map[string]interface{} "Registros inválidos"
+// @Failure 500 {object} map[string]string "Error interno del servidor"
+// @Router /ingest/crm [post]
+func (h *APIHandler) IngestCRMWebhookHandler(c *gin.Context) {
+	var records []models.CRMRecord
+	body, ok := readWebhookBody(c, &records)
+	if !ok {
+		return
+	}
+
+	h.ingestWebhook(c, webhookDelivery{
+		kind:    application.SourceKindCRM,
+		records: application.Records{CRM: records},
+		count:   len(records),
+		errors:  application.ValidateCRMRecords(records),
+		body:    body,
+	})
+}
+
+// webhookDelivery agrupa una entrega ya decodificada y validada
+type webhookDelivery struct {
+	kind    application.SourceKind
+	records application.Records
+	count   int
+	errors  []application.RecordError
+	body    []byte
+}
+
+// ingestWebhook incorpora la entrega como un lote que suma sus hechos a los existentes.
+// Una entrega con registros inválidos se rechaza completa para que el reintento corregido cuente una sola vez.
+func (h *APIHandler) ingestWebhook(c *gin.Context, delivery webhookDelivery) {
+	requestID := GetRequestID(c)
+
+	if len(delivery.errors) > 0 {
+		logger.GlobalLogger.Warn("Entrega de webhook rechazada por registros inválidos", requestID, map[string]interface{}{
+			"kind":            delivery.kind,
+			"invalid_records": len(delivery.errors),
+		})
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid records", "record_errors": delivery.errors})
+		return
+	}
+
+	idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+	if idempotencyKey == "" {
+		idempotencyKey = fmt.Sprintf("%x", md5.Sum(delivery.body))
+	}
+	batchID := generateWebhookBatchID(delivery.kind, idempotencyKey)
+
+	// Serializa verificación y guardado para que dos reintentos simultáneos no se cuenten ambos
+	h.webhookMu.Lock()
+	defer h.webhookMu.Unlock()
+
+	if h.isBatchAlreadyProcessed(c, batchID) {
+		return
+	}
+
+	run := ingestRun{
+		requestID: requestID,
+		batchID:   batchID,
+		sources:   []application.Source{application.NewStaticSource("webhook:"+string(delivery.kind), delivery.kind, delivery.records)},
+		merge:     true,
+	}
+
+	result, err := h.runBatch(c.Request.Context(), run, nil)
+	if errors.Is(err, errBatchAlreadyProcessed) {
+		respondBatchAlreadyProcessed(c, batchID)
+		return
+	}
+	if errors.Is(err, errSaveResults) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save ETL results", "details": err.Error(), "batch_id": batchID})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ETL failed", "details": err.Error(), "batch_id": batchID})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":                 "Records merged",
+		"batch_id":               batchID,
+		"idempotency_key":        idempotencyKey,
+		"records":                delivery.count,
+		"processed_combinations": len(result.Metrics),
+		"currency":               result.Currency,
+		"timezone":               result.Timezone,
+	})
+}
+
+// readWebhookBody lee el cuerpo con límite de tamaño y lo decodifica en records; responde el error si falla
+func readWebhookBody[T any](c *gin.Context, records *[]T) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Payload too large", "limit_bytes": maxBytesErr.Limit})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body", "details": err.Error()})
+		return nil, false
+	}
+
+	if err := json.Unmarshal(body, records); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: expected an array of records", "details": err.Error()})
+		return nil, false
+	}
+	if len(*records) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Empty payload"})
+		return nil, false
+	}
+
+	return body, true
+}
@@ -1,69 +1,471 @@
 package repository
 
 import (
+	"bytes"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/m4ck-y/ETL_go/internal/domain/models"
 )
 
 type InMemoryMetricsRepository struct {
-	data             map[models.UTMKey]models.AggregatedMetrics
-	processedBatches map[string]bool
-	mu               sync.RWMutex
+	data    map[models.DailyKey]models.AggregatedMetrics
+	batches map[string]models.Batch
+	// adsDeltas guarda los contadores de ads de cada lote de webhooks; adsPulled, los hechos cuyos
+	// contadores de ads extrajo Save y que por eso no se recalculan desde adsDeltas
+	adsDeltas map[string]map[models.DailyKey]models.AggregatedMetrics
+	adsPulled map[models.DailyKey]bool
+	// opportunities guarda el último snapshot de cada oportunidad; define los contadores de CRM de data
+	opportunities map[string]models.Opportunity
+	utmMappings   map[utmMappingKey]models.UTMMapping
+	// reconciliations guarda la clasificación de claves por lote; lastReconciled es el último lote clasificado
+	reconciliations map[string][]models.KeyReconciliation
+	lastReconciled  string
+	// rejections se guarda en orden de inserción; nextRejectionID emula el autoincremento
+	rejections      []models.Rejection
+	nextRejectionID int64
+	mu              sync.RWMutex
 }
 
 func NewInMemoryMetricsRepository() *InMemoryMetricsRepository {
 	return &InMemoryMetricsRepository{
-		data:             make(map[models.UTMKey]models.AggregatedMetrics),
-		processedBatches: make(map[string]bool),
+		data:            make(map[models.DailyKey]models.AggregatedMetrics),
+		batches:         make(map[string]models.Batch),
+		adsDeltas:       make(map[string]map[models.DailyKey]models.AggregatedMetrics),
+		adsPulled:       make(map[models.DailyKey]bool),
+		opportunities:   make(map[string]models.Opportunity),
+		utmMappings:     make(map[utmMappingKey]models.UTMMapping),
+		reconciliations: make(map[string][]models.KeyReconciliation),
 	}
 }
 
-func (r *InMemoryMetricsRepository) Save(metrics map[models.UTMKey]models.AggregatedMetrics) error {
+func (r *InMemoryMetricsRepository) Save(metrics map[models.DailyKey]models.AggregatedMetrics) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	for k, v := range metrics {
-		r.data[k] = v
+		r.data[k] = v.WithCRM(r.data[k])
+		r.adsPulled[k] = true
 	}
 	return nil
 }
 
+func (r *InMemoryMetricsRepository) Merge(batchID string, metrics map[models.DailyKey]models.AggregatedMetrics) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deltas := r.adsDeltas[batchID]
+	if deltas == nil {
+		deltas = make(map[models.DailyKey]models.AggregatedMetrics)
+		r.adsDeltas[batchID] = deltas
+	}
+	// Un reintento del mismo lote reemplaza sus contadores en lugar de sumarlos otra vez
+	for k, v := range metrics {
+		deltas[k] = v.WithCRM(models.AggregatedMetrics{})
+	}
+
+	batchIDs := make([]string, 0, len(r.adsDeltas))
+	for id := range r.adsDeltas {
+		batchIDs = append(batchIDs, id)
+	}
+	sort.Strings(batchIDs)
+	for k := range metrics {
+		if r.adsPulled[k] {
+			continue
+		}
+		var ads models.AggregatedMetrics
+		for _, id := range batchIDs {
+			ads = ads.Add(r.adsDeltas[id][k])
+		}
+		r.data[k] = ads.WithCRM(r.data[k])
+	}
+	return nil
+}
+
+func (r *InMemoryMetricsRepository) SaveOpportunities(opportunities []models.Opportunity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	affected := make(map[models.DailyKey]bool)
+	for _, opportunity := range opportunities {
+		current, exists := r.opportunities[opportunity.ID]
+		if exists {
+			if !current.SupersededBy(opportunity) {
+				continue
+			}
+			affected[current.Key()] = true
+		}
+		r.opportunities[opportunity.ID] = opportunity
+		affected[opportunity.Key()] = true
+	}
+	r.recomputeCRMCounters(affected)
+	return nil
+}
+
+// recomputeCRMCounters reemplaza los contadores de CRM de los hechos diarios por la suma de sus oportunidades
+func (r *InMemoryMetricsRepository) recomputeCRMCounters(affected map[models.DailyKey]bool) {
+	crm := make(map[models.DailyKey]models.AggregatedMetrics, len(affected))
+	for _, opportunity := range r.opportunities {
+		if key := opportunity.Key(); affected[key] {
+			crm[key] = crm[key].Add(opportunity.Contribution())
+		}
+	}
+	for key := range affected {
+		r.data[key] = r.data[key].WithCRM(crm[key])
+	}
+}
+
 func (r *InMemoryMetricsRepository) GetAll() (map[models.UTMKey]models.AggregatedMetrics, error) {
+	return r.GetByDateRange(nil, nil)
+}
+
+func (r *InMemoryMetricsRepository) GetByKey(key models.UTMKey) (models.AggregatedMetrics, bool, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	copy := make(map[models.UTMKey]models.AggregatedMetrics)
-	for k, v := range r.data {
-		copy[k] = v
+	totals := sumDailyFacts(r.data, func(k models.DailyKey) bool { return k.UTMKey == key })
+	value, found := totals[key]
+	return value, found, nil
+}
+
+func (r *InMemoryMetricsRepository) GetByDateRange(from, to *time.Time) (map[models.UTMKey]models.AggregatedMetrics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return sumDailyFacts(r.data, func(k models.DailyKey) bool { return isDayInRange(k.Date, from, to) }), nil
+}
+
+func (r *InMemoryMetricsRepository) GetCRMAttribution(from, to *time.Time, windowDays int) (map[models.UTMKey]models.CRMAttribution, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[models.UTMKey]models.CRMAttribution)
+	for _, opportunity := range r.opportunities {
+		if !isDayInRange(opportunity.Day, from, to) {
+			continue
+		}
+		attribution := result[opportunity.UTM]
+		if r.hasAdActivityBefore(opportunity, windowDays) {
+			attribution.Attributed = attribution.Attributed.Add(opportunity.Contribution())
+		} else {
+			attribution.Unattributed = attribution.Unattributed.Add(opportunity.Contribution())
+		}
+		result[opportunity.UTM] = attribution
 	}
-	return copy, nil
+	return result, nil
 }
 
-func (r *InMemoryMetricsRepository) GetByKey(key models.UTMKey) (models.AggregatedMetrics, bool, error) {
+// hasAdActivityBefore busca actividad de ads en la UTM de la oportunidad desde windowDays días antes hasta su día
+func (r *InMemoryMetricsRepository) hasAdActivityBefore(opportunity models.Opportunity, windowDays int) bool {
+	day, err := time.Parse(dateLayout, opportunity.Day)
+	if err != nil {
+		return false
+	}
+	for i := 0; i <= windowDays; i++ {
+		key := models.DailyKey{Date: day.AddDate(0, 0, -i).Format(dateLayout), UTMKey: opportunity.UTM}
+		if r.data[key].HasAdActivity() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *InMemoryMetricsRepository) ListOpportunities(from, to *time.Time) ([]models.Opportunity, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	value, found := r.data[key]
-	return value, found, nil
+
+	var result []models.Opportunity
+	for _, opportunity := range r.opportunities {
+		if isDayInRange(opportunity.Day, from, to) {
+			result = append(result, opportunity)
+		}
+	}
+	sortOpportunitiesByID(result)
+	return result, nil
+}
+
+func (r *InMemoryMetricsRepository) ListOpportunitiesByContact(contacts []string) ([]models.Opportunity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(contacts))
+	for _, contact := range contacts {
+		if contact != "" {
+			wanted[contact] = true
+		}
+	}
+	var result []models.Opportunity
+	for _, opportunity := range r.opportunities {
+		if wanted[opportunity.ContactHash] {
+			result = append(result, opportunity)
+		}
+	}
+	sortOpportunitiesByID(result)
+	return result, nil
+}
+
+func (r *InMemoryMetricsRepository) CountUniqueContacts(from, to *time.Time) (map[models.UTMKey]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	contacts := make(map[models.UTMKey]map[string]bool)
+	for _, opportunity := range r.opportunities {
+		if opportunity.ContactHash == "" || !isDayInRange(opportunity.Day, from, to) {
+			continue
+		}
+		if contacts[opportunity.UTM] == nil {
+			contacts[opportunity.UTM] = make(map[string]bool)
+		}
+		contacts[opportunity.UTM][opportunity.ContactHash] = true
+	}
+	result := make(map[models.UTMKey]int, len(contacts))
+	for key, set := range contacts {
+		result[key] = len(set)
+	}
+	return result, nil
+}
+
+func (r *InMemoryMetricsRepository) PseudonymizeContacts(hash func(email string) string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := 0
+	for id, opportunity := range r.opportunities {
+		if strings.Contains(opportunity.ContactHash, "@") {
+			opportunity.ContactHash = hash(opportunity.ContactHash)
+			r.opportunities[id] = opportunity
+			changed++
+		}
+	}
+	for i, rejection := range r.rejections {
+		if record, ok := pseudonymizeRecord(rejection.Record, hash); ok {
+			r.rejections[i].Record = record
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func (r *InMemoryMetricsRepository) PurgeContact(contactHash string) (models.ContactPurge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purge := models.ContactPurge{ContactHash: contactHash}
+	if contactHash == "" {
+		return purge, nil
+	}
+	affected := make(map[models.DailyKey]bool)
+	for id, opportunity := range r.opportunities {
+		if opportunity.ContactHash == contactHash {
+			delete(r.opportunities, id)
+			affected[opportunity.Key()] = true
+			purge.Opportunities++
+		}
+	}
+	r.recomputeCRMCounters(affected)
+
+	kept := r.rejections[:0]
+	for _, rejection := range r.rejections {
+		if bytes.Contains(rejection.Record, []byte(contactHash)) {
+			purge.Rejections++
+			continue
+		}
+		kept = append(kept, rejection)
+	}
+	r.rejections = kept
+	return purge, nil
 }
 
 func (r *InMemoryMetricsRepository) Clear() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.data = make(map[models.UTMKey]models.AggregatedMetrics)
-	r.processedBatches = make(map[string]bool)
+	r.data = make(map[models.DailyKey]models.AggregatedMetrics)
+	r.batches = make(map[string]models.Batch)
+	r.adsDeltas = make(map[string]map[models.DailyKey]models.AggregatedMetrics)
+	r.adsPulled = make(map[models.DailyKey]bool)
+	r.opportunities = make(map[string]models.Opportunity)
+	r.utmMappings = make(map[utmMappingKey]models.UTMMapping)
+	r.reconciliations = make(map[string][]models.KeyReconciliation)
+	r.lastReconciled = ""
+	r.rejections = nil
 	return nil
 }
 
 func (r *InMemoryMetricsRepository) IsBatchProcessed(batchID string) (bool, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	processed, exists := r.processedBatches[batchID]
-	return processed && exists, nil
+	batch, exists := r.batches[batchID]
+	return exists && batch.Status == models.BatchStatusCompleted, nil
 }
 
 func (r *InMemoryMetricsRepository) MarkBatchProcessed(batchID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.processedBatches[batchID] = true
+	batch, exists := r.batches[batchID]
+	if !exists {
+		batch = models.Batch{ID: batchID, StartedAt: time.Now().UTC(), Attempts: 1}
+	}
+	batch.Status = models.BatchStatusCompleted
+	r.batches[batchID] = batch
+	return nil
+}
+
+func (r *InMemoryMetricsRepository) SaveBatch(batch models.Batch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches[batch.ID] = batch
 	return nil
 }
+
+func (r *InMemoryMetricsRepository) GetBatch(batchID string) (models.Batch, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	batch, found := r.batches[batchID]
+	return batch, found, nil
+}
+
+func (r *InMemoryMetricsRepository) ListBatches(limit, offset int) ([]models.Batch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	batches := make([]models.Batch, 0, len(r.batches))
+	for _, b := range r.batches {
+		batches = append(batches, b)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].StartedAt.After(batches[j].StartedAt)
+	})
+
+	if offset >= len(batches) {
+		return []models.Batch{}, nil
+	}
+	end := offset + limit
+	if end > len(batches) {
+		end = len(batches)
+	}
+	return batches[offset:end], nil
+}
+
+func (r *InMemoryMetricsRepository) SaveRejections(batchID string, rejections []models.Rejection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.rejections[:0]
+	for _, rejection := range r.rejections {
+		if rejection.BatchID != batchID {
+			kept = append(kept, rejection)
+		}
+	}
+	r.rejections = kept
+
+	for _, rejection := range rejections {
+		r.nextRejectionID++
+		rejection.ID = r.nextRejectionID
+		rejection.BatchID = batchID
+		r.rejections = append(r.rejections, rejection)
+	}
+	return nil
+}
+
+func (r *InMemoryMetricsRepository) ListRejections(filter models.RejectionFilter, limit, offset int) ([]models.Rejection, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []models.Rejection{}
+	skipped := 0
+	for i := len(r.rejections) - 1; i >= 0 && len(result) < limit; i-- {
+		rejection := r.rejections[i]
+		if !matchesRejectionFilter(rejection, filter) {
+			continue
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		result = append(result, rejection)
+	}
+	return result, nil
+}
+
+// utmMappingKey identifica un valor crudo de un campo UTM
+type utmMappingKey struct {
+	field string
+	raw   string
+}
+
+func (r *InMemoryMetricsRepository) SaveUTMMappings(mappings []models.UTMMapping) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, mapping := range mappings {
+		key := utmMappingKey{field: mapping.Field, raw: mapping.Raw}
+		mapping.Records += r.utmMappings[key].Records
+		r.utmMappings[key] = mapping
+	}
+	return nil
+}
+
+func (r *InMemoryMetricsRepository) ListUTMMappings(field string) ([]models.UTMMapping, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []models.UTMMapping{}
+	for _, mapping := range r.utmMappings {
+		if field == "" || mapping.Field == field {
+			result = append(result, mapping)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Field != result[j].Field {
+			return result[i].Field < result[j].Field
+		}
+		if result[i].Canonical != result[j].Canonical {
+			return result[i].Canonical < result[j].Canonical
+		}
+		return result[i].Raw < result[j].Raw
+	})
+	return result, nil
+}
+
+func (r *InMemoryMetricsRepository) SaveReconciliation(batchID string, keys []models.KeyReconciliation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := make([]models.KeyReconciliation, len(keys))
+	for i, key := range keys {
+		key.BatchID = batchID
+		stored[i] = key
+	}
+	r.reconciliations[batchID] = stored
+	// Igual que en SQLite, un lote sin claves no pasa a ser el último clasificado
+	if len(stored) > 0 {
+		r.lastReconciled = batchID
+	}
+	return nil
+}
+
+func (r *InMemoryMetricsRepository) ListReconciliation(filter models.ReconciliationFilter, limit, offset int) ([]models.KeyReconciliation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	batchID := filter.BatchID
+	if batchID == "" {
+		batchID = r.lastReconciled
+	}
+
+	result := []models.KeyReconciliation{}
+	skipped := 0
+	for _, key := range r.reconciliations[batchID] {
+		if len(result) >= limit {
+			break
+		}
+		if !matchesReconciliationFilter(key, filter) {
+			continue
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		result = append(result, key)
+	}
+	return result, nil
+}
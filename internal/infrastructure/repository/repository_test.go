@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain"
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+// forEachRepository ejecuta fn como subtest con un repositorio vacío de cada implementación
+func forEachRepository(t *testing.T, fn func(t *testing.T, repo domain.MetricsRepository)) {
+	t.Run("sqlite", func(t *testing.T) {
+		repo, _ := newTestSQLiteRepository(t)
+		fn(t, repo)
+	})
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewInMemoryMetricsRepository())
+	})
+}
+
+// testOpportunity crea el snapshot de una oportunidad sin contacto creada el día day
+func testOpportunity(id, day string, step models.FunnelStep, amount float64, utm models.UTMKey) models.Opportunity {
+	return models.Opportunity{ID: id, Stage: string(step), Step: step, Amount: decimal.NewFromFloat(amount), CreatedAt: day, Day: day, UTM: utm,
+		IngestedAt: time.Now().UTC(), BatchID: "b1"}
+}
+
+// withContact asigna el hash de contacto a la oportunidad
+func withContact(opportunity models.Opportunity, contactHash string) models.Opportunity {
+	opportunity.ContactHash = contactHash
+	return opportunity
+}
+
+func TestMetricsRepository_Merge(t *testing.T) {
+	key := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	dailyKey := models.DailyKey{Date: "2025-01-15", UTMKey: key}
+
+	forEachRepository(t, func(t *testing.T, repo domain.MetricsRepository) {
+		if err := repo.Merge("w1", map[models.DailyKey]models.AggregatedMetrics{
+			dailyKey: {Impressions: 200, Clicks: 10, Cost: decimal.NewFromFloat(5)},
+		}); err != nil {
+			t.Fatalf("Merge() unexpected error: %v", err)
+		}
+		// El segundo lote suma contadores y completa el canal vacío; los contadores de CRM se ignoran.
+		// Reintentarlo (p.ej. tras fallar el registro en la bitácora) no lo suma dos veces.
+		for i := 0; i < 2; i++ {
+			if err := repo.Merge("w2", map[models.DailyKey]models.AggregatedMetrics{
+				dailyKey: {Channel: "google_ads", Impressions: 100, Clicks: 5, Cost: decimal.NewFromFloat(2.5), ClosedWon: 1, Revenue: decimal.NewFromFloat(300)},
+			}); err != nil {
+				t.Fatalf("Merge() unexpected error: %v", err)
+			}
+		}
+
+		got, _, _ := repo.GetByKey(key)
+		want := models.AggregatedMetrics{Channel: "google_ads", Impressions: 300, Clicks: 15, Cost: decimal.NewFromFloat(7.5)}
+		if !got.Equal(want) {
+			t.Errorf("GetByKey() after merges = %v, want %v", got, want)
+		}
+
+		// Los contadores extraídos prevalecen sobre los de webhooks, antes o después de ellos
+		pulled := models.AggregatedMetrics{Channel: "google_ads", Impressions: 320, Clicks: 16, Cost: decimal.NewFromFloat(8)}
+		if err := repo.Save(map[models.DailyKey]models.AggregatedMetrics{dailyKey: pulled}); err != nil {
+			t.Fatalf("Save() unexpected error: %v", err)
+		}
+		if err := repo.Merge("w3", map[models.DailyKey]models.AggregatedMetrics{
+			dailyKey: {Impressions: 20, Clicks: 1, Cost: decimal.NewFromFloat(0.5)},
+		}); err != nil {
+			t.Fatalf("Merge() unexpected error: %v", err)
+		}
+		if got, _, _ := repo.GetByKey(key); !got.Equal(pulled) {
+			t.Errorf("GetByKey() after pull and merge = %v, want %v", got, pulled)
+		}
+	})
+}
+
+func TestMetricsRepository_MergeUploads(t *testing.T) {
+	key := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	dailyKey := models.DailyKey{Date: "2025-01-15", UTMKey: key}
+	upload := func(cost float64) map[models.DailyKey]models.AggregatedMetrics {
+		return map[models.DailyKey]models.AggregatedMetrics{dailyKey: {Clicks: 10, Cost: decimal.NewFromFloat(cost)}}
+	}
+
+	forEachRepository(t, func(t *testing.T, repo domain.MetricsRepository) {
+		// Dos exportaciones de cuentas distintas con el mismo día y UTM se suman; volver a
+		// guardar la primera (reintento del mismo lote) no la cuenta dos veces
+		for _, step := range []struct {
+			batchID string
+			cost    float64
+		}{{"upload-account-a", 120.5}, {"upload-account-b", 80}, {"upload-account-a", 120.5}} {
+			if err := repo.Merge(step.batchID, upload(step.cost)); err != nil {
+				t.Fatalf("Merge(%s) unexpected error: %v", step.batchID, err)
+			}
+		}
+
+		want := models.AggregatedMetrics{Clicks: 20, Cost: decimal.NewFromFloat(200.5)}
+		if got, _, _ := repo.GetByKey(key); !got.Equal(want) {
+			t.Errorf("GetByKey() after uploads = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestMetricsRepository_LatestChannel(t *testing.T) {
+	key := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	fact := func(date, channel string) (models.DailyKey, models.AggregatedMetrics) {
+		return models.DailyKey{Date: date, UTMKey: key}, models.AggregatedMetrics{Channel: channel, Clicks: 1}
+	}
+
+	forEachRepository(t, func(t *testing.T, repo domain.MetricsRepository) {
+		// El canal cambió el 16; el día 17 no informa canal y el hecho sin fecha es el más antiguo
+		metrics := make(map[models.DailyKey]models.AggregatedMetrics)
+		for _, f := range [][2]string{{"", "zz_legacy"}, {"2025-01-15", "google_ads"}, {"2025-01-16", "display"}, {"2025-01-17", ""}} {
+			k, v := fact(f[0], f[1])
+			metrics[k] = v
+		}
+		if err := repo.Save(metrics); err != nil {
+			t.Fatalf("Save() unexpected error: %v", err)
+		}
+
+		if got, _, _ := repo.GetByKey(key); got.Channel != "display" {
+			t.Errorf("GetByKey() channel = %q, want display", got.Channel)
+		}
+		all, _ := repo.GetAll()
+		if got := all[key].Channel; got != "display" {
+			t.Errorf("GetAll() channel = %q, want display", got)
+		}
+		to := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+		ranged, _ := repo.GetByDateRange(nil, &to)
+		if got := ranged[key].Channel; got != "google_ads" {
+			t.Errorf("GetByDateRange(to 15) channel = %q, want google_ads", got)
+		}
+	})
+}
+
+func TestMetricsRepository_CRMAttribution(t *testing.T) {
+	sale := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	organic := models.UTMKey{Campaign: "organic", Source: "newsletter", Medium: "email"}
+	from := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	forEachRepository(t, func(t *testing.T, repo domain.MetricsRepository) {
+		if err := repo.Save(map[models.DailyKey]models.AggregatedMetrics{
+			{Date: "2025-01-10", UTMKey: sale}: {Clicks: 100, Cost: decimal.NewFromFloat(50)},
+			// Un día sin actividad no atribuye
+			{Date: "2025-01-19", UTMKey: sale}: {},
+		}); err != nil {
+			t.Fatalf("Save() unexpected error: %v", err)
+		}
+		if err := repo.SaveOpportunities([]models.Opportunity{
+			testOpportunity("O1", "2025-01-15", models.StepWon, 500, sale),   // 5 días después del gasto
+			testOpportunity("O2", "2025-01-20", models.StepWon, 300, sale),   // 10 días después: fuera de la ventana
+			testOpportunity("O3", "2025-01-16", models.StepLead, 0, organic), // UTM sin ads
+			testOpportunity("O4", "2025-01-12", models.StepLead, 0, sale),    // Fuera del rango consultado
+			testOpportunity("O5", "", models.StepOpportunity, 0, sale),       // Sin día
+		}); err != nil {
+			t.Fatalf("SaveOpportunities() unexpected error: %v", err)
+		}
+
+		result, err := repo.GetCRMAttribution(&from, &to, 7)
+		if err != nil {
+			t.Fatalf("GetCRMAttribution() unexpected error: %v", err)
+		}
+		won := models.AggregatedMetrics{Leads: 1, MQLs: 1, SQLs: 1, Opportunities: 1, ClosedWon: 1, Revenue: decimal.NewFromFloat(500)}
+		if !result[sale].Attributed.Equal(won) {
+			t.Errorf("sale attributed = %v, want %v", result[sale].Attributed, won)
+		}
+		won.Revenue = decimal.NewFromFloat(300)
+		if !result[sale].Unattributed.Equal(won) {
+			t.Errorf("sale unattributed = %v, want %v", result[sale].Unattributed, won)
+		}
+		if want := (models.AggregatedMetrics{Leads: 1}); !result[organic].Unattributed.Equal(want) || !result[organic].Attributed.Equal(models.AggregatedMetrics{}) {
+			t.Errorf("organic = %+v, want only unattributed %v", result[organic], want)
+		}
+
+		// Sin rango se incluyen O4 (atribuida) y O5 (sin día, nunca atribuible)
+		all, err := repo.GetCRMAttribution(nil, nil, 10)
+		if err != nil {
+			t.Fatalf("GetCRMAttribution() unexpected error: %v", err)
+		}
+		if all[sale].Attributed.Leads != 3 || all[sale].Unattributed.Leads != 1 {
+			t.Errorf("sale without range = %+v, want 3 attributed and 1 unattributed leads", all[sale])
+		}
+	})
+}
+
+func TestMetricsRepository_ListOpportunities(t *testing.T) {
+	sale := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	lead := func(id, contact, day string) models.Opportunity {
+		return withContact(testOpportunity(id, day, models.StepLead, 0, sale), contact)
+	}
+	ids := func(opportunities []models.Opportunity) []string {
+		var result []string
+		for _, o := range opportunities {
+			result = append(result, o.ID)
+		}
+		return result
+	}
+	from := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	forEachRepository(t, func(t *testing.T, repo domain.MetricsRepository) {
+		if err := repo.SaveOpportunities([]models.Opportunity{
+			lead("O3", "hash-ana", "2025-01-20"),
+			lead("O1", "hash-ana", "2025-01-01"),
+			lead("O2", "hash-luis", "2025-01-16"),
+			lead("O4", "", "2025-01-17"),
+		}); err != nil {
+			t.Fatalf("SaveOpportunities() unexpected error: %v", err)
+		}
+
+		inRange, err := repo.ListOpportunities(&from, nil)
+		if err != nil {
+			t.Fatalf("ListOpportunities() unexpected error: %v", err)
+		}
+		if got := ids(inRange); len(got) != 3 || got[0] != "O2" || got[1] != "O3" || got[2] != "O4" {
+			t.Errorf("ListOpportunities() = %v, want [O2 O3 O4]", got)
+		}
+
+		byContact, err := repo.ListOpportunitiesByContact([]string{"hash-ana", ""})
+		if err != nil {
+			t.Fatalf("ListOpportunitiesByContact() unexpected error: %v", err)
+		}
+		if got := ids(byContact); len(got) != 2 || got[0] != "O1" || got[1] != "O3" {
+			t.Errorf("ListOpportunitiesByContact() = %v, want [O1 O3]", got)
+		}
+	})
+}
+
+func TestMetricsRepository_ContactPrivacy(t *testing.T) {
+	sale := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	hashes := map[string]string{"ana@example.com": "hash-ana", "luis@example.com": "hash-luis"}
+	hash := func(email string) string { return hashes[email] }
+	opportunity := func(id, contact string, step models.FunnelStep, amount float64) models.Opportunity {
+		return withContact(testOpportunity(id, "2025-01-15", step, amount, sale), contact)
+	}
+
+	forEachRepository(t, func(t *testing.T, repo domain.MetricsRepository) {
+		if err := repo.Save(map[models.DailyKey]models.AggregatedMetrics{
+			{Date: "2025-01-15", UTMKey: sale}: {Clicks: 10, Cost: decimal.NewFromFloat(5)},
+		}); err != nil {
+			t.Fatalf("Save() unexpected error: %v", err)
+		}
+		// O1 se guardó antes de seudonimizar al extraer: su contacto está en claro
+		if err := repo.SaveOpportunities([]models.Opportunity{
+			opportunity("O1", "ana@example.com", models.StepLead, 0),
+			opportunity("O2", hash("ana@example.com"), models.StepWon, 300),
+			opportunity("O3", hash("luis@example.com"), models.StepLead, 0),
+			opportunity("O4", "", models.StepLead, 0),
+		}); err != nil {
+			t.Fatalf("SaveOpportunities() unexpected error: %v", err)
+		}
+		if err := repo.SaveRejections("b1", []models.Rejection{
+			{Source: "crm", Kind: "crm", Rule: "known_stage", Reason: "bad", Record: []byte(`{"amount":12345678901234567890.10,"contact_email":"ana@example.com","opportunity_id":"O5"}`), RejectedAt: time.Now().UTC()},
+			{Source: "crm", Kind: "crm", Rule: "known_stage", Reason: "bad", Record: []byte(`{"contact_email":"hash-luis","opportunity_id":"O6"}`), RejectedAt: time.Now().UTC()},
+		}); err != nil {
+			t.Fatalf("SaveRejections() unexpected error: %v", err)
+		}
+
+		changed, err := repo.PseudonymizeContacts(hash)
+		if err != nil {
+			t.Fatalf("PseudonymizeContacts() unexpected error: %v", err)
+		}
+		if changed != 2 {
+			t.Errorf("PseudonymizeContacts() = %d, want 2 (O1 and the first rejection)", changed)
+		}
+		if again, _ := repo.PseudonymizeContacts(hash); again != 0 {
+			t.Errorf("PseudonymizeContacts() again = %d, want 0", again)
+		}
+		// Solo cambia contact_email: el monto conserva sus dígitos
+		rejections, _ := repo.ListRejections(models.RejectionFilter{}, 10, 0)
+		want := `{"amount":12345678901234567890.10,"contact_email":"hash-ana","opportunity_id":"O5"}`
+		found := false
+		for _, rejection := range rejections {
+			found = found || string(rejection.Record) == want
+		}
+		if !found {
+			t.Errorf("pseudonymized rejections = %+v, want a record %s", rejections, want)
+		}
+
+		contacts, err := repo.CountUniqueContacts(nil, nil)
+		if err != nil {
+			t.Fatalf("CountUniqueContacts() unexpected error: %v", err)
+		}
+		if contacts[sale] != 2 {
+			t.Errorf("CountUniqueContacts() = %d, want 2", contacts[sale])
+		}
+
+		purge, err := repo.PurgeContact(hash("ana@example.com"))
+		if err != nil {
+			t.Fatalf("PurgeContact() unexpected error: %v", err)
+		}
+		if purge.Opportunities != 2 || purge.Rejections != 1 {
+			t.Errorf("PurgeContact() = %+v, want 2 opportunities and 1 rejection", purge)
+		}
+		if left, _ := repo.ListOpportunitiesByContact([]string{hash("ana@example.com")}); len(left) != 0 {
+			t.Errorf("opportunities left after purge: %+v", left)
+		}
+		all, _ := repo.GetAll()
+		if want := (models.AggregatedMetrics{Clicks: 10, Cost: decimal.NewFromFloat(5), Leads: 2}); !all[sale].Equal(want) {
+			t.Errorf("sale after purge = %v, want %v", all[sale], want)
+		}
+		if rejections, _ := repo.ListRejections(models.RejectionFilter{}, 10, 0); len(rejections) != 1 {
+			t.Errorf("rejections after purge = %d, want 1", len(rejections))
+		}
+	})
+}
@@ -0,0 +1,1330 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/m4ck-y/ETL_go/internal/pkg/logger"
+
+	_ "modernc.org/sqlite" // driver SQLite en Go puro (sin CGO)
+)
+
+// migration representa un cambio de esquema versionado
+type migration struct {
+	version    int
+	statements []string
+}
+
+// migrations contiene el esquema en orden; nunca modificar una migración ya publicada, agregar una nueva
+var migrations = []migration{
+	{
+		version: 1,
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS metrics (
+				campaign      TEXT    NOT NULL,
+				source        TEXT    NOT NULL,
+				medium        TEXT    NOT NULL,
+				channel       TEXT    NOT NULL DEFAULT '',
+				clicks        INTEGER NOT NULL DEFAULT 0,
+				cost          REAL    NOT NULL DEFAULT 0,
+				leads         INTEGER NOT NULL DEFAULT 0,
+				opportunities INTEGER NOT NULL DEFAULT 0,
+				closed_won    INTEGER NOT NULL DEFAULT 0,
+				revenue       REAL    NOT NULL DEFAULT 0,
+				PRIMARY KEY (campaign, source, medium)
+			)`,
+			`CREATE TABLE IF NOT EXISTS processed_batches (
+				batch_id     TEXT PRIMARY KEY,
+				processed_at TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		// Hechos diarios: las métricas previas sin fecha se conservan con date = ''
+		version: 2,
+		statements: []string{
+			`CREATE TABLE daily_metrics (
+				date          TEXT    NOT NULL,
+				campaign      TEXT    NOT NULL,
+				source        TEXT    NOT NULL,
+				medium        TEXT    NOT NULL,
+				channel       TEXT    NOT NULL DEFAULT '',
+				clicks        INTEGER NOT NULL DEFAULT 0,
+				cost          REAL    NOT NULL DEFAULT 0,
+				leads         INTEGER NOT NULL DEFAULT 0,
+				opportunities INTEGER NOT NULL DEFAULT 0,
+				closed_won    INTEGER NOT NULL DEFAULT 0,
+				revenue       REAL    NOT NULL DEFAULT 0,
+				PRIMARY KEY (date, campaign, source, medium)
+			)`,
+			`INSERT INTO daily_metrics
+				(date, campaign, source, medium, channel, clicks, cost, leads, opportunities, closed_won, revenue)
+				SELECT '', campaign, source, medium, channel, clicks, cost, leads, opportunities, closed_won, revenue
+				FROM metrics`,
+			`DROP TABLE metrics`,
+			`CREATE INDEX idx_daily_metrics_utm ON daily_metrics (campaign, source, medium)`,
+		},
+	},
+	{
+		// Bitácora de lotes: reemplaza processed_batches conservando los lotes ya procesados
+		version: 3,
+		statements: []string{
+			`CREATE TABLE batches (
+				id           TEXT    PRIMARY KEY,
+				since        TEXT    NOT NULL DEFAULT '',
+				status       TEXT    NOT NULL,
+				started_at   TEXT    NOT NULL,
+				finished_at  TEXT,
+				duration_ms  INTEGER NOT NULL DEFAULT 0,
+				ads_records  INTEGER NOT NULL DEFAULT 0,
+				crm_records  INTEGER NOT NULL DEFAULT 0,
+				combinations INTEGER NOT NULL DEFAULT 0,
+				error        TEXT    NOT NULL DEFAULT '',
+				attempts     INTEGER NOT NULL DEFAULT 1
+			)`,
+			`INSERT INTO batches (id, status, started_at, finished_at)
+				SELECT batch_id, 'completed', ts, ts FROM (
+					SELECT batch_id, strftime('%Y-%m-%dT%H:%M:%S', processed_at) || '.000000000Z' AS ts
+					FROM processed_batches
+				)`,
+			`DROP TABLE processed_batches`,
+			`CREATE INDEX idx_batches_started_at ON batches (started_at)`,
+		},
+	},
+	{
+		// Calidad de datos: resumen por lote (JSON) y cuarentena de registros rechazados
+		version: 4,
+		statements: []string{
+			`ALTER TABLE batches ADD COLUMN quality TEXT NOT NULL DEFAULT ''`,
+			`CREATE TABLE rejections (
+				id          INTEGER PRIMARY KEY AUTOINCREMENT,
+				batch_id    TEXT NOT NULL,
+				source      TEXT NOT NULL,
+				kind        TEXT NOT NULL,
+				rule        TEXT NOT NULL,
+				reason      TEXT NOT NULL,
+				record      TEXT NOT NULL,
+				rejected_at TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_rejections_batch_id ON rejections (batch_id)`,
+		},
+	},
+	{
+		// Último snapshot de cada oportunidad; define los contadores de CRM de daily_metrics
+		version: 5,
+		statements: []string{
+			`CREATE TABLE opportunities (
+				id            TEXT PRIMARY KEY,
+				stage         TEXT NOT NULL,
+				amount        REAL NOT NULL DEFAULT 0,
+				contact_email TEXT NOT NULL DEFAULT '',
+				created_at    TEXT NOT NULL,
+				day           TEXT NOT NULL,
+				campaign      TEXT NOT NULL,
+				source        TEXT NOT NULL,
+				medium        TEXT NOT NULL,
+				updated_at    TEXT,
+				ingested_at   TEXT NOT NULL,
+				batch_id      TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX idx_opportunities_daily_key ON opportunities (day, campaign, source, medium)`,
+		},
+	},
+	{
+		// Taxonomía de etapas: paso del embudo por oportunidad y contadores acumulativos por paso.
+		// Las oportunidades existentes se clasifican con la taxonomía por defecto y se recalculan sus hechos.
+		version: 6,
+		statements: []string{
+			`ALTER TABLE opportunities ADD COLUMN step TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE daily_metrics ADD COLUMN mqls INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE daily_metrics ADD COLUMN sqls INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE daily_metrics ADD COLUMN closed_lost INTEGER NOT NULL DEFAULT 0`,
+			`UPDATE opportunities SET step = CASE stage
+				WHEN 'lead' THEN 'lead'
+				WHEN 'mql' THEN 'mql'
+				WHEN 'sql' THEN 'sql'
+				WHEN 'opportunity' THEN 'opportunity'
+				WHEN 'closed_won' THEN 'won'
+				WHEN 'closed_lost' THEN 'lost'
+				ELSE '' END`,
+			`UPDATE daily_metrics SET
+				leads = (SELECT COUNT(*) FROM opportunities o WHERE o.day = daily_metrics.date AND o.campaign = daily_metrics.campaign AND o.source = daily_metrics.source AND o.medium = daily_metrics.medium AND o.step != ''),
+				mqls = (SELECT COUNT(*) FROM opportunities o WHERE o.day = daily_metrics.date AND o.campaign = daily_metrics.campaign AND o.source = daily_metrics.source AND o.medium = daily_metrics.medium AND o.step IN ('mql', 'sql', 'opportunity', 'won', 'lost')),
+				sqls = (SELECT COUNT(*) FROM opportunities o WHERE o.day = daily_metrics.date AND o.campaign = daily_metrics.campaign AND o.source = daily_metrics.source AND o.medium = daily_metrics.medium AND o.step IN ('sql', 'opportunity', 'won', 'lost')),
+				opportunities = (SELECT COUNT(*) FROM opportunities o WHERE o.day = daily_metrics.date AND o.campaign = daily_metrics.campaign AND o.source = daily_metrics.source AND o.medium = daily_metrics.medium AND o.step IN ('opportunity', 'won', 'lost')),
+				closed_lost = (SELECT COUNT(*) FROM opportunities o WHERE o.day = daily_metrics.date AND o.campaign = daily_metrics.campaign AND o.source = daily_metrics.source AND o.medium = daily_metrics.medium AND o.step = 'lost')
+				WHERE EXISTS (SELECT 1 FROM opportunities o WHERE o.day = daily_metrics.date AND o.campaign = daily_metrics.campaign AND o.source = daily_metrics.source AND o.medium = daily_metrics.medium)`,
+		},
+	},
+	{
+		// Valores UTM crudos observados y el valor canónico al que se normalizaron
+		version: 7,
+		statements: []string{
+			`CREATE TABLE utm_mappings (
+				field     TEXT    NOT NULL,
+				raw       TEXT    NOT NULL,
+				canonical TEXT    NOT NULL,
+				records   INTEGER NOT NULL DEFAULT 0,
+				last_seen TEXT    NOT NULL,
+				PRIMARY KEY (field, raw)
+			)`,
+		},
+	},
+	{
+		// Conciliación de claves UTM entre ads y CRM por lote
+		version: 8,
+		statements: []string{
+			`ALTER TABLE batches ADD COLUMN reconciliation TEXT NOT NULL DEFAULT ''`,
+			`CREATE TABLE key_reconciliation (
+				id            INTEGER PRIMARY KEY AUTOINCREMENT,
+				batch_id      TEXT    NOT NULL,
+				campaign      TEXT    NOT NULL,
+				source        TEXT    NOT NULL,
+				medium        TEXT    NOT NULL,
+				status        TEXT    NOT NULL,
+				clicks        INTEGER NOT NULL DEFAULT 0,
+				cost          REAL    NOT NULL DEFAULT 0,
+				opportunities INTEGER NOT NULL DEFAULT 0,
+				revenue       REAL    NOT NULL DEFAULT 0,
+				suggestions   TEXT    NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX idx_key_reconciliation_batch_id ON key_reconciliation (batch_id, status)`,
+		},
+	},
+	{
+		// Impresiones de ads para CTR y CPM; los hechos anteriores quedan en 0
+		version: 9,
+		statements: []string{
+			`ALTER TABLE daily_metrics ADD COLUMN impressions INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		// Moneda de reporte de cada lote; los lotes anteriores quedan vacíos
+		version: 10,
+		statements: []string{
+			`ALTER TABLE batches ADD COLUMN currency TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		// Montos exactos: micro-unidades enteras (models.MoneyScale) en lugar de REAL.
+		// Los montos guardados se redondean a la micro-unidad más cercana.
+		version: 11,
+		statements: []string{
+			`ALTER TABLE daily_metrics ADD COLUMN cost_micros INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE daily_metrics ADD COLUMN revenue_micros INTEGER NOT NULL DEFAULT 0`,
+			`UPDATE daily_metrics SET cost_micros = CAST(ROUND(cost * 1000000) AS INTEGER),
+				revenue_micros = CAST(ROUND(revenue * 1000000) AS INTEGER)`,
+			`ALTER TABLE daily_metrics DROP COLUMN cost`,
+			`ALTER TABLE daily_metrics DROP COLUMN revenue`,
+			`ALTER TABLE opportunities ADD COLUMN amount_micros INTEGER NOT NULL DEFAULT 0`,
+			`UPDATE opportunities SET amount_micros = CAST(ROUND(amount * 1000000) AS INTEGER)`,
+			`ALTER TABLE opportunities DROP COLUMN amount`,
+			`ALTER TABLE key_reconciliation ADD COLUMN cost_micros INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE key_reconciliation ADD COLUMN revenue_micros INTEGER NOT NULL DEFAULT 0`,
+			`UPDATE key_reconciliation SET cost_micros = CAST(ROUND(cost * 1000000) AS INTEGER),
+				revenue_micros = CAST(ROUND(revenue * 1000000) AS INTEGER)`,
+			`ALTER TABLE key_reconciliation DROP COLUMN cost`,
+			`ALTER TABLE key_reconciliation DROP COLUMN revenue`,
+		},
+	},
+	{
+		// Zona de reporte de cada lote; los lotes anteriores quedan vacíos
+		version: 12,
+		statements: []string{
+			`ALTER TABLE batches ADD COLUMN timezone TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		// Historial de puntos de contacto por contacto para la atribución multi-touch
+		version: 13,
+		statements: []string{
+			`CREATE INDEX IF NOT EXISTS idx_opportunities_contact ON opportunities (contact_email)`,
+		},
+	},
+	{
+		// Los contactos se guardan como HMAC del email; los emails ya guardados los reemplaza
+		// PseudonymizeContacts al iniciar, porque la clave no está disponible en SQL
+		version: 14,
+		statements: []string{
+			`ALTER TABLE opportunities RENAME COLUMN contact_email TO contact_hash`,
+		},
+	},
+	{
+		// Contadores de ads de cada lote de webhooks, separados de los extraídos; los hechos ya
+		// guardados con actividad de ads se consideran extraídos y conservan sus contadores
+		version: 15,
+		statements: []string{
+			`CREATE TABLE ads_deltas (
+				batch_id    TEXT NOT NULL,
+				date        TEXT NOT NULL,
+				campaign    TEXT NOT NULL,
+				source      TEXT NOT NULL,
+				medium      TEXT NOT NULL,
+				channel     TEXT NOT NULL DEFAULT '',
+				impressions INTEGER NOT NULL DEFAULT 0,
+				clicks      INTEGER NOT NULL DEFAULT 0,
+				cost_micros INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (batch_id, date, campaign, source, medium)
+			)`,
+			`CREATE INDEX idx_ads_deltas_key ON ads_deltas (date, campaign, source, medium)`,
+			`ALTER TABLE daily_metrics ADD COLUMN ads_pulled INTEGER NOT NULL DEFAULT 0`,
+			`UPDATE daily_metrics SET ads_pulled = 1 WHERE impressions > 0 OR clicks > 0 OR cost_micros > 0`,
+		},
+	},
+}
+
+type SQLiteMetricsRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteMetricsRepository abre (o crea) la base de datos en path y aplica las migraciones pendientes
+func NewSQLiteMetricsRepository(path string) (*SQLiteMetricsRepository, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite database: %w", err)
+	}
+
+	// SQLite admite un solo escritor; una conexión evita errores SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	pragmas := []string{
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA busy_timeout = 5000",
+		"PRAGMA foreign_keys = ON",
+	}
+	for _, pragma := range pragmas {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("error applying %q: %w", pragma, err)
+		}
+	}
+
+	repo := &SQLiteMetricsRepository{db: db}
+	if err := repo.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// migrate aplica en una transacción cada migración cuya versión aún no está registrada
+func (r *SQLiteMetricsRepository) migrate() error {
+	if _, err := r.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+
+	var current int
+	if err := r.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := r.db.Begin()
+		if err != nil {
+			return fmt.Errorf("error starting migration %d: %w", m.version, err)
+		}
+		for _, stmt := range m.statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("error applying migration %d: %w", m.version, err)
+			}
+		}
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)",
+			m.version, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error recording migration %d: %w", m.version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error committing migration %d: %w", m.version, err)
+		}
+
+		logger.GlobalLogger.Info("Migración de esquema aplicada", "system", map[string]interface{}{
+			"version": m.version,
+		})
+	}
+
+	return nil
+}
+
+// Close libera la conexión a la base de datos
+func (r *SQLiteMetricsRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *SQLiteMetricsRepository) Save(metrics map[models.DailyKey]models.AggregatedMetrics) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Los contadores de CRM no se tocan: los recalcula SaveOpportunities
+	stmt, err := tx.Prepare(`INSERT INTO daily_metrics (date, campaign, source, medium, channel, impressions, clicks, cost_micros, ads_pulled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT (date, campaign, source, medium) DO UPDATE SET
+			channel = excluded.channel,
+			impressions = excluded.impressions,
+			clicks = excluded.clicks,
+			cost_micros = excluded.cost_micros,
+			ads_pulled = 1`)
+	if err != nil {
+		return fmt.Errorf("error preparing metrics upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for k, v := range metrics {
+		if _, err := stmt.Exec(k.Date, k.Campaign, k.Source, k.Medium, v.Channel, v.Impressions, v.Clicks, toMicros(v.Cost)); err != nil {
+			return fmt.Errorf("error saving metrics: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteMetricsRepository) Merge(batchID string, metrics map[models.DailyKey]models.AggregatedMetrics) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Un reintento del mismo lote reemplaza sus filas en lugar de sumarlas otra vez
+	stmt, err := tx.Prepare(`INSERT INTO ads_deltas (batch_id, date, campaign, source, medium, channel, impressions, clicks, cost_micros)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (batch_id, date, campaign, source, medium) DO UPDATE SET
+			channel = excluded.channel,
+			impressions = excluded.impressions,
+			clicks = excluded.clicks,
+			cost_micros = excluded.cost_micros`)
+	if err != nil {
+		return fmt.Errorf("error preparing ads delta upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for k, v := range metrics {
+		if _, err := stmt.Exec(batchID, k.Date, k.Campaign, k.Source, k.Medium, v.Channel, v.Impressions, v.Clicks, toMicros(v.Cost)); err != nil {
+			return fmt.Errorf("error merging metrics: %w", err)
+		}
+	}
+	for k := range metrics {
+		if err := recomputeAdsCounters(tx, k); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// recomputeAdsCounters reemplaza los contadores de ads del hecho diario por la suma de sus lotes de
+// webhooks, salvo que los haya extraído Save: los extraídos prevalecen
+func recomputeAdsCounters(tx *sql.Tx, key models.DailyKey) error {
+	rows, err := tx.Query(`SELECT channel, impressions, clicks, cost_micros FROM ads_deltas
+		WHERE date = ? AND campaign = ? AND source = ? AND medium = ? ORDER BY batch_id`,
+		key.Date, key.Campaign, key.Source, key.Medium)
+	if err != nil {
+		return fmt.Errorf("error querying ads deltas: %w", err)
+	}
+	var ads models.AggregatedMetrics
+	for rows.Next() {
+		var delta models.AggregatedMetrics
+		var costMicros int64
+		if err := rows.Scan(&delta.Channel, &delta.Impressions, &delta.Clicks, &costMicros); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning ads delta: %w", err)
+		}
+		delta.Cost = fromMicros(costMicros)
+		ads = ads.Add(delta)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading ads deltas: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO daily_metrics (date, campaign, source, medium, channel, impressions, clicks, cost_micros)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (date, campaign, source, medium) DO UPDATE SET
+			channel = excluded.channel,
+			impressions = excluded.impressions,
+			clicks = excluded.clicks,
+			cost_micros = excluded.cost_micros
+		WHERE daily_metrics.ads_pulled = 0`,
+		key.Date, key.Campaign, key.Source, key.Medium, ads.Channel, ads.Impressions, ads.Clicks, toMicros(ads.Cost))
+	if err != nil {
+		return fmt.Errorf("error updating ads counters: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteMetricsRepository) SaveOpportunities(opportunities []models.Opportunity) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	affected := make(map[models.DailyKey]bool)
+	for _, opportunity := range opportunities {
+		current, exists, err := getOpportunity(tx, opportunity.ID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			if !current.SupersededBy(opportunity) {
+				continue
+			}
+			affected[current.Key()] = true
+		}
+		if err := upsertOpportunity(tx, opportunity); err != nil {
+			return err
+		}
+		affected[opportunity.Key()] = true
+	}
+
+	for key := range affected {
+		if err := recomputeCRMCounters(tx, key); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// maxContactsPerQuery acota los parámetros de cada consulta por contacto
+const maxContactsPerQuery = 500
+
+const opportunityColumns = `id, stage, step, amount_micros, contact_hash, created_at, day, campaign, source, medium,
+	updated_at, ingested_at, batch_id`
+
+func scanOpportunity(row rowScanner) (models.Opportunity, error) {
+	var o models.Opportunity
+	var updatedAt sql.NullString
+	var ingestedAt string
+	var amountMicros int64
+	if err := row.Scan(&o.ID, &o.Stage, &o.Step, &amountMicros, &o.ContactHash, &o.CreatedAt, &o.Day,
+		&o.UTM.Campaign, &o.UTM.Source, &o.UTM.Medium, &updatedAt, &ingestedAt, &o.BatchID); err != nil {
+		return models.Opportunity{}, err
+	}
+	o.Amount = fromMicros(amountMicros)
+	if updatedAt.Valid {
+		t, err := parseTimestamp(updatedAt.String)
+		if err != nil {
+			return models.Opportunity{}, err
+		}
+		o.UpdatedAt = &t
+	}
+	var err error
+	if o.IngestedAt, err = parseTimestamp(ingestedAt); err != nil {
+		return models.Opportunity{}, err
+	}
+	return o, nil
+}
+
+func getOpportunity(tx *sql.Tx, id string) (models.Opportunity, bool, error) {
+	o, err := scanOpportunity(tx.QueryRow("SELECT "+opportunityColumns+" FROM opportunities WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Opportunity{}, false, nil
+	}
+	if err != nil {
+		return models.Opportunity{}, false, fmt.Errorf("error querying opportunity: %w", err)
+	}
+	return o, true, nil
+}
+
+func upsertOpportunity(tx *sql.Tx, o models.Opportunity) error {
+	var updatedAt interface{}
+	if o.UpdatedAt != nil {
+		updatedAt = formatTimestamp(*o.UpdatedAt)
+	}
+	_, err := tx.Exec(`INSERT INTO opportunities (`+opportunityColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			stage = excluded.stage,
+			step = excluded.step,
+			amount_micros = excluded.amount_micros,
+			contact_hash = excluded.contact_hash,
+			created_at = excluded.created_at,
+			day = excluded.day,
+			campaign = excluded.campaign,
+			source = excluded.source,
+			medium = excluded.medium,
+			updated_at = excluded.updated_at,
+			ingested_at = excluded.ingested_at,
+			batch_id = excluded.batch_id`,
+		o.ID, o.Stage, o.Step, toMicros(o.Amount), o.ContactHash, o.CreatedAt, o.Day, o.UTM.Campaign, o.UTM.Source, o.UTM.Medium,
+		updatedAt, formatTimestamp(o.IngestedAt), o.BatchID)
+	if err != nil {
+		return fmt.Errorf("error saving opportunity: %w", err)
+	}
+	return nil
+}
+
+// recomputeCRMCounters reemplaza los contadores de CRM del hecho diario por la suma de sus oportunidades
+func recomputeCRMCounters(tx *sql.Tx, key models.DailyKey) error {
+	rows, err := tx.Query("SELECT "+opportunityColumns+` FROM opportunities
+		WHERE day = ? AND campaign = ? AND source = ? AND medium = ?`,
+		key.Date, key.Campaign, key.Source, key.Medium)
+	if err != nil {
+		return fmt.Errorf("error querying opportunities: %w", err)
+	}
+	var crm models.AggregatedMetrics
+	for rows.Next() {
+		o, err := scanOpportunity(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning opportunity: %w", err)
+		}
+		crm = crm.Add(o.Contribution())
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading opportunities: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO daily_metrics
+		(date, campaign, source, medium, leads, mqls, sqls, opportunities, closed_won, closed_lost, revenue_micros)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (date, campaign, source, medium) DO UPDATE SET
+			leads = excluded.leads,
+			mqls = excluded.mqls,
+			sqls = excluded.sqls,
+			opportunities = excluded.opportunities,
+			closed_won = excluded.closed_won,
+			closed_lost = excluded.closed_lost,
+			revenue_micros = excluded.revenue_micros`,
+		key.Date, key.Campaign, key.Source, key.Medium,
+		crm.Leads, crm.MQLs, crm.SQLs, crm.Opportunities, crm.ClosedWon, crm.ClosedLost, toMicros(crm.Revenue))
+	if err != nil {
+		return fmt.Errorf("error updating crm counters: %w", err)
+	}
+	return nil
+}
+
+// latestChannelColumn agrega el canal del día más reciente que lo informa. Cada fecha se rellena a
+// 10 caracteres para que los hechos sin fecha queden como los más antiguos y el canal empiece
+// siempre en la posición 11.
+const latestChannelColumn = `COALESCE(SUBSTR(MAX(CASE WHEN channel != '' THEN printf('%-10s', date) || channel END), 11), '')`
+
+func (r *SQLiteMetricsRepository) GetAll() (map[models.UTMKey]models.AggregatedMetrics, error) {
+	return r.GetByDateRange(nil, nil)
+}
+
+func (r *SQLiteMetricsRepository) GetByKey(key models.UTMKey) (models.AggregatedMetrics, bool, error) {
+	var m models.AggregatedMetrics
+	var days int
+	var costMicros, revenueMicros int64
+	err := r.db.QueryRow(`SELECT COUNT(*), `+latestChannelColumn+`, COALESCE(SUM(impressions), 0),
+		COALESCE(SUM(clicks), 0), COALESCE(SUM(cost_micros), 0),
+		COALESCE(SUM(leads), 0), COALESCE(SUM(mqls), 0), COALESCE(SUM(sqls), 0), COALESCE(SUM(opportunities), 0),
+		COALESCE(SUM(closed_won), 0), COALESCE(SUM(closed_lost), 0), COALESCE(SUM(revenue_micros), 0)
+		FROM daily_metrics WHERE campaign = ? AND source = ? AND medium = ?`,
+		key.Campaign, key.Source, key.Medium).
+		Scan(&days, &m.Channel, &m.Impressions, &m.Clicks, &costMicros, &m.Leads, &m.MQLs, &m.SQLs, &m.Opportunities, &m.ClosedWon, &m.ClosedLost, &revenueMicros)
+	if err != nil {
+		return models.AggregatedMetrics{}, false, fmt.Errorf("error querying metrics by key: %w", err)
+	}
+	if days == 0 {
+		return models.AggregatedMetrics{}, false, nil
+	}
+	m.Cost, m.Revenue = fromMicros(costMicros), fromMicros(revenueMicros)
+	return m, true, nil
+}
+
+func (r *SQLiteMetricsRepository) GetByDateRange(from, to *time.Time) (map[models.UTMKey]models.AggregatedMetrics, error) {
+	query := `SELECT campaign, source, medium, ` + latestChannelColumn + `, SUM(impressions), SUM(clicks), SUM(cost_micros), SUM(leads), SUM(mqls),
+		SUM(sqls), SUM(opportunities), SUM(closed_won), SUM(closed_lost), SUM(revenue_micros)
+		FROM daily_metrics`
+
+	// Mismo criterio que isDayInRange: con cualquier filtro se excluyen los hechos sin fecha
+	var conditions []string
+	var args []interface{}
+	if from != nil || to != nil {
+		conditions = append(conditions, "date != ''")
+	}
+	if from != nil {
+		conditions = append(conditions, "date >= ?")
+		args = append(args, from.Format(dateLayout))
+	}
+	if to != nil {
+		conditions = append(conditions, "date <= ?")
+		args = append(args, to.Format(dateLayout))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " GROUP BY campaign, source, medium"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[models.UTMKey]models.AggregatedMetrics)
+	for rows.Next() {
+		var k models.UTMKey
+		var m models.AggregatedMetrics
+		var costMicros, revenueMicros int64
+		if err := rows.Scan(&k.Campaign, &k.Source, &k.Medium, &m.Channel, &m.Impressions, &m.Clicks, &costMicros,
+			&m.Leads, &m.MQLs, &m.SQLs, &m.Opportunities, &m.ClosedWon, &m.ClosedLost, &revenueMicros); err != nil {
+			return nil, fmt.Errorf("error scanning metrics: %w", err)
+		}
+		m.Cost, m.Revenue = fromMicros(costMicros), fromMicros(revenueMicros)
+		result[k] = m
+	}
+
+	return result, rows.Err()
+}
+
+func (r *SQLiteMetricsRepository) GetCRMAttribution(from, to *time.Time, windowDays int) (map[models.UTMKey]models.CRMAttribution, error) {
+	// Una oportunidad es atribuible si su UTM tiene actividad de ads en [day - windowDays, day]
+	query := `SELECT o.campaign, o.source, o.medium, o.step, o.amount_micros,
+		o.day != '' AND EXISTS (SELECT 1 FROM daily_metrics d
+			WHERE d.campaign = o.campaign AND d.source = o.source AND d.medium = o.medium
+				AND d.date != '' AND d.date BETWEEN date(o.day, ?) AND o.day
+				AND (d.impressions > 0 OR d.clicks > 0 OR d.cost_micros > 0))
+		FROM opportunities o`
+	args := []interface{}{fmt.Sprintf("-%d days", windowDays)}
+
+	// Mismo criterio que GetByDateRange para el día de la oportunidad
+	var conditions []string
+	if from != nil || to != nil {
+		conditions = append(conditions, "o.day != ''")
+	}
+	if from != nil {
+		conditions = append(conditions, "o.day >= ?")
+		args = append(args, from.Format(dateLayout))
+	}
+	if to != nil {
+		conditions = append(conditions, "o.day <= ?")
+		args = append(args, to.Format(dateLayout))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attribution: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[models.UTMKey]models.CRMAttribution)
+	for rows.Next() {
+		var o models.Opportunity
+		var amountMicros int64
+		var attributed bool
+		if err := rows.Scan(&o.UTM.Campaign, &o.UTM.Source, &o.UTM.Medium, &o.Step, &amountMicros, &attributed); err != nil {
+			return nil, fmt.Errorf("error scanning attribution: %w", err)
+		}
+		o.Amount = fromMicros(amountMicros)
+		attribution := result[o.UTM]
+		if attributed {
+			attribution.Attributed = attribution.Attributed.Add(o.Contribution())
+		} else {
+			attribution.Unattributed = attribution.Unattributed.Add(o.Contribution())
+		}
+		result[o.UTM] = attribution
+	}
+	return result, rows.Err()
+}
+
+func (r *SQLiteMetricsRepository) ListOpportunities(from, to *time.Time) ([]models.Opportunity, error) {
+	query := "SELECT " + opportunityColumns + " FROM opportunities"
+
+	// Mismo criterio que isDayInRange
+	var conditions []string
+	var args []interface{}
+	if from != nil || to != nil {
+		conditions = append(conditions, "day != ''")
+	}
+	if from != nil {
+		conditions = append(conditions, "day >= ?")
+		args = append(args, from.Format(dateLayout))
+	}
+	if to != nil {
+		conditions = append(conditions, "day <= ?")
+		args = append(args, to.Format(dateLayout))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id"
+
+	return r.queryOpportunities(query, args...)
+}
+
+func (r *SQLiteMetricsRepository) ListOpportunitiesByContact(contacts []string) ([]models.Opportunity, error) {
+	var wanted []interface{}
+	for _, contact := range contacts {
+		if contact != "" {
+			wanted = append(wanted, contact)
+		}
+	}
+
+	// En tandas para no superar el límite de parámetros de SQLite
+	var result []models.Opportunity
+	for start := 0; start < len(wanted); start += maxContactsPerQuery {
+		end := start + maxContactsPerQuery
+		if end > len(wanted) {
+			end = len(wanted)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", end-start), ", ")
+		batch, err := r.queryOpportunities("SELECT "+opportunityColumns+" FROM opportunities WHERE contact_hash IN ("+placeholders+")", wanted[start:end]...)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, batch...)
+	}
+	sortOpportunitiesByID(result)
+	return result, nil
+}
+
+func (r *SQLiteMetricsRepository) queryOpportunities(query string, args ...interface{}) ([]models.Opportunity, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying opportunities: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.Opportunity
+	for rows.Next() {
+		o, err := scanOpportunity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning opportunity: %w", err)
+		}
+		result = append(result, o)
+	}
+	return result, rows.Err()
+}
+
+func (r *SQLiteMetricsRepository) CountUniqueContacts(from, to *time.Time) (map[models.UTMKey]int, error) {
+	query := `SELECT campaign, source, medium, COUNT(DISTINCT contact_hash) FROM opportunities`
+
+	// Mismo criterio que isDayInRange
+	conditions := []string{"contact_hash != ''"}
+	var args []interface{}
+	if from != nil || to != nil {
+		conditions = append(conditions, "day != ''")
+	}
+	if from != nil {
+		conditions = append(conditions, "day >= ?")
+		args = append(args, from.Format(dateLayout))
+	}
+	if to != nil {
+		conditions = append(conditions, "day <= ?")
+		args = append(args, to.Format(dateLayout))
+	}
+	query += " WHERE " + strings.Join(conditions, " AND ") + " GROUP BY campaign, source, medium"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying unique contacts: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[models.UTMKey]int)
+	for rows.Next() {
+		var k models.UTMKey
+		var contacts int
+		if err := rows.Scan(&k.Campaign, &k.Source, &k.Medium, &contacts); err != nil {
+			return nil, fmt.Errorf("error scanning unique contacts: %w", err)
+		}
+		result[k] = contacts
+	}
+	return result, rows.Err()
+}
+
+func (r *SQLiteMetricsRepository) PseudonymizeContacts(hash func(email string) string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Se leen antes de escribir para no modificar la tabla con el cursor abierto
+	type pending struct {
+		id    interface{}
+		value string
+	}
+	collect := func(query string) ([]pending, error) {
+		rows, err := tx.Query(query)
+		if err != nil {
+			return nil, fmt.Errorf("error querying contacts: %w", err)
+		}
+		defer rows.Close()
+		var result []pending
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.id, &p.value); err != nil {
+				return nil, fmt.Errorf("error scanning contacts: %w", err)
+			}
+			result = append(result, p)
+		}
+		return result, rows.Err()
+	}
+
+	opportunities, err := collect(`SELECT id, contact_hash FROM opportunities WHERE contact_hash LIKE '%@%'`)
+	if err != nil {
+		return 0, err
+	}
+	for _, o := range opportunities {
+		if _, err := tx.Exec("UPDATE opportunities SET contact_hash = ? WHERE id = ?", hash(o.value), o.id); err != nil {
+			return 0, fmt.Errorf("error pseudonymizing opportunity: %w", err)
+		}
+	}
+	changed := len(opportunities)
+
+	rejections, err := collect(`SELECT id, record FROM rejections WHERE record LIKE '%@%'`)
+	if err != nil {
+		return 0, err
+	}
+	for _, rejection := range rejections {
+		record, ok := pseudonymizeRecord(json.RawMessage(rejection.value), hash)
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE rejections SET record = ? WHERE id = ?", string(record), rejection.id); err != nil {
+			return 0, fmt.Errorf("error pseudonymizing rejection: %w", err)
+		}
+		changed++
+	}
+
+	return changed, tx.Commit()
+}
+
+func (r *SQLiteMetricsRepository) PurgeContact(contactHash string) (models.ContactPurge, error) {
+	purge := models.ContactPurge{ContactHash: contactHash}
+	if contactHash == "" {
+		return purge, nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return purge, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT day, campaign, source, medium FROM opportunities WHERE contact_hash = ?`, contactHash)
+	if err != nil {
+		return purge, fmt.Errorf("error querying contact opportunities: %w", err)
+	}
+	affected := make(map[models.DailyKey]bool)
+	for rows.Next() {
+		var key models.DailyKey
+		if err := rows.Scan(&key.Date, &key.Campaign, &key.Source, &key.Medium); err != nil {
+			rows.Close()
+			return purge, fmt.Errorf("error scanning contact opportunities: %w", err)
+		}
+		affected[key] = true
+		purge.Opportunities++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return purge, fmt.Errorf("error reading contact opportunities: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM opportunities WHERE contact_hash = ?`, contactHash); err != nil {
+		return purge, fmt.Errorf("error deleting contact opportunities: %w", err)
+	}
+	for key := range affected {
+		if err := recomputeCRMCounters(tx, key); err != nil {
+			return purge, err
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM rejections WHERE instr(record, ?) > 0`, contactHash)
+	if err != nil {
+		return purge, fmt.Errorf("error deleting contact rejections: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return purge, fmt.Errorf("error deleting contact rejections: %w", err)
+	}
+	purge.Rejections = int(deleted)
+
+	return purge, tx.Commit()
+}
+
+func (r *SQLiteMetricsRepository) Clear() error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"daily_metrics", "ads_deltas", "opportunities", "utm_mappings", "batches", "rejections", "key_reconciliation"} {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return fmt.Errorf("error clearing %s: %w", table, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteMetricsRepository) IsBatchProcessed(batchID string) (bool, error) {
+	var status string
+	err := r.db.QueryRow("SELECT status FROM batches WHERE id = ?", batchID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking batch status: %w", err)
+	}
+	return status == models.BatchStatusCompleted, nil
+}
+
+func (r *SQLiteMetricsRepository) MarkBatchProcessed(batchID string) error {
+	_, err := r.db.Exec(`INSERT INTO batches (id, status, started_at) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET status = excluded.status`,
+		batchID, models.BatchStatusCompleted, formatTimestamp(time.Now()))
+	if err != nil {
+		return fmt.Errorf("error marking batch as processed: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteMetricsRepository) SaveBatch(batch models.Batch) error {
+	var finishedAt interface{}
+	if batch.FinishedAt != nil {
+		finishedAt = formatTimestamp(*batch.FinishedAt)
+	}
+
+	quality := ""
+	if batch.Quality != nil {
+		encoded, err := json.Marshal(batch.Quality)
+		if err != nil {
+			return fmt.Errorf("error encoding batch quality: %w", err)
+		}
+		quality = string(encoded)
+	}
+	reconciliation := ""
+	if batch.Reconciliation != nil {
+		encoded, err := json.Marshal(batch.Reconciliation)
+		if err != nil {
+			return fmt.Errorf("error encoding batch reconciliation: %w", err)
+		}
+		reconciliation = string(encoded)
+	}
+
+	_, err := r.db.Exec(`INSERT INTO batches
+		(id, since, status, started_at, finished_at, duration_ms, ads_records, crm_records, combinations, error, attempts,
+			quality, reconciliation, currency, timezone)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			since = excluded.since,
+			status = excluded.status,
+			started_at = excluded.started_at,
+			finished_at = excluded.finished_at,
+			duration_ms = excluded.duration_ms,
+			ads_records = excluded.ads_records,
+			crm_records = excluded.crm_records,
+			combinations = excluded.combinations,
+			error = excluded.error,
+			attempts = excluded.attempts,
+			quality = excluded.quality,
+			reconciliation = excluded.reconciliation,
+			currency = excluded.currency,
+			timezone = excluded.timezone`,
+		batch.ID, batch.Since, batch.Status, formatTimestamp(batch.StartedAt), finishedAt, batch.DurationMS,
+		batch.AdsRecords, batch.CRMRecords, batch.Combinations, batch.Error, batch.Attempts, quality, reconciliation, batch.Currency,
+		batch.Timezone)
+	if err != nil {
+		return fmt.Errorf("error saving batch: %w", err)
+	}
+	return nil
+}
+
+const batchColumns = `id, since, status, started_at, finished_at, duration_ms, ads_records, crm_records, combinations, error, attempts,
+	quality, reconciliation, currency, timezone`
+
+// rowScanner abstrae *sql.Row y *sql.Rows para reutilizar el escaneo
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBatch(row rowScanner) (models.Batch, error) {
+	var b models.Batch
+	var startedAt string
+	var finishedAt sql.NullString
+	var quality, reconciliation string
+	if err := row.Scan(&b.ID, &b.Since, &b.Status, &startedAt, &finishedAt, &b.DurationMS,
+		&b.AdsRecords, &b.CRMRecords, &b.Combinations, &b.Error, &b.Attempts, &quality, &reconciliation, &b.Currency,
+		&b.Timezone); err != nil {
+		return models.Batch{}, err
+	}
+
+	var err error
+	if b.StartedAt, err = parseTimestamp(startedAt); err != nil {
+		return models.Batch{}, fmt.Errorf("invalid started_at for batch %s: %w", b.ID, err)
+	}
+	if finishedAt.Valid {
+		t, err := parseTimestamp(finishedAt.String)
+		if err != nil {
+			return models.Batch{}, fmt.Errorf("invalid finished_at for batch %s: %w", b.ID, err)
+		}
+		b.FinishedAt = &t
+	}
+	if quality != "" {
+		b.Quality = &models.QualitySummary{}
+		if err := json.Unmarshal([]byte(quality), b.Quality); err != nil {
+			return models.Batch{}, fmt.Errorf("invalid quality for batch %s: %w", b.ID, err)
+		}
+	}
+	if reconciliation != "" {
+		b.Reconciliation = &models.ReconciliationSummary{}
+		if err := json.Unmarshal([]byte(reconciliation), b.Reconciliation); err != nil {
+			return models.Batch{}, fmt.Errorf("invalid reconciliation for batch %s: %w", b.ID, err)
+		}
+	}
+	return b, nil
+}
+
+func (r *SQLiteMetricsRepository) GetBatch(batchID string) (models.Batch, bool, error) {
+	batch, err := scanBatch(r.db.QueryRow("SELECT "+batchColumns+" FROM batches WHERE id = ?", batchID))
+	if err == sql.ErrNoRows {
+		return models.Batch{}, false, nil
+	}
+	if err != nil {
+		return models.Batch{}, false, fmt.Errorf("error querying batch: %w", err)
+	}
+	return batch, true, nil
+}
+
+func (r *SQLiteMetricsRepository) ListBatches(limit, offset int) ([]models.Batch, error) {
+	rows, err := r.db.Query("SELECT "+batchColumns+" FROM batches ORDER BY started_at DESC LIMIT ? OFFSET ?",
+		limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying batches: %w", err)
+	}
+	defer rows.Close()
+
+	batches := []models.Batch{}
+	for rows.Next() {
+		batch, err := scanBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning batch: %w", err)
+		}
+		batches = append(batches, batch)
+	}
+	return batches, rows.Err()
+}
+
+func (r *SQLiteMetricsRepository) SaveRejections(batchID string, rejections []models.Rejection) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM rejections WHERE batch_id = ?", batchID); err != nil {
+		return fmt.Errorf("error clearing batch rejections: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO rejections (batch_id, source, kind, rule, reason, record, rejected_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("error preparing rejections insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, rejection := range rejections {
+		if _, err := stmt.Exec(batchID, rejection.Source, rejection.Kind, rejection.Rule, rejection.Reason,
+			string(rejection.Record), formatTimestamp(rejection.RejectedAt)); err != nil {
+			return fmt.Errorf("error saving rejection: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteMetricsRepository) ListRejections(filter models.RejectionFilter, limit, offset int) ([]models.Rejection, error) {
+	query := "SELECT id, batch_id, source, kind, rule, reason, record, rejected_at FROM rejections"
+	var conditions []string
+	var args []interface{}
+	for column, value := range map[string]string{"batch_id": filter.BatchID, "rule": filter.Rule, "source": filter.Source} {
+		if value != "" {
+			conditions = append(conditions, column+" = ?")
+			args = append(args, value)
+		}
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying rejections: %w", err)
+	}
+	defer rows.Close()
+
+	rejections := []models.Rejection{}
+	for rows.Next() {
+		var rejection models.Rejection
+		var record, rejectedAt string
+		if err := rows.Scan(&rejection.ID, &rejection.BatchID, &rejection.Source, &rejection.Kind, &rejection.Rule,
+			&rejection.Reason, &record, &rejectedAt); err != nil {
+			return nil, fmt.Errorf("error scanning rejection: %w", err)
+		}
+		rejection.Record = json.RawMessage(record)
+		if rejection.RejectedAt, err = parseTimestamp(rejectedAt); err != nil {
+			return nil, fmt.Errorf("invalid rejected_at for rejection %d: %w", rejection.ID, err)
+		}
+		rejections = append(rejections, rejection)
+	}
+	return rejections, rows.Err()
+}
+
+func (r *SQLiteMetricsRepository) SaveUTMMappings(mappings []models.UTMMapping) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO utm_mappings (field, raw, canonical, records, last_seen)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (field, raw) DO UPDATE SET
+			canonical = excluded.canonical,
+			records = utm_mappings.records + excluded.records,
+			last_seen = excluded.last_seen`)
+	if err != nil {
+		return fmt.Errorf("error preparing utm mappings upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, m := range mappings {
+		if _, err := stmt.Exec(m.Field, m.Raw, m.Canonical, m.Records, formatTimestamp(m.LastSeen)); err != nil {
+			return fmt.Errorf("error saving utm mapping: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteMetricsRepository) ListUTMMappings(field string) ([]models.UTMMapping, error) {
+	query := "SELECT field, raw, canonical, records, last_seen FROM utm_mappings"
+	var args []interface{}
+	if field != "" {
+		query += " WHERE field = ?"
+		args = append(args, field)
+	}
+	query += " ORDER BY field, canonical, raw"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying utm mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []models.UTMMapping{}
+	for rows.Next() {
+		var m models.UTMMapping
+		var lastSeen string
+		if err := rows.Scan(&m.Field, &m.Raw, &m.Canonical, &m.Records, &lastSeen); err != nil {
+			return nil, fmt.Errorf("error scanning utm mapping: %w", err)
+		}
+		if m.LastSeen, err = parseTimestamp(lastSeen); err != nil {
+			return nil, fmt.Errorf("error parsing utm mapping last_seen: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, rows.Err()
+}
+
+func (r *SQLiteMetricsRepository) SaveReconciliation(batchID string, keys []models.KeyReconciliation) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM key_reconciliation WHERE batch_id = ?", batchID); err != nil {
+		return fmt.Errorf("error clearing batch reconciliation: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO key_reconciliation
+		(batch_id, campaign, source, medium, status, clicks, cost_micros, opportunities, revenue_micros, suggestions)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("error preparing reconciliation insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, key := range keys {
+		suggestions := ""
+		if len(key.Suggestions) > 0 {
+			encoded, err := json.Marshal(key.Suggestions)
+			if err != nil {
+				return fmt.Errorf("error encoding key suggestions: %w", err)
+			}
+			suggestions = string(encoded)
+		}
+		if _, err := stmt.Exec(batchID, key.UTMCampaign, key.UTMSource, key.UTMMedium, key.Status,
+			key.Clicks, toMicros(key.Cost), key.Opportunities, toMicros(key.Revenue), suggestions); err != nil {
+			return fmt.Errorf("error saving key reconciliation: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteMetricsRepository) ListReconciliation(filter models.ReconciliationFilter, limit, offset int) ([]models.KeyReconciliation, error) {
+	query := `SELECT batch_id, campaign, source, medium, status, clicks, cost_micros, opportunities, revenue_micros, suggestions
+		FROM key_reconciliation`
+	var conditions []string
+	var args []interface{}
+	if filter.BatchID != "" {
+		conditions = append(conditions, "batch_id = ?")
+		args = append(args, filter.BatchID)
+	} else {
+		conditions = append(conditions, "batch_id = (SELECT batch_id FROM key_reconciliation ORDER BY id DESC LIMIT 1)")
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	} else {
+		conditions = append(conditions, "status != ?")
+		args = append(args, models.MatchStatusMatched)
+	}
+	query += " WHERE " + strings.Join(conditions, " AND ") + " ORDER BY id LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying reconciliation: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.KeyReconciliation{}
+	for rows.Next() {
+		var key models.KeyReconciliation
+		var suggestions string
+		var costMicros, revenueMicros int64
+		if err := rows.Scan(&key.BatchID, &key.UTMCampaign, &key.UTMSource, &key.UTMMedium, &key.Status,
+			&key.Clicks, &costMicros, &key.Opportunities, &revenueMicros, &suggestions); err != nil {
+			return nil, fmt.Errorf("error scanning reconciliation: %w", err)
+		}
+		key.Cost, key.Revenue = fromMicros(costMicros), fromMicros(revenueMicros)
+		if suggestions != "" {
+			if err := json.Unmarshal([]byte(suggestions), &key.Suggestions); err != nil {
+				return nil, fmt.Errorf("invalid suggestions for key in batch %s: %w", key.BatchID, err)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
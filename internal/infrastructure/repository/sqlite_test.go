@@ -0,0 +1,452 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+func newTestSQLiteRepository(t *testing.T) (*SQLiteMetricsRepository, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "etl.db")
+	repo, err := NewSQLiteMetricsRepository(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteMetricsRepository() unexpected error: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo, path
+}
+
+func TestSQLiteMetricsRepository_SaveAndGet(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+
+	key := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	dailyKey := models.DailyKey{Date: "2025-01-15", UTMKey: key}
+	// Save solo escribe los contadores de ads; los de CRM provienen de SaveOpportunities
+	metrics := map[models.DailyKey]models.AggregatedMetrics{
+		dailyKey: {Channel: "google_ads", Impressions: 4000, Clicks: 100, Cost: decimal.NewFromFloat(50.5)},
+	}
+
+	if err := repo.Save(metrics); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll() unexpected error: %v", err)
+	}
+	if len(all) != 1 || !all[key].Equal(metrics[dailyKey]) {
+		t.Errorf("GetAll() = %v, want %v", all, metrics)
+	}
+
+	got, found, err := repo.GetByKey(key)
+	if err != nil || !found {
+		t.Fatalf("GetByKey() = found %v, err %v", found, err)
+	}
+	if !got.Equal(metrics[dailyKey]) {
+		t.Errorf("GetByKey() = %v, want %v", got, metrics[dailyKey])
+	}
+
+	// Guardar la misma clave y día reemplaza los valores previos
+	updated := metrics[dailyKey]
+	updated.Clicks = 300
+	if err := repo.Save(map[models.DailyKey]models.AggregatedMetrics{dailyKey: updated}); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	got, _, _ = repo.GetByKey(key)
+	if got.Clicks != 300 {
+		t.Errorf("Expected 300 clicks after upsert, got %d", got.Clicks)
+	}
+
+	_, found, err = repo.GetByKey(models.UTMKey{Campaign: "missing"})
+	if err != nil || found {
+		t.Errorf("GetByKey() for missing key = found %v, err %v", found, err)
+	}
+}
+
+func TestSQLiteMetricsRepository_ExactMoney(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+
+	key := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	// Diez merges de 0.1 suman exactamente 1 (con REAL quedaba 0.9999999999999999)
+	for i := 0; i < 10; i++ {
+		if err := repo.Merge(string(rune('a'+i)), map[models.DailyKey]models.AggregatedMetrics{
+			{Date: "2025-01-15", UTMKey: key}: {Clicks: 1, Cost: decimal.RequireFromString("0.1")},
+		}); err != nil {
+			t.Fatalf("Merge() unexpected error: %v", err)
+		}
+	}
+	got, _, _ := repo.GetByKey(key)
+	if got.Cost.String() != "1" {
+		t.Errorf("Cost after 10 merges of 0.1 = %s, want 1", got.Cost)
+	}
+}
+
+func TestSQLiteMetricsRepository_MigratesMoneyToMicros(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "etl.db")
+
+	// Esquema anterior a la versión 11, con montos REAL
+	published := migrations
+	migrations = published[:10]
+	old, err := NewSQLiteMetricsRepository(path)
+	migrations = published
+	if err != nil {
+		t.Fatalf("NewSQLiteMetricsRepository() unexpected error: %v", err)
+	}
+	if _, err := old.db.Exec(`INSERT INTO daily_metrics (date, campaign, source, medium, clicks, cost, revenue)
+		VALUES ('2025-01-15', 'sale', 'google', 'cpc', 10, 45.1, 300.25)`); err != nil {
+		t.Fatalf("insert daily_metrics: %v", err)
+	}
+	old.Close()
+
+	repo, err := NewSQLiteMetricsRepository(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteMetricsRepository() after upgrade unexpected error: %v", err)
+	}
+	defer repo.Close()
+
+	got, found, err := repo.GetByKey(models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"})
+	if err != nil || !found {
+		t.Fatalf("GetByKey() = found %v, err %v", found, err)
+	}
+	if got.Cost.String() != "45.1" || got.Revenue.String() != "300.25" || got.Clicks != 10 {
+		t.Errorf("GetByKey() after migration = %+v, want cost 45.1 and revenue 300.25", got)
+	}
+}
+
+func TestSQLiteMetricsRepository_SaveOpportunities(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+
+	sale := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	promo := models.UTMKey{Campaign: "promo", Source: "google", Medium: "cpc"}
+	day := "2025-01-15"
+	if err := repo.Save(map[models.DailyKey]models.AggregatedMetrics{
+		{Date: day, UTMKey: sale}: {Channel: "google_ads", Clicks: 100, Cost: decimal.NewFromFloat(50)},
+	}); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	at := func(hour int) *time.Time {
+		t := time.Date(2025, 1, 16, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+	opportunity := func(id string, step models.FunnelStep, amount float64, utm models.UTMKey, updatedAt *time.Time) models.Opportunity {
+		return models.Opportunity{ID: id, Stage: string(step), Step: step, Amount: decimal.NewFromFloat(amount), CreatedAt: day, Day: day, UTM: utm,
+			UpdatedAt: updatedAt, IngestedAt: time.Now().UTC(), BatchID: "b1"}
+	}
+
+	// Primera ejecución: dos leads
+	if err := repo.SaveOpportunities([]models.Opportunity{
+		opportunity("O1", models.StepLead, 0, sale, at(10)),
+		opportunity("O2", models.StepLead, 0, sale, nil),
+	}); err != nil {
+		t.Fatalf("SaveOpportunities() unexpected error: %v", err)
+	}
+	// Una ventana solapada reingesta O1 ya ganada, O2 movida a otra campaña y un snapshot viejo de O1
+	if err := repo.SaveOpportunities([]models.Opportunity{
+		opportunity("O1", models.StepWon, 500, sale, at(12)),
+		opportunity("O2", models.StepOpportunity, 0, promo, nil),
+	}); err != nil {
+		t.Fatalf("SaveOpportunities() unexpected error: %v", err)
+	}
+	if err := repo.SaveOpportunities([]models.Opportunity{opportunity("O1", models.StepLead, 0, sale, at(11))}); err != nil {
+		t.Fatalf("SaveOpportunities() unexpected error: %v", err)
+	}
+
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll() unexpected error: %v", err)
+	}
+	wantSale := models.AggregatedMetrics{Channel: "google_ads", Clicks: 100, Cost: decimal.NewFromFloat(50),
+		Leads: 1, MQLs: 1, SQLs: 1, Opportunities: 1, ClosedWon: 1, Revenue: decimal.NewFromFloat(500)}
+	if !all[sale].Equal(wantSale) {
+		t.Errorf("sale = %v, want %v", all[sale], wantSale)
+	}
+	if want := (models.AggregatedMetrics{Leads: 1, MQLs: 1, SQLs: 1, Opportunities: 1}); !all[promo].Equal(want) {
+		t.Errorf("promo = %v, want %v", all[promo], want)
+	}
+
+	// Un nuevo Save de ads no borra los contadores de CRM
+	if err := repo.Save(map[models.DailyKey]models.AggregatedMetrics{
+		{Date: day, UTMKey: sale}: {Channel: "google_ads", Clicks: 120, Cost: decimal.NewFromFloat(60)},
+	}); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	got, _, _ := repo.GetByKey(sale)
+	if got.Clicks != 120 || got.ClosedWon != 1 || !got.Revenue.Equal(decimal.NewFromFloat(500)) {
+		t.Errorf("GetByKey() after ads save = %v", got)
+	}
+}
+
+func TestSQLiteMetricsRepository_Rejections(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+
+	rejectedAt := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	rejection := func(source, rule string) models.Rejection {
+		return models.Rejection{Source: source, Kind: "crm", Rule: rule, Reason: "bad", Record: []byte(`{"opportunity_id":"O1"}`), RejectedAt: rejectedAt}
+	}
+
+	if err := repo.SaveRejections("b1", []models.Rejection{rejection("crm", "known_stage"), rejection("crm", "valid_email")}); err != nil {
+		t.Fatalf("SaveRejections() unexpected error: %v", err)
+	}
+	if err := repo.SaveRejections("b2", []models.Rejection{rejection("hubspot", "known_stage")}); err != nil {
+		t.Fatalf("SaveRejections() unexpected error: %v", err)
+	}
+	// Guardar de nuevo el mismo lote reemplaza sus rechazos
+	if err := repo.SaveRejections("b1", []models.Rejection{rejection("crm", "known_stage")}); err != nil {
+		t.Fatalf("SaveRejections() unexpected error: %v", err)
+	}
+
+	all, err := repo.ListRejections(models.RejectionFilter{}, 10, 0)
+	if err != nil {
+		t.Fatalf("ListRejections() unexpected error: %v", err)
+	}
+	if len(all) != 2 || all[0].BatchID != "b1" || string(all[0].Record) != `{"opportunity_id":"O1"}` || !all[0].RejectedAt.Equal(rejectedAt) {
+		t.Errorf("Unexpected rejections (newest first): %+v", all)
+	}
+
+	filtered, _ := repo.ListRejections(models.RejectionFilter{Rule: "known_stage", Source: "hubspot"}, 10, 0)
+	if len(filtered) != 1 || filtered[0].BatchID != "b2" {
+		t.Errorf("Unexpected filtered rejections: %+v", filtered)
+	}
+}
+
+func TestSQLiteMetricsRepository_UTMMappings(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+
+	seen := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	mappings := []models.UTMMapping{
+		{Field: "source", Raw: "fb", Canonical: "fb", Records: 2, LastSeen: seen},
+		{Field: "medium", Raw: "CPC", Canonical: "cpc", Records: 1, LastSeen: seen},
+	}
+	if err := repo.SaveUTMMappings(mappings); err != nil {
+		t.Fatalf("SaveUTMMappings() unexpected error: %v", err)
+	}
+	// Tras recargar las reglas, el mismo valor crudo suma registros y toma el nuevo canónico
+	later := seen.Add(time.Hour)
+	if err := repo.SaveUTMMappings([]models.UTMMapping{
+		{Field: "source", Raw: "fb", Canonical: "facebook", Records: 3, LastSeen: later},
+	}); err != nil {
+		t.Fatalf("SaveUTMMappings() unexpected error: %v", err)
+	}
+
+	got, err := repo.ListUTMMappings("source")
+	if err != nil {
+		t.Fatalf("ListUTMMappings() unexpected error: %v", err)
+	}
+	want := models.UTMMapping{Field: "source", Raw: "fb", Canonical: "facebook", Records: 5, LastSeen: later}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("ListUTMMappings(source) = %+v, want [%+v]", got, want)
+	}
+
+	all, _ := repo.ListUTMMappings("")
+	if len(all) != 2 || all[0].Field != "medium" {
+		t.Errorf("ListUTMMappings() = %+v", all)
+	}
+}
+
+func TestSQLiteMetricsRepository_Batches(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+
+	processed, err := repo.IsBatchProcessed("batch-1")
+	if err != nil || processed {
+		t.Fatalf("IsBatchProcessed() before mark = %v, err %v", processed, err)
+	}
+
+	if err := repo.MarkBatchProcessed("batch-1"); err != nil {
+		t.Fatalf("MarkBatchProcessed() unexpected error: %v", err)
+	}
+	// Marcar dos veces no debe fallar
+	if err := repo.MarkBatchProcessed("batch-1"); err != nil {
+		t.Fatalf("MarkBatchProcessed() second call unexpected error: %v", err)
+	}
+
+	processed, err = repo.IsBatchProcessed("batch-1")
+	if err != nil || !processed {
+		t.Errorf("IsBatchProcessed() after mark = %v, err %v", processed, err)
+	}
+
+	if err := repo.Clear(); err != nil {
+		t.Fatalf("Clear() unexpected error: %v", err)
+	}
+	processed, _ = repo.IsBatchProcessed("batch-1")
+	if processed {
+		t.Error("Expected batch to be cleared")
+	}
+}
+
+func TestSQLiteMetricsRepository_PersistsAcrossReopen(t *testing.T) {
+	repo, path := newTestSQLiteRepository(t)
+
+	key := models.UTMKey{Campaign: "sale", Source: "meta", Medium: "paid_social"}
+	if err := repo.Save(map[models.DailyKey]models.AggregatedMetrics{{Date: "2025-01-15", UTMKey: key}: {Clicks: 42}}); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	repo.Close()
+
+	// Reabrir aplica las migraciones de nuevo sin fallar y conserva los datos
+	reopened, err := NewSQLiteMetricsRepository(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteMetricsRepository() on reopen unexpected error: %v", err)
+	}
+	defer reopened.Close()
+
+	got, found, err := reopened.GetByKey(key)
+	if err != nil || !found {
+		t.Fatalf("GetByKey() after reopen = found %v, err %v", found, err)
+	}
+	if got.Clicks != 42 {
+		t.Errorf("Expected 42 clicks after reopen, got %d", got.Clicks)
+	}
+}
+
+func TestSQLiteMetricsRepository_GetByDateRange(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+
+	key := models.UTMKey{Campaign: "sale", Source: "google", Medium: "cpc"}
+	if err := repo.Save(map[models.DailyKey]models.AggregatedMetrics{
+		{Date: "2025-01-10", UTMKey: key}: {Clicks: 10, Cost: decimal.NewFromFloat(1)},
+		{Date: "2025-01-15", UTMKey: key}: {Clicks: 20, Cost: decimal.NewFromFloat(2)},
+		{Date: "2025-01-20", UTMKey: key}: {Clicks: 40, Cost: decimal.NewFromFloat(4)},
+		{Date: "", UTMKey: key}:           {Clicks: 80, Cost: decimal.NewFromFloat(8)},
+	}); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	from := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		from           *time.Time
+		to             *time.Time
+		expectedClicks int
+	}{
+		{name: "Sin filtro incluye hechos sin fecha", from: nil, to: nil, expectedClicks: 150},
+		{name: "Solo from", from: &from, to: nil, expectedClicks: 60},
+		{name: "Solo to excluye hechos sin fecha", from: nil, to: &from, expectedClicks: 30},
+		{name: "Rango inclusivo", from: &from, to: &to, expectedClicks: 60},
+		{name: "Un solo día", from: &to, to: &to, expectedClicks: 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.GetByDateRange(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("GetByDateRange() unexpected error: %v", err)
+			}
+			if result[key].Clicks != tt.expectedClicks {
+				t.Errorf("GetByDateRange() clicks = %d, want %d", result[key].Clicks, tt.expectedClicks)
+			}
+		})
+	}
+}
+
+func TestSQLiteMetricsRepository_BatchLedger(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+
+	started := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	finished := started.Add(1500 * time.Millisecond)
+
+	failed := models.Batch{ID: "batch-a", Status: models.BatchStatusFailed, StartedAt: started,
+		FinishedAt: &finished, DurationMS: 1500, Error: "HTTP 503", Attempts: 1}
+	completed := models.Batch{ID: "batch-b", Since: "2025-01-01", Status: models.BatchStatusCompleted,
+		StartedAt: started.Add(time.Hour), FinishedAt: &finished, AdsRecords: 10, CRMRecords: 4, Combinations: 3, Attempts: 1,
+		Quality:        &models.QualitySummary{RecordsChecked: 14, RecordsAccepted: 13, RecordsRejected: 1, RejectionsByRule: map[string]int{"known_stage": 1}},
+		Reconciliation: &models.ReconciliationSummary{Matched: 2, CRMOnly: 1}, Currency: "EUR", Timezone: "America/Mexico_City"}
+
+	for _, b := range []models.Batch{failed, completed} {
+		if err := repo.SaveBatch(b); err != nil {
+			t.Fatalf("SaveBatch() unexpected error: %v", err)
+		}
+	}
+
+	got, found, err := repo.GetBatch("batch-b")
+	if err != nil || !found {
+		t.Fatalf("GetBatch() = found %v, err %v", found, err)
+	}
+	if got.Since != "2025-01-01" || got.AdsRecords != 10 || got.CRMRecords != 4 || got.Combinations != 3 || got.Currency != "EUR" ||
+		got.Timezone != "America/Mexico_City" {
+		t.Errorf("GetBatch() = %+v, want %+v", got, completed)
+	}
+	if got.FinishedAt == nil || !got.FinishedAt.Equal(finished) {
+		t.Errorf("GetBatch() finished_at = %v, want %v", got.FinishedAt, finished)
+	}
+	if got.Quality == nil || got.Quality.RecordsRejected != 1 || got.Quality.RejectionsByRule["known_stage"] != 1 {
+		t.Errorf("GetBatch() quality = %+v, want %+v", got.Quality, completed.Quality)
+	}
+	if got.Reconciliation == nil || *got.Reconciliation != *completed.Reconciliation {
+		t.Errorf("GetBatch() reconciliation = %+v, want %+v", got.Reconciliation, completed.Reconciliation)
+	}
+
+	// Solo los lotes completados cuentan como procesados
+	if processed, _ := repo.IsBatchProcessed("batch-a"); processed {
+		t.Error("Expected failed batch not to be processed")
+	}
+	if processed, _ := repo.IsBatchProcessed("batch-b"); !processed {
+		t.Error("Expected completed batch to be processed")
+	}
+
+	batches, err := repo.ListBatches(10, 0)
+	if err != nil {
+		t.Fatalf("ListBatches() unexpected error: %v", err)
+	}
+	if len(batches) != 2 || batches[0].ID != "batch-b" || batches[1].ID != "batch-a" {
+		t.Errorf("ListBatches() expected newest first, got %+v", batches)
+	}
+
+	page, _ := repo.ListBatches(1, 1)
+	if len(page) != 1 || page[0].ID != "batch-a" {
+		t.Errorf("ListBatches(1, 1) = %+v, want batch-a", page)
+	}
+}
+
+func TestSQLiteMetricsRepository_Reconciliation(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+
+	first := []models.KeyReconciliation{
+		{UTMCampaign: "sprng_sale", Status: models.MatchStatusCRMOnly, Opportunities: 1, Revenue: decimal.NewFromFloat(500),
+			Suggestions: []models.KeySuggestion{{UTMCampaign: "spring_sale", Similarity: 0.97}}},
+		{UTMCampaign: "sale", Status: models.MatchStatusMatched, Clicks: 10, Cost: decimal.NewFromFloat(20)},
+	}
+	if err := repo.SaveReconciliation("batch-1", first); err != nil {
+		t.Fatalf("SaveReconciliation() unexpected error: %v", err)
+	}
+	second := []models.KeyReconciliation{
+		{UTMCampaign: "spring_sale", Status: models.MatchStatusAdsOnly, Clicks: 5, Cost: decimal.NewFromFloat(80)},
+		{UTMCampaign: "promo", Status: models.MatchStatusCRMOnly, Opportunities: 2},
+	}
+	// Guardar dos veces el mismo lote reemplaza sus claves
+	for i := 0; i < 2; i++ {
+		if err := repo.SaveReconciliation("batch-2", second); err != nil {
+			t.Fatalf("SaveReconciliation() unexpected error: %v", err)
+		}
+	}
+
+	// Sin lote se usa el último conciliado; sin status, solo las huérfanas en el orden guardado
+	got, err := repo.ListReconciliation(models.ReconciliationFilter{}, 10, 0)
+	if err != nil {
+		t.Fatalf("ListReconciliation() unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].BatchID != "batch-2" || got[0].UTMCampaign != "spring_sale" || got[1].UTMCampaign != "promo" {
+		t.Errorf("ListReconciliation() = %+v", got)
+	}
+
+	got, _ = repo.ListReconciliation(models.ReconciliationFilter{BatchID: "batch-1"}, 10, 0)
+	if len(got) != 1 || got[0].Status != models.MatchStatusCRMOnly || len(got[0].Suggestions) != 1 || got[0].Suggestions[0].UTMCampaign != "spring_sale" {
+		t.Errorf("ListReconciliation(batch-1) = %+v", got)
+	}
+
+	got, _ = repo.ListReconciliation(models.ReconciliationFilter{BatchID: "batch-1", Status: models.MatchStatusMatched}, 10, 0)
+	if len(got) != 1 || !got[0].Cost.Equal(decimal.NewFromFloat(20)) || got[0].Suggestions != nil {
+		t.Errorf("ListReconciliation(batch-1, matched) = %+v", got)
+	}
+
+	got, _ = repo.ListReconciliation(models.ReconciliationFilter{}, 1, 1)
+	if len(got) != 1 || got[0].UTMCampaign != "promo" {
+		t.Errorf("ListReconciliation() page 2 = %+v", got)
+	}
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/m4ck-y/ETL_go/internal/domain/models"
+	"github.com/shopspring/decimal"
+)
+
+// dateLayout es el formato con el que se guardan las fechas de los hechos diarios
+const dateLayout = "2006-01-02"
+
+// timestampLayout tiene ancho fijo para que las marcas de tiempo se ordenen correctamente como texto
+const timestampLayout = "2006-01-02T15:04:05.000000000Z"
+
+// toMicros expresa un monto como micro-unidades enteras (models.MoneyScale) para guardarlo sin error de redondeo
+func toMicros(amount decimal.Decimal) int64 {
+	return models.RoundMoney(amount).Shift(models.MoneyScale).IntPart()
+}
+
+// fromMicros es la inversa de toMicros
+func fromMicros(micros int64) decimal.Decimal {
+	return decimal.New(micros, -models.MoneyScale)
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+func parseTimestamp(value string) (time.Time, error) {
+	return time.Parse(timestampLayout, value)
+}
+
+// isDayInRange verifica si un día (YYYY-MM-DD) cae dentro del rango inclusivo [from, to].
+// Los hechos sin fecha solo se incluyen cuando no hay ningún filtro.
+func isDayInRange(day string, from, to *time.Time) bool {
+	if from == nil && to == nil {
+		return true
+	}
+	if day == "" {
+		return false
+	}
+	if from != nil && day < from.Format(dateLayout) {
+		return false
+	}
+	if to != nil && day > to.Format(dateLayout) {
+		return false
+	}
+	return true
+}
+
+// sumDailyFacts suma por UTM los hechos diarios que cumplen include. El canal es el del día más reciente
+// que lo informa (los hechos sin fecha son los más antiguos), el mismo criterio que latestChannelColumn.
+func sumDailyFacts(data map[models.DailyKey]models.AggregatedMetrics, include func(models.DailyKey) bool) map[models.UTMKey]models.AggregatedMetrics {
+	totals := make(map[models.UTMKey]models.AggregatedMetrics)
+	channelDays := make(map[models.UTMKey]string)
+	for k, v := range data {
+		if !include(k) {
+			continue
+		}
+		total := totals[k.UTMKey].Add(v)
+		if v.Channel != "" && (totals[k.UTMKey].Channel == "" || k.Date > channelDays[k.UTMKey]) {
+			total.Channel = v.Channel
+			channelDays[k.UTMKey] = k.Date
+		}
+		totals[k.UTMKey] = total
+	}
+	return totals
+}
+
+// sortOpportunitiesByID ordena como el ORDER BY id de SQLite
+func sortOpportunitiesByID(opportunities []models.Opportunity) {
+	sort.Slice(opportunities, func(i, j int) bool { return opportunities[i].ID < opportunities[j].ID })
+}
+
+// pseudonymizeRecord reemplaza el contact_email en claro de un registro en cuarentena por su hash.
+// Los emails siempre contienen @ y los hashes nunca, así que un registro ya seudonimizado no cambia.
+// Los demás campos se copian tal cual: decodificarlos alteraría los números (1e21, 0.10, enteros grandes).
+func pseudonymizeRecord(record json.RawMessage, hash func(string) string) (json.RawMessage, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(record, &fields); err != nil {
+		return record, false
+	}
+	var email string
+	if err := json.Unmarshal(fields["contact_email"], &email); err != nil || !strings.Contains(email, "@") {
+		return record, false
+	}
+	hashed, err := json.Marshal(hash(email))
+	if err != nil {
+		return record, false
+	}
+	fields["contact_email"] = hashed
+	updated, err := json.Marshal(fields)
+	if err != nil {
+		return record, false
+	}
+	return updated, true
+}
+
+func matchesRejectionFilter(rejection models.Rejection, filter models.RejectionFilter) bool {
+	return (filter.BatchID == "" || rejection.BatchID == filter.BatchID) &&
+		(filter.Rule == "" || rejection.Rule == filter.Rule) &&
+		(filter.Source == "" || rejection.Source == filter.Source)
+}
+
+// matchesReconciliationFilter aplica el filtro de estado; sin estado solo pasan las claves huérfanas
+func matchesReconciliationFilter(key models.KeyReconciliation, filter models.ReconciliationFilter) bool {
+	if filter.Status == "" {
+		return key.Status != models.MatchStatusMatched
+	}
+	return key.Status == filter.Status
+}
@@ -29,6 +29,7 @@ type StructuredLog struct {
 // Logger es el logger global del sistema
 type Logger struct {
 	serviceName string
+	filters     []Filter
 }
 
 // NewLogger crea un nuevo logger
@@ -38,8 +39,17 @@ func NewLogger(serviceName string) *Logger {
 	}
 }
 
+// AddFilter agrega un filtro que se aplica a cada entrada, en orden de registro
+func (l *Logger) AddFilter(filter Filter) {
+	l.filters = append(l.filters, filter)
+}
+
 // log escribe una entrada de log estructurada en formato JSON
 func (l *Logger) log(level LogLevel, message string, requestID string, extra map[string]interface{}) {
+	for _, filter := range l.filters {
+		message, extra = filter(message, extra)
+	}
+
 	// Crear entrada de log estructurada
 	logEntry := StructuredLog{
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
@@ -97,8 +107,17 @@ func (l *Logger) Fatal(message string, requestID string, extra ...map[string]int
 	}
 	l.log(FATAL, message, requestID, extraData)
 	// Para errores fatales, terminamos el programa
+	for _, filter := range l.filters {
+		message, _ = filter(message, nil)
+	}
 	log.Fatal(message)
 }
 
-// Logger global
-var GlobalLogger = NewLogger("etl-go-service")
+// Logger global; enmascara los emails para que ningún dato de contacto llegue a los logs
+var GlobalLogger = newGlobalLogger()
+
+func newGlobalLogger() *Logger {
+	logger := NewLogger("etl-go-service")
+	logger.AddFilter(RedactEmails)
+	return logger
+}
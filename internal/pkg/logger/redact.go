@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Filter transforma cada entrada antes de escribirla; devuelve el mensaje y los datos a registrar
+type Filter func(message string, extra map[string]interface{}) (string, map[string]interface{})
+
+// emailPattern reconoce valores con forma de email dentro de cualquier texto
+var emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,})`)
+
+// MaskEmails enmascara los emails de s conservando la primera letra y el dominio: ana@example.com → a***@example.com
+func MaskEmails(s string) string {
+	if !strings.Contains(s, "@") {
+		return s
+	}
+	return emailPattern.ReplaceAllString(s, "${1}***@${2}")
+}
+
+// RedactEmails es el filtro que enmascara los emails del mensaje y de cualquier valor de extra,
+// incluidos errores, mapas, listas y estructuras, sin modificar el mapa recibido
+func RedactEmails(message string, extra map[string]interface{}) (string, map[string]interface{}) {
+	if extra == nil {
+		return MaskEmails(message), nil
+	}
+	redacted := make(map[string]interface{}, len(extra))
+	for key, value := range extra {
+		redacted[key] = redactValue(value)
+	}
+	return MaskEmails(message), redacted
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return v
+	case string:
+		return MaskEmails(v)
+	case error:
+		return MaskEmails(v.Error())
+	case []string:
+		masked := make([]string, len(v))
+		for i, s := range v {
+			masked[i] = MaskEmails(s)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = redactValue(item)
+		}
+		return masked
+	case map[string]interface{}:
+		_, masked := RedactEmails("", v)
+		return masked
+	}
+	// Cualquier otro valor (registros, structs, fechas) se enmascara sobre su JSON; sin emails queda igual
+	data, err := json.Marshal(value)
+	if err != nil || !strings.Contains(string(data), "@") {
+		return value
+	}
+	return json.RawMessage(MaskEmails(string(data)))
+}
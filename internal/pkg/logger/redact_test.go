@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMaskEmails(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "sin datos de contacto", expected: "sin datos de contacto"},
+		{input: "ana@example.com", expected: "a***@example.com"},
+		{input: `invalid contact_email "Luis.Perez+ads@mail.example.co.uk"`, expected: `invalid contact_email "L***@mail.example.co.uk"`},
+		{input: "a@b.io y c@d.io", expected: "a***@b.io y c***@d.io"},
+		{input: "user@localhost", expected: "user@localhost"},
+	}
+	for _, tt := range tests {
+		if got := MaskEmails(tt.input); got != tt.expected {
+			t.Errorf("MaskEmails(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestRedactEmails(t *testing.T) {
+	record := struct {
+		ContactEmail string `json:"contact_email"`
+		Amount       int    `json:"amount"`
+	}{ContactEmail: "ana@example.com", Amount: 5}
+	extra := map[string]interface{}{
+		"error":   errors.New("lookup ana@example.com failed"),
+		"emails":  []string{"ana@example.com"},
+		"nested":  map[string]interface{}{"email": "ana@example.com"},
+		"record":  record,
+		"records": 3,
+	}
+
+	message, redacted := RedactEmails("contacto ana@example.com", extra)
+	if message != "contacto a***@example.com" {
+		t.Errorf("message = %q", message)
+	}
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "ana@") {
+		t.Errorf("redacted extra still contains the email: %s", data)
+	}
+	if redacted["records"] != 3 {
+		t.Errorf("records = %v, want 3", redacted["records"])
+	}
+	// El mapa del llamador no se modifica
+	if extra["emails"].([]string)[0] != "ana@example.com" {
+		t.Error("RedactEmails modified the caller's map")
+	}
+}